@@ -0,0 +1,157 @@
+// Package auth is a lightweight credential store.
+// It provides functionality for loading credentials, as well as validating credentials.
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BasicAuther is the interface an object must support to return basic auth information.
+type BasicAuther interface {
+	BasicAuth() (string, string, bool)
+}
+
+// Credential represents authentication and authorization configuration for a single user.
+type Credential struct {
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"` // bcrypt hash of the password.
+	Perms    []string `json:"perms,omitempty"`
+}
+
+// CredentialsStore stores authentication and authorization information for all users.
+type CredentialsStore struct {
+	store map[string][]byte
+	perms map[string]map[string]bool
+
+	// Checking a bcrypt hash is deliberately slow, so a digest of the last
+	// password checked successfully for each user is kept, and checked first.
+	mu       sync.Mutex
+	verified map[string][sha256.Size]byte
+}
+
+// NewCredentialsStore returns a new instance of a CredentialStore.
+func NewCredentialsStore() *CredentialsStore {
+	return &CredentialsStore{
+		store:    make(map[string][]byte),
+		perms:    make(map[string]map[string]bool),
+		verified: make(map[string][sha256.Size]byte),
+	}
+}
+
+// NewCredentialsStoreFromFile returns a new instance of a CredentialStore,
+// loaded from the file at path.
+func NewCredentialsStoreFromFile(path string) (*CredentialsStore, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c := NewCredentialsStore()
+	if err := c.Load(f); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Load loads credential information from a reader. The reader must supply a
+// JSON array of credentials, each with a username, a bcrypt-hashed password,
+// and a list of permissions.
+func (c *CredentialsStore) Load(r io.Reader) error {
+	dec := json.NewDecoder(r)
+	// Read open bracket
+	_, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	var cred Credential
+	for dec.More() {
+		err := dec.Decode(&cred)
+		if err != nil {
+			return err
+		}
+		if cred.Username == "" {
+			return fmt.Errorf("credential without username")
+		}
+		if _, err := bcrypt.Cost([]byte(cred.Password)); err != nil {
+			return fmt.Errorf("password of user %s is not a bcrypt hash: %s", cred.Username, err)
+		}
+		c.store[cred.Username] = []byte(cred.Password)
+		c.perms[cred.Username] = make(map[string]bool, len(cred.Perms))
+		for _, p := range cred.Perms {
+			c.perms[cred.Username][p] = true
+		}
+		cred = Credential{}
+	}
+
+	// Read closing bracket.
+	_, err = dec.Token()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Check returns true if the password is correct for the given username.
+func (c *CredentialsStore) Check(username, password string) bool {
+	hash, ok := c.store[username]
+	if !ok {
+		return false
+	}
+
+	digest := sha256.Sum256([]byte(password))
+	c.mu.Lock()
+	v, ok := c.verified[username]
+	c.mu.Unlock()
+	if ok && subtle.ConstantTimeCompare(v[:], digest[:]) == 1 {
+		return true
+	}
+
+	if bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil {
+		return false
+	}
+	c.mu.Lock()
+	c.verified[username] = digest
+	c.mu.Unlock()
+	return true
+}
+
+// CheckRequest returns true if b contains a valid username and password.
+func (c *CredentialsStore) CheckRequest(b BasicAuther) bool {
+	username, password, ok := b.BasicAuth()
+	if !ok || !c.Check(username, password) {
+		return false
+	}
+	return true
+}
+
+// HasPerm returns true if username has the given perm. It does not
+// perform any password checking.
+func (c *CredentialsStore) HasPerm(username string, perm string) bool {
+	m, ok := c.perms[username]
+	if !ok {
+		return false
+	}
+	return m[perm]
+}
+
+// HasAnyPerm returns true if username has at least one of the given perms.
+// It does not perform any password checking.
+func (c *CredentialsStore) HasAnyPerm(username string, perm ...string) bool {
+	for _, p := range perm {
+		if c.HasPerm(username, p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,251 @@
+package backup
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	emptyPayloadHash    = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	unsignedPayloadHash = "UNSIGNED-PAYLOAD"
+	amzDateFormat       = "20060102T150405Z"
+	s3Timeout           = 5 * time.Minute
+)
+
+// S3Config is the configuration of an S3-compatible storage.
+type S3Config struct {
+	Endpoint        string // Base URL of the service, e.g. https://s3.amazonaws.com.
+	Region          string // Region used to sign requests.
+	Bucket          string // Bucket holding the backups.
+	Prefix          string // Prefix prepended to the key of every backup.
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// S3Storage stores backups in a bucket of an S3-compatible service. Requests
+// use path-style addressing and are signed with AWS Signature Version 4, so
+// services other than AWS S3, such as MinIO, are supported.
+type S3Storage struct {
+	endpoint *url.URL
+	conf     S3Config
+	client   *http.Client
+}
+
+// NewS3Storage returns an S3Storage using the given configuration.
+func NewS3Storage(c *S3Config) (*S3Storage, error) {
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parse S3 endpoint: %s", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("S3 endpoint %s must be an http or https URL", c.Endpoint)
+	}
+	if c.Bucket == "" {
+		return nil, fmt.Errorf("S3 bucket not set")
+	}
+	return &S3Storage{
+		endpoint: u,
+		conf:     *c,
+		client:   &http.Client{Timeout: s3Timeout},
+	}, nil
+}
+
+// Upload puts the object into the bucket.
+func (s *S3Storage) Upload(name string, r io.Reader, size int64) error {
+	req, err := http.NewRequest("PUT", s.objectURL(name), ioutil.NopCloser(r))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	_, err = s.do(req, unsignedPayloadHash)
+	return err
+}
+
+// List returns the names, relative to the prefix, of all objects in the
+// bucket under the prefix.
+func (s *S3Storage) List() ([]string, error) {
+	var names []string
+	token := ""
+	for {
+		q := url.Values{}
+		q.Set("list-type", "2")
+		q.Set("prefix", s.conf.Prefix)
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		req, err := http.NewRequest("GET", s.bucketURL()+"?"+canonicalQuery(q), nil)
+		if err != nil {
+			return nil, err
+		}
+		b, err := s.do(req, emptyPayloadHash)
+		if err != nil {
+			return nil, err
+		}
+
+		var res struct {
+			Contents []struct {
+				Key string
+			}
+			IsTruncated           bool
+			NextContinuationToken string
+		}
+		if err := xml.Unmarshal(b, &res); err != nil {
+			return nil, fmt.Errorf("parse S3 list response: %s", err)
+		}
+		for _, c := range res.Contents {
+			names = append(names, strings.TrimPrefix(c.Key, s.conf.Prefix))
+		}
+		if !res.IsTruncated || res.NextContinuationToken == "" {
+			return names, nil
+		}
+		token = res.NextContinuationToken
+	}
+}
+
+// Delete removes the object from the bucket.
+func (s *S3Storage) Delete(name string) error {
+	req, err := http.NewRequest("DELETE", s.objectURL(name), nil)
+	if err != nil {
+		return err
+	}
+	_, err = s.do(req, emptyPayloadHash)
+	return err
+}
+
+// String returns the location of the backups as an S3 URL.
+func (s *S3Storage) String() string {
+	return fmt.Sprintf("s3://%s/%s", s.conf.Bucket, s.conf.Prefix)
+}
+
+func (s *S3Storage) bucketURL() string {
+	return strings.TrimSuffix(s.endpoint.String(), "/") + "/" + s.conf.Bucket
+}
+
+func (s *S3Storage) objectURL(name string) string {
+	return s.bucketURL() + "/" + uriEncode(s.conf.Prefix+name, true)
+}
+
+// do signs and performs the request, returning the response body. Any
+// response other than 2xx is returned as an error.
+func (s *S3Storage) do(req *http.Request, payloadHash string) ([]byte, error) {
+	signV4(req, payloadHash, s.conf.Region, s.conf.AccessKeyID, s.conf.SecretAccessKey, time.Now())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("S3 %s %s: %s: %s", req.Method, req.URL.Path, resp.Status, b)
+	}
+	return b, nil
+}
+
+// signV4 adds AWS Signature Version 4 headers for the S3 service to the
+// request. All headers already set on the request are signed.
+func signV4(req *http.Request, payloadHash, region, accessKeyID, secretAccessKey string, t time.Time) {
+	t = t.UTC()
+	amzDate := t.Format(amzDateFormat)
+	date := t.Format("20060102")
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var canonicalHeaders strings.Builder
+	for _, k := range keys {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(keys, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uriEncode(req.URL.Path, true),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, region, "s3", "aws4_request"}, "/")
+	h := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(h[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKeyID, scope, signedHeaders, signature))
+}
+
+// canonicalQuery returns the query string in the canonical form required by
+// Signature Version 4.
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		vals := q[k]
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, uriEncode(k, false)+"="+uriEncode(v, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode encodes s as required by Signature Version 4, leaving only
+// unreserved characters as-is. If path is set, slashes are also left as-is.
+func uriEncode(s string, path bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (path && c == '/') {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,93 @@
+package backup
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Storage is the interface backup destinations must implement.
+type Storage interface {
+	// Upload stores size bytes, read from r, as the object with the given name.
+	Upload(name string, r io.Reader, size int64) error
+
+	// List returns the names of all objects in the storage.
+	List() ([]string, error)
+
+	// Delete removes the object with the given name.
+	Delete(name string) error
+
+	// String returns a human-readable description of the storage.
+	String() string
+}
+
+// LocalStorage stores backups as files in a directory.
+type LocalStorage struct {
+	dir string
+}
+
+// NewLocalStorage returns a LocalStorage which stores backups in dir. The
+// directory is created if it does not exist.
+func NewLocalStorage(dir string) (*LocalStorage, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create backup directory: %s", err)
+	}
+	return &LocalStorage{dir: dir}, nil
+}
+
+// Upload writes the object to a file in the directory. The file is written
+// under a temporary name first, so a partial backup is never visible.
+func (l *LocalStorage) Upload(name string, r io.Reader, size int64) (retErr error) {
+	f, err := ioutil.TempFile(l.dir, ".tmp-"+name+"-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if retErr != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	n, err := io.Copy(f, r)
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return fmt.Errorf("wrote %d bytes, expected %d", n, size)
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(l.dir, name))
+}
+
+// List returns the names of all regular files in the directory.
+func (l *LocalStorage) List() ([]string, error) {
+	fis, err := ioutil.ReadDir(l.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, fi := range fis {
+		if fi.Mode().IsRegular() {
+			names = append(names, fi.Name())
+		}
+	}
+	return names, nil
+}
+
+// Delete removes the file with the given name from the directory.
+func (l *LocalStorage) Delete(name string) error {
+	return os.Remove(filepath.Join(l.dir, name))
+}
+
+// String returns the path of the directory.
+func (l *LocalStorage) String() string {
+	return l.dir
+}
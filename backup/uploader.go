@@ -0,0 +1,284 @@
+// Package backup periodically uploads backups of the database to a storage
+// location, and prunes old backups from it.
+package backup
+
+import (
+	"compress/gzip"
+	"expvar"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minghsu0107/tqlite/store"
+)
+
+const (
+	namePrefix      = "backup-"
+	nameTimeFormat  = "20060102T150405Z"
+	binaryExtension = ".sqlite.gz"
+	sqlExtension    = ".sql.gz"
+)
+
+const (
+	numUploads      = "num_uploads"
+	numUploadErrors = "num_upload_errors"
+	numSkipped      = "num_skipped_not_leader"
+	numDeleted      = "num_deleted"
+	numDeleteErrors = "num_delete_errors"
+)
+
+// stats captures stats for the backup uploader.
+var stats *expvar.Map
+
+func init() {
+	stats = expvar.NewMap("backup")
+	stats.Add(numUploads, 0)
+	stats.Add(numUploadErrors, 0)
+	stats.Add(numSkipped, 0)
+	stats.Add(numDeleted, 0)
+	stats.Add(numDeleteErrors, 0)
+}
+
+// Database is the interface the system being backed up must implement.
+type Database interface {
+	// IsLeader returns whether this node is the leader.
+	IsLeader() bool
+
+	// Backup writes a backup of the database, in the given format, to dst.
+	Backup(leader bool, f store.BackupFormat, dst io.Writer) error
+}
+
+// Config is the configuration of an Uploader.
+type Config struct {
+	Interval    time.Duration      // Time between backups.
+	Format      store.BackupFormat // Format of the backups.
+	RetainCount int                // Number of backups kept, 0 for no limit.
+	RetainAge   time.Duration      // Age after which backups are deleted, 0 for no limit.
+}
+
+// Uploader periodically backs up the database, if this node is the leader, to
+// a Storage. Each backup is gzip-compressed and named after the time it was
+// taken.
+type Uploader struct {
+	db      Database
+	storage Storage
+	conf    Config
+
+	done chan struct{}
+	wg   sync.WaitGroup
+
+	mu          sync.RWMutex
+	lastSuccess time.Time
+	lastName    string
+	lastFailure time.Time
+	lastError   string
+
+	logger *log.Logger
+}
+
+// New returns an Uploader which backs up db to storage.
+func New(db Database, storage Storage, c *Config) *Uploader {
+	return &Uploader{
+		db:      db,
+		storage: storage,
+		conf:    *c,
+		done:    make(chan struct{}),
+		logger:  log.New(os.Stderr, "[backup] ", log.LstdFlags),
+	}
+}
+
+// Start starts the backup loop.
+func (u *Uploader) Start() {
+	u.wg.Add(1)
+	go u.run()
+	u.logger.Printf("backing up every %s to %s", u.conf.Interval, u.storage)
+}
+
+// Close stops the backup loop, waiting for any backup in progress to finish.
+func (u *Uploader) Close() {
+	close(u.done)
+	u.wg.Wait()
+}
+
+// Stats returns the status of the Uploader.
+func (u *Uploader) Stats() (interface{}, error) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	status := map[string]interface{}{
+		"storage":  u.storage.String(),
+		"interval": u.conf.Interval.String(),
+		"format":   formatName(u.conf.Format),
+	}
+	if u.conf.RetainCount > 0 {
+		status["retain_count"] = u.conf.RetainCount
+	}
+	if u.conf.RetainAge > 0 {
+		status["retain_age"] = u.conf.RetainAge.String()
+	}
+	if !u.lastSuccess.IsZero() {
+		status["last_success_time"] = u.lastSuccess
+		status["last_success_name"] = u.lastName
+	}
+	if !u.lastFailure.IsZero() {
+		status["last_failure_time"] = u.lastFailure
+		status["last_failure_error"] = u.lastError
+	}
+	return status, nil
+}
+
+func (u *Uploader) run() {
+	defer u.wg.Done()
+	ticker := time.NewTicker(u.conf.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-u.done:
+			return
+		case <-ticker.C:
+			if !u.db.IsLeader() {
+				stats.Add(numSkipped, 1)
+				continue
+			}
+			name, err := u.backup(time.Now())
+			u.mu.Lock()
+			if err != nil {
+				stats.Add(numUploadErrors, 1)
+				u.lastFailure = time.Now()
+				u.lastError = err.Error()
+				u.logger.Printf("failed to back up to %s: %s", u.storage, err.Error())
+			} else {
+				stats.Add(numUploads, 1)
+				u.lastSuccess = time.Now()
+				u.lastName = name
+				u.logger.Printf("backed up to %s as %s", u.storage, name)
+			}
+			u.mu.Unlock()
+			if err == nil {
+				u.prune(time.Now())
+			}
+		}
+	}
+}
+
+// backup writes a compressed backup to a temporary file, and then uploads it.
+func (u *Uploader) backup(t time.Time) (string, error) {
+	f, err := ioutil.TempFile("", "tqlite-backup-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	gz, err := gzip.NewWriterLevel(f, gzip.BestCompression)
+	if err != nil {
+		return "", err
+	}
+	if err := u.db.Backup(true, u.conf.Format, gz); err != nil {
+		return "", fmt.Errorf("backup: %s", err)
+	}
+	if err := gz.Close(); err != nil {
+		return "", err
+	}
+
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	name := backupName(t, u.conf.Format)
+	if err := u.storage.Upload(name, f, size); err != nil {
+		return "", fmt.Errorf("upload: %s", err)
+	}
+	return name, nil
+}
+
+// prune deletes backups which fall outside the retention limits. The most
+// recent backup is always kept.
+func (u *Uploader) prune(now time.Time) {
+	if u.conf.RetainCount <= 0 && u.conf.RetainAge <= 0 {
+		return
+	}
+
+	names, err := u.storage.List()
+	if err != nil {
+		stats.Add(numDeleteErrors, 1)
+		u.logger.Printf("failed to list backups in %s: %s", u.storage, err.Error())
+		return
+	}
+
+	type backup struct {
+		name string
+		t    time.Time
+	}
+	var backups []backup
+	for _, n := range names {
+		if t, ok := parseBackupName(n); ok {
+			backups = append(backups, backup{n, t})
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].t.After(backups[j].t) })
+
+	for i := 1; i < len(backups); i++ {
+		expired := u.conf.RetainAge > 0 && now.Sub(backups[i].t) > u.conf.RetainAge
+		excess := u.conf.RetainCount > 0 && i >= u.conf.RetainCount
+		if !expired && !excess {
+			continue
+		}
+		if err := u.storage.Delete(backups[i].name); err != nil {
+			stats.Add(numDeleteErrors, 1)
+			u.logger.Printf("failed to delete backup %s: %s", backups[i].name, err.Error())
+			continue
+		}
+		stats.Add(numDeleted, 1)
+		u.logger.Printf("deleted backup %s", backups[i].name)
+	}
+}
+
+// backupName returns the name of a backup taken at t in the given format.
+func backupName(t time.Time, f store.BackupFormat) string {
+	ext := binaryExtension
+	if f == store.BackupSQL {
+		ext = sqlExtension
+	}
+	return namePrefix + t.UTC().Format(nameTimeFormat) + ext
+}
+
+// parseBackupName returns the time at which the backup with the given name
+// was taken. It returns false if the name is not that of a backup.
+func parseBackupName(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, namePrefix) {
+		return time.Time{}, false
+	}
+	s := strings.TrimPrefix(name, namePrefix)
+	switch {
+	case strings.HasSuffix(s, binaryExtension):
+		s = strings.TrimSuffix(s, binaryExtension)
+	case strings.HasSuffix(s, sqlExtension):
+		s = strings.TrimSuffix(s, sqlExtension)
+	default:
+		return time.Time{}, false
+	}
+	t, err := time.Parse(nameTimeFormat, s)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func formatName(f store.BackupFormat) string {
+	if f == store.BackupSQL {
+		return "sql"
+	}
+	return "binary"
+}
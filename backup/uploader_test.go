@@ -0,0 +1,218 @@
+package backup
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minghsu0107/tqlite/store"
+)
+
+func Test_BackupName(t *testing.T) {
+	ts := time.Date(2021, 7, 4, 13, 5, 9, 0, time.UTC)
+	tests := []struct {
+		f   store.BackupFormat
+		exp string
+	}{
+		{store.BackupBinary, "backup-20210704T130509Z.sqlite.gz"},
+		{store.BackupSQL, "backup-20210704T130509Z.sql.gz"},
+	}
+	for _, tt := range tests {
+		name := backupName(ts, tt.f)
+		if name != tt.exp {
+			t.Fatalf("wrong name, exp %s, got %s", tt.exp, name)
+		}
+		pt, ok := parseBackupName(name)
+		if !ok || !pt.Equal(ts) {
+			t.Fatalf("failed to parse %s, got %s, %v", name, pt, ok)
+		}
+	}
+
+	for _, n := range []string{"foo", "backup-20210704T130509Z", "backup-yesterday.sql.gz", "x-20210704T130509Z.sql.gz"} {
+		if _, ok := parseBackupName(n); ok {
+			t.Fatalf("parsed %s as the name of a backup", n)
+		}
+	}
+}
+
+func Test_UploaderBackup(t *testing.T) {
+	ls := mustNewLocalStorage(t)
+	db := &mockDatabase{isLeader: true, data: "CREATE TABLE foo (id INTEGER);"}
+	u := New(db, ls, &Config{Interval: time.Hour, Format: store.BackupSQL})
+
+	ts := time.Date(2021, 7, 4, 13, 5, 9, 0, time.UTC)
+	name, err := u.backup(ts)
+	if err != nil {
+		t.Fatalf("failed to back up: %s", err)
+	}
+	if exp := "backup-20210704T130509Z.sql.gz"; name != exp {
+		t.Fatalf("wrong name, exp %s, got %s", exp, name)
+	}
+	if db.format != store.BackupSQL || !db.leader {
+		t.Fatalf("backup requested with wrong arguments: format %d, leader %v", db.format, db.leader)
+	}
+
+	f, err := os.Open(filepath.Join(ls.dir, name))
+	if err != nil {
+		t.Fatalf("failed to open backup: %s", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("backup is not gzip-compressed: %s", err)
+	}
+	b, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("failed to read backup: %s", err)
+	}
+	if string(b) != db.data {
+		t.Fatalf("wrong backup content, exp %q, got %q", db.data, b)
+	}
+}
+
+func Test_UploaderBackupFail(t *testing.T) {
+	ls := mustNewLocalStorage(t)
+	db := &mockDatabase{isLeader: true, err: errors.New("database is locked")}
+	u := New(db, ls, &Config{Interval: time.Hour})
+
+	if _, err := u.backup(time.Now()); err == nil || !strings.Contains(err.Error(), "database is locked") {
+		t.Fatalf("expected backup error, got %v", err)
+	}
+	names, err := ls.List()
+	if err != nil {
+		t.Fatalf("failed to list backups: %s", err)
+	}
+	if len(names) != 0 {
+		t.Fatalf("failed backup was uploaded: %v", names)
+	}
+}
+
+func Test_UploaderPrune(t *testing.T) {
+	now := time.Date(2021, 7, 4, 12, 0, 0, 0, time.UTC)
+	hoursAgo := func(h ...int) []string {
+		var names []string
+		for _, n := range h {
+			names = append(names, backupName(now.Add(-time.Duration(n)*time.Hour), store.BackupBinary))
+		}
+		return names
+	}
+
+	tests := []struct {
+		name string
+		conf Config
+		have []string
+		exp  []string
+	}{
+		{
+			name: "no limits",
+			conf: Config{},
+			have: hoursAgo(1, 2, 3),
+			exp:  hoursAgo(1, 2, 3),
+		},
+		{
+			name: "count",
+			conf: Config{RetainCount: 2},
+			have: hoursAgo(1, 2, 3, 4),
+			exp:  hoursAgo(1, 2),
+		},
+		{
+			name: "age",
+			conf: Config{RetainAge: 150 * time.Minute},
+			have: hoursAgo(1, 2, 3, 4),
+			exp:  hoursAgo(1, 2),
+		},
+		{
+			name: "count and age",
+			conf: Config{RetainCount: 3, RetainAge: 150 * time.Minute},
+			have: hoursAgo(1, 2, 3, 4),
+			exp:  hoursAgo(1, 2),
+		},
+		{
+			name: "newest always kept",
+			conf: Config{RetainAge: time.Hour},
+			have: hoursAgo(5, 6),
+			exp:  hoursAgo(5),
+		},
+		{
+			name: "other files kept",
+			conf: Config{RetainCount: 1},
+			have: append(hoursAgo(1, 2), "notes.txt"),
+			exp:  append(hoursAgo(1), "notes.txt"),
+		},
+	}
+
+	for _, tt := range tests {
+		ls := mustNewLocalStorage(t)
+		for _, n := range tt.have {
+			if err := ls.Upload(n, strings.NewReader("x"), 1); err != nil {
+				t.Fatalf("%s: failed to upload %s: %s", tt.name, n, err)
+			}
+		}
+
+		u := New(&mockDatabase{}, ls, &tt.conf)
+		u.prune(now)
+
+		got, err := ls.List()
+		if err != nil {
+			t.Fatalf("%s: failed to list backups: %s", tt.name, err)
+		}
+		sort.Strings(got)
+		exp := append([]string(nil), tt.exp...)
+		sort.Strings(exp)
+		if strings.Join(got, ",") != strings.Join(exp, ",") {
+			t.Fatalf("%s: wrong backups kept\nexp: %v\ngot: %v", tt.name, exp, got)
+		}
+	}
+}
+
+func Test_UploaderSkipsFollower(t *testing.T) {
+	ls := mustNewLocalStorage(t)
+	db := &mockDatabase{}
+	u := New(db, ls, &Config{Interval: 10 * time.Millisecond})
+	u.Start()
+	time.Sleep(100 * time.Millisecond)
+	u.Close()
+
+	if db.calls != 0 {
+		t.Fatalf("follower was backed up %d times", db.calls)
+	}
+}
+
+type mockDatabase struct {
+	isLeader bool
+	leader   bool
+	format   store.BackupFormat
+	data     string
+	err      error
+	calls    int
+}
+
+func (m *mockDatabase) IsLeader() bool {
+	return m.isLeader
+}
+
+func (m *mockDatabase) Backup(leader bool, f store.BackupFormat, dst io.Writer) error {
+	m.calls++
+	m.leader = leader
+	m.format = f
+	if m.err != nil {
+		return m.err
+	}
+	_, err := io.WriteString(dst, m.data)
+	return err
+}
+
+func mustNewLocalStorage(t *testing.T) *LocalStorage {
+	ls, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create local storage: %s", err)
+	}
+	return ls
+}
@@ -0,0 +1,206 @@
+package cluster
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	httpd "github.com/minghsu0107/tqlite/http"
+)
+
+// defaultBootInterval is the default time between bootstrap attempts.
+const defaultBootInterval = 2 * time.Second
+
+// ErrBootTimeout is returned when a node does not join or bootstrap a
+// cluster within the timeout.
+var ErrBootTimeout = errors.New("boot timeout")
+
+// AddressProvider is the interface types must implement to provide the HTTP
+// API addresses of the nodes to a Bootstrapper.
+type AddressProvider interface {
+	Lookup() ([]string, error)
+}
+
+// BootStatus is how a node became a member of a cluster.
+type BootStatus int
+
+const (
+	// BootJoin means the node joined an existing cluster.
+	BootJoin BootStatus = iota
+
+	// BootDone means the node bootstrapped a new cluster, together with the
+	// other nodes.
+	BootDone
+)
+
+// Bootstrapper makes a node a member of a cluster, without the order nodes
+// start in mattering. It repeatedly looks up the addresses of the nodes, and
+// tries to join a cluster through them. Until that succeeds, it notifies every
+// node, itself included, that it is ready to bootstrap a new cluster. Each
+// node bootstraps the cluster once the expected number of nodes are ready.
+// Nodes are only notified while exactly the expected number of nodes are
+// found, so that every node bootstraps with the same nodes. Were more found,
+// different nodes could bootstrap with different nodes, forming more than
+// one cluster.
+type Bootstrapper struct {
+	provider  AddressProvider
+	expect    int
+	tlsConfig *tls.Config
+	secret    string
+	https     bool
+
+	// SrcIP, if set, is the source IP address of requests to other nodes.
+	SrcIP string
+
+	// Interval is the time between attempts.
+	Interval time.Duration
+
+	// NonVoter, if set, makes the node join the cluster as a non-voter. Such
+	// a node never notifies other nodes, so only joins a cluster once the
+	// voting nodes have bootstrapped it.
+	NonVoter bool
+
+	logger *log.Logger
+}
+
+// NewBootstrapper returns a Bootstrapper which bootstraps a cluster of expect
+// nodes, found through p. tlsConfig, if set, is used to connect to nodes
+// serving HTTPS, and https sets whether addresses without a scheme are
+// assumed to serve HTTPS. If secret is set, a join token created with it is
+// presented with every request.
+func NewBootstrapper(p AddressProvider, expect int, tlsConfig *tls.Config, https bool, secret string) *Bootstrapper {
+	return &Bootstrapper{
+		provider:  p,
+		expect:    expect,
+		tlsConfig: tlsConfig,
+		secret:    secret,
+		https:     https,
+		Interval:  defaultBootInterval,
+		logger:    log.New(os.Stderr, "[cluster-bootstrap] ", log.LstdFlags),
+	}
+}
+
+// Boot makes the node with the given ID, reachable over Raft at raftAddr, a
+// member of a cluster. done is called before every attempt, and must return
+// true once the node is a member of a cluster, which happens when the nodes
+// notified bootstrap it. Boot gives up after timeout.
+func (b *Bootstrapper) Boot(id, raftAddr string, done func() bool, timeout time.Duration) (BootStatus, error) {
+	// Notify using IP address, as that is what Hashicorp Raft works in.
+	resv, err := net.ResolveTCPAddr("tcp", raftAddr)
+	if err != nil {
+		return 0, err
+	}
+	client := newClient(b.SrcIP, b.tlsConfig)
+
+	tmr := time.NewTimer(timeout)
+	defer tmr.Stop()
+	tck := time.NewTicker(b.Interval)
+	defer tck.Stop()
+
+	var lastErr, lastFound string
+	for {
+		if done() {
+			b.logger.Println("cluster bootstrapped")
+			return BootDone, nil
+		}
+
+		addrs, err := b.lookup()
+		if err != nil {
+			if err.Error() != lastErr {
+				b.logger.Printf("failed to look up nodes: %s", err.Error())
+				lastErr = err.Error()
+			}
+		} else {
+			if found := strings.Join(addrs, ", "); found != lastFound {
+				b.logger.Printf("found %d of %d expected nodes: %s", len(addrs), b.expect, found)
+				if len(addrs) > b.expect {
+					b.logger.Printf("found more than %d nodes, not bootstrapping", b.expect)
+				}
+				lastFound = found
+			}
+
+			// A cluster may exist already, if this node is started after
+			// it was bootstrapped.
+			for _, a := range addrs {
+				j, err := join(b.SrcIP, a, id, raftAddr, !b.NonVoter, b.tlsConfig, b.secret, b.logger)
+				if err == nil {
+					b.logger.Printf("joined cluster at %s", j)
+					return BootJoin, nil
+				}
+				if errors.Is(err, ErrJoinRejected) {
+					return 0, err
+				}
+			}
+
+			if len(addrs) == b.expect && !b.NonVoter {
+				for _, a := range addrs {
+					if err := b.notify(client, a, id, resv.String()); err != nil {
+						b.logger.Printf("failed to notify %s: %s", a, err.Error())
+					}
+				}
+			}
+		}
+
+		select {
+		case <-tck.C:
+		case <-tmr.C:
+			return 0, ErrBootTimeout
+		}
+	}
+}
+
+// lookup returns the addresses of the nodes, with the HTTP scheme set.
+func (b *Bootstrapper) lookup() ([]string, error) {
+	addrs, err := b.provider.Lookup()
+	if err != nil {
+		return nil, err
+	}
+	full := make([]string, len(addrs))
+	for i := range addrs {
+		if b.https && !strings.HasPrefix(addrs[i], "http://") {
+			full[i] = httpd.EnsureHTTPS(addrs[i])
+		} else {
+			full[i] = httpd.NormalizeAddr(addrs[i])
+		}
+	}
+	return full, nil
+}
+
+// notify tells the node at nodeAddr that the node with the given ID, at
+// raftAddr, is ready to bootstrap a cluster.
+func (b *Bootstrapper) notify(client *http.Client, nodeAddr, id, raftAddr string) error {
+	md := map[string]interface{}{
+		"id":   id,
+		"addr": raftAddr,
+	}
+	if b.secret != "" {
+		now := time.Now()
+		md["timestamp"] = now.Unix()
+		md["token"] = httpd.JoinToken(b.secret, id, raftAddr, true, now)
+	}
+	body, err := json.Marshal(md)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Post(fmt.Sprintf("%s/notify", nodeAddr), "application-type/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("node returned: %s: (%s)", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
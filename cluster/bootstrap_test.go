@@ -0,0 +1,201 @@
+package cluster
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	httpd "github.com/minghsu0107/tqlite/http"
+)
+
+func Test_BootstrapperBootJoin(t *testing.T) {
+	n := newFakeNode(t, http.StatusOK)
+	bs := mustNewBootstrapper(t, []string{n.addr()}, 3, "")
+
+	status, err := bs.Boot("2", "127.0.0.1:4012", func() bool { return false }, time.Second)
+	if err != nil {
+		t.Fatalf("failed to boot: %s", err)
+	}
+	if status != BootJoin {
+		t.Fatalf("wrong boot status, exp %d, got %d", BootJoin, status)
+	}
+	joins, notifies := n.requests()
+	if len(joins) != 1 || len(notifies) != 0 {
+		t.Fatalf("expected one join and no notifications, got %d and %d", len(joins), len(notifies))
+	}
+	if joins[0]["id"] != "2" || joins[0]["addr"] != "127.0.0.1:4012" || joins[0]["voter"] != true {
+		t.Fatalf("wrong join request: %v", joins[0])
+	}
+}
+
+func Test_BootstrapperBootDone(t *testing.T) {
+	n1 := newFakeNode(t, http.StatusServiceUnavailable)
+	n2 := newFakeNode(t, http.StatusServiceUnavailable)
+	bs := mustNewBootstrapper(t, []string{n1.addr(), n2.addr()}, 2, "secret")
+
+	// The cluster is bootstrapped once both nodes are notified.
+	done := func() bool {
+		_, a := n1.requests()
+		_, b := n2.requests()
+		return len(a) > 0 && len(b) > 0
+	}
+	status, err := bs.Boot("1", "127.0.0.1:4002", done, 5*time.Second)
+	if err != nil {
+		t.Fatalf("failed to boot: %s", err)
+	}
+	if status != BootDone {
+		t.Fatalf("wrong boot status, exp %d, got %d", BootDone, status)
+	}
+
+	_, notifies := n1.requests()
+	md := notifies[0]
+	if md["id"] != "1" || md["addr"] != "127.0.0.1:4002" {
+		t.Fatalf("wrong notification: %v", md)
+	}
+	token, _ := md["token"].(string)
+	timestamp, _ := md["timestamp"].(float64)
+	if err := httpd.VerifyJoinToken("secret", token, "1", "127.0.0.1:4002", true,
+		time.Unix(int64(timestamp), 0)); err != nil {
+		t.Fatalf("notification carries invalid token: %s", err)
+	}
+}
+
+func Test_BootstrapperBootTimeout(t *testing.T) {
+	n := newFakeNode(t, http.StatusServiceUnavailable)
+	bs := mustNewBootstrapper(t, []string{n.addr()}, 2, "")
+
+	// Too few nodes are found to bootstrap, so none are notified.
+	_, err := bs.Boot("1", "127.0.0.1:4002", func() bool { return false }, 200*time.Millisecond)
+	if err != ErrBootTimeout {
+		t.Fatalf("expected boot timeout, got %v", err)
+	}
+	joins, notifies := n.requests()
+	if len(joins) == 0 || len(notifies) != 0 {
+		t.Fatalf("expected joins and no notifications, got %d and %d", len(joins), len(notifies))
+	}
+}
+
+func Test_BootstrapperBootTooManyNodes(t *testing.T) {
+	var nodes []*fakeNode
+	var addrs []string
+	for i := 0; i < 4; i++ {
+		n := newFakeNode(t, http.StatusServiceUnavailable)
+		nodes = append(nodes, n)
+		addrs = append(addrs, n.addr())
+	}
+	bs := mustNewBootstrapper(t, addrs, 3, "")
+
+	// Nodes finding different sets of 3 of the 4 nodes could bootstrap
+	// different clusters, so none are notified.
+	_, err := bs.Boot("1", "127.0.0.1:4002", func() bool { return false }, 200*time.Millisecond)
+	if err != ErrBootTimeout {
+		t.Fatalf("expected boot timeout, got %v", err)
+	}
+	for _, n := range nodes {
+		joins, notifies := n.requests()
+		if len(joins) == 0 || len(notifies) != 0 {
+			t.Fatalf("expected joins and no notifications, got %d and %d", len(joins), len(notifies))
+		}
+	}
+}
+
+func Test_BootstrapperBootRejected(t *testing.T) {
+	n := newFakeNode(t, http.StatusForbidden)
+	bs := mustNewBootstrapper(t, []string{n.addr()}, 1, "")
+
+	_, err := bs.Boot("1", "127.0.0.1:4002", func() bool { return false }, time.Second)
+	if !errors.Is(err, ErrJoinRejected) {
+		t.Fatalf("expected join rejection, got %v", err)
+	}
+}
+
+func Test_BootstrapperBootNonVoter(t *testing.T) {
+	n := newFakeNode(t, http.StatusServiceUnavailable)
+	bs := mustNewBootstrapper(t, []string{n.addr()}, 1, "")
+	bs.NonVoter = true
+
+	// A non-voter only joins, even once enough nodes are found.
+	if _, err := bs.Boot("3", "127.0.0.1:4022", func() bool { return false }, 200*time.Millisecond); err != ErrBootTimeout {
+		t.Fatalf("expected boot timeout, got %v", err)
+	}
+	joins, notifies := n.requests()
+	if len(joins) == 0 || len(notifies) != 0 {
+		t.Fatalf("expected joins and no notifications, got %d and %d", len(joins), len(notifies))
+	}
+	if joins[0]["voter"] != false {
+		t.Fatalf("non-voter joined as voter: %v", joins[0])
+	}
+}
+
+// fakeNode is a stand-in for the HTTP API of a node, which records the join
+// requests and bootstrap notifications it receives. It answers joins with
+// a fixed status.
+type fakeNode struct {
+	server     *httptest.Server
+	joinStatus int
+
+	mu       sync.Mutex
+	joins    []map[string]interface{}
+	notifies []map[string]interface{}
+}
+
+func newFakeNode(t *testing.T, joinStatus int) *fakeNode {
+	n := &fakeNode{joinStatus: joinStatus}
+	n.server = httptest.NewServer(n)
+	t.Cleanup(n.server.Close)
+	return n
+}
+
+func (n *fakeNode) addr() string {
+	return n.server.URL
+}
+
+func (n *fakeNode) requests() ([]map[string]interface{}, []map[string]interface{}) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.joins, n.notifies
+}
+
+func (n *fakeNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	md := map[string]interface{}{}
+	if err := json.Unmarshal(b, &md); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	switch r.URL.Path {
+	case "/join":
+		n.joins = append(n.joins, md)
+		w.WriteHeader(n.joinStatus)
+	case "/notify":
+		n.notifies = append(n.notifies, md)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+type staticProvider []string
+
+func (p staticProvider) Lookup() ([]string, error) {
+	return p, nil
+}
+
+func mustNewBootstrapper(t *testing.T, addrs []string, expect int, secret string) *Bootstrapper {
+	bs := NewBootstrapper(staticProvider(addrs), expect, nil, false, secret)
+	bs.Interval = 10 * time.Millisecond
+	bs.logger = log.New(ioutil.Discard, "", 0)
+	return bs
+}
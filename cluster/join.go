@@ -2,6 +2,7 @@ package cluster
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,7 +10,9 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"strings"
 	"time"
 
 	httpd "github.com/minghsu0107/tqlite/http"
@@ -18,25 +21,36 @@ import (
 var (
 	// ErrJoinFailed is returned when a node fails to join a cluster
 	ErrJoinFailed = errors.New("failed to join cluster")
+
+	// ErrJoinRejected is returned when a node refuses to join this node to
+	// its cluster, as its join token was not accepted.
+	ErrJoinRejected = errors.New("join rejected")
 )
 
 // Join attempts to join the cluster at one of the addresses given in joinAddr.
 // It walks through joinAddr in order, and sets the node ID and Raft address of
 // the joining node as id addr respectively. It returns the endpoint successfully
-// used to join the cluster.
+// used to join the cluster. tlsConfig, if set, is used to connect to nodes
+// serving HTTPS. If secret is set, a join token created with it is presented,
+// authorizing the node to join the cluster. Attempts stop once a node rejects
+// the token, since retrying cannot succeed.
 func Join(srcIP string, joinAddr []string, id, addr string, voter bool, numAttempts int,
-	attemptInterval time.Duration) (string, error) {
+	attemptInterval time.Duration, tlsConfig *tls.Config, secret string) (string, error) {
 	var err error
 	var j string
 	logger := log.New(os.Stderr, "[cluster-join] ", log.LstdFlags)
 
 	for i := 0; i < numAttempts; i++ {
 		for _, a := range joinAddr {
-			j, err = join(srcIP, a, id, addr, voter, logger)
+			j, err = join(srcIP, a, id, addr, voter, tlsConfig, secret, logger)
 			if err == nil {
 				// Success!
 				return j, nil
 			}
+			if errors.Is(err, ErrJoinRejected) {
+				logger.Printf("failed to join cluster at %s: %s", a, err.Error())
+				return "", err
+			}
 		}
 		logger.Printf("failed to join cluster at %s: %s, sleeping %s before retry", joinAddr, err.Error(), attemptInterval)
 		time.Sleep(attemptInterval)
@@ -45,20 +59,10 @@ func Join(srcIP string, joinAddr []string, id, addr string, voter bool, numAttem
 	return "", ErrJoinFailed
 }
 
-func join(srcIP, joinAddr, id, addr string, voter bool, logger *log.Logger) (string, error) {
+func join(srcIP, joinAddr, id, addr string, voter bool, tlsConfig *tls.Config, secret string, logger *log.Logger) (string, error) {
 	if id == "" {
 		return "", fmt.Errorf("node ID not set")
 	}
-	// The specified source IP is optional
-	var dialer *net.Dialer
-	dialer = &net.Dialer{}
-	if srcIP != "" {
-		netAddr := &net.TCPAddr{
-			IP:   net.ParseIP(srcIP),
-			Port: 0,
-		}
-		dialer = &net.Dialer{LocalAddr: netAddr}
-	}
 	// Join using IP address, as that is what Hashicorp Raft works in.
 	resv, err := net.ResolveTCPAddr("tcp", addr)
 	if err != nil {
@@ -69,20 +73,20 @@ func join(srcIP, joinAddr, id, addr string, voter bool, logger *log.Logger) (str
 	fullAddr := httpd.NormalizeAddr(fmt.Sprintf("%s/join", joinAddr))
 
 	// Create and configure the client to connect to the other node.
-	tr := &http.Transport{
-		Dial: dialer.Dial,
-	}
-	client := &http.Client{Transport: tr}
-	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
-		return http.ErrUseLastResponse
-	}
+	client := newClient(srcIP, tlsConfig)
 
 	for {
-		b, err := json.Marshal(map[string]interface{}{
+		md := map[string]interface{}{
 			"id":    id,
 			"addr":  resv.String(),
 			"voter": voter,
-		})
+		}
+		if secret != "" {
+			now := time.Now()
+			md["timestamp"] = now.Unix()
+			md["token"] = httpd.JoinToken(secret, id, resv.String(), voter, now)
+		}
+		b, err := json.Marshal(md)
 		if err != nil {
 			return "", err
 		}
@@ -103,13 +107,55 @@ func join(srcIP, joinAddr, id, addr string, voter bool, logger *log.Logger) (str
 		case http.StatusOK:
 			return fullAddr, nil
 		case http.StatusMovedPermanently:
-			fullAddr = resp.Header.Get("location")
-			if fullAddr == "" {
+			redirect := resp.Header.Get("location")
+			if redirect == "" {
 				return "", fmt.Errorf("failed to join, invalid redirect received")
 			}
+			fullAddr = withCredentials(redirect, fullAddr)
 			continue
+		case http.StatusForbidden:
+			return "", fmt.Errorf("%w by %s: %s", ErrJoinRejected, fullAddr, strings.TrimSpace(string(b)))
 		default:
 			return "", fmt.Errorf("failed to join, node returned: %s: (%s)", resp.Status, string(b))
 		}
 	}
 }
+
+// newClient returns an HTTP client for connecting to other nodes, which
+// connects from srcIP, if set, and does not follow redirects.
+func newClient(srcIP string, tlsConfig *tls.Config) *http.Client {
+	// The specified source IP is optional
+	dialer := &net.Dialer{}
+	if srcIP != "" {
+		netAddr := &net.TCPAddr{
+			IP:   net.ParseIP(srcIP),
+			Port: 0,
+		}
+		dialer = &net.Dialer{LocalAddr: netAddr}
+	}
+	tr := &http.Transport{
+		Dial:            dialer.Dial,
+		TLSClientConfig: tlsConfig,
+	}
+	client := &http.Client{Transport: tr}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return client
+}
+
+// withCredentials returns addr with the user credentials, if any, of from
+// added, so credentials given in a join address are sent to the leader when
+// redirected.
+func withCredentials(addr, from string) string {
+	f, err := url.Parse(from)
+	if err != nil || f.User == nil {
+		return addr
+	}
+	u, err := url.Parse(addr)
+	if err != nil || u.User != nil {
+		return addr
+	}
+	u.User = f.User
+	return u.String()
+}
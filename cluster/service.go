@@ -2,6 +2,7 @@ package cluster
 
 import (
 	"encoding/binary"
+	"errors"
 	"expvar"
 	"fmt"
 	"io"
@@ -12,9 +13,19 @@ import (
 	"sync"
 	"time"
 
+	"github.com/minghsu0107/tqlite/command"
+	sql "github.com/minghsu0107/tqlite/db"
+	httpd "github.com/minghsu0107/tqlite/http"
+	"github.com/minghsu0107/tqlite/store"
 	"google.golang.org/protobuf/proto"
 )
 
+var (
+	// ErrUnauthorized is returned when the credentials a command carries do
+	// not permit it.
+	ErrUnauthorized = errors.New("unauthorized")
+)
+
 // stats captures stats for the Cluster service.
 var stats *expvar.Map
 
@@ -22,6 +33,27 @@ const (
 	numGetNodeAPI         = "num_get_node_api"
 	numGetNodeAPIRequest  = "num_get_node_api_req"
 	numGetNodeAPIResponse = "num_get_node_api_resp"
+	numExecute            = "num_execute"
+	numExecuteRequest     = "num_execute_req"
+	numLoadDatabase       = "num_load_database"
+	numLoadDatabaseReq    = "num_load_database_req"
+	numLoadChunk          = "num_load_chunk"
+	numLoadChunkReq       = "num_load_chunk_req"
+	numJoin               = "num_join"
+	numJoinRequest        = "num_join_req"
+	numRemove             = "num_remove"
+	numRemoveRequest      = "num_remove_req"
+	numTransfer           = "num_transfer_leadership"
+	numTransferRequest    = "num_transfer_leadership_req"
+	numPromote            = "num_promote"
+	numPromoteRequest     = "num_promote_req"
+	numDemote             = "num_demote"
+	numDemoteRequest      = "num_demote_req"
+	numGetNodeStatus      = "num_get_node_status"
+	numGetNodeStatusReq   = "num_get_node_status_req"
+	numJoinRejections     = "num_join_rejections"
+	numUnauthorizedReq    = "num_unauthorized_req"
+	numOversizedReq       = "num_oversized_req"
 )
 
 const (
@@ -30,6 +62,10 @@ const (
 
 	// MuxClusterHeader is the byte used to request internode cluster state information.
 	MuxClusterHeader = 2 // Cluster state communications
+
+	// maxCommandSize is the size, in bytes, of the largest command the
+	// service accepts. Commands carry at most a SQLite database file.
+	maxCommandSize = 1 << 30
 )
 
 func init() {
@@ -37,6 +73,66 @@ func init() {
 	stats.Add(numGetNodeAPI, 0)
 	stats.Add(numGetNodeAPIRequest, 0)
 	stats.Add(numGetNodeAPIResponse, 0)
+	stats.Add(numExecute, 0)
+	stats.Add(numExecuteRequest, 0)
+	stats.Add(numLoadDatabase, 0)
+	stats.Add(numLoadDatabaseReq, 0)
+	stats.Add(numLoadChunk, 0)
+	stats.Add(numLoadChunkReq, 0)
+	stats.Add(numJoin, 0)
+	stats.Add(numJoinRequest, 0)
+	stats.Add(numRemove, 0)
+	stats.Add(numRemoveRequest, 0)
+	stats.Add(numTransfer, 0)
+	stats.Add(numTransferRequest, 0)
+	stats.Add(numPromote, 0)
+	stats.Add(numPromoteRequest, 0)
+	stats.Add(numDemote, 0)
+	stats.Add(numDemoteRequest, 0)
+	stats.Add(numGetNodeStatus, 0)
+	stats.Add(numGetNodeStatusReq, 0)
+	stats.Add(numJoinRejections, 0)
+	stats.Add(numUnauthorizedReq, 0)
+	stats.Add(numOversizedReq, 0)
+}
+
+// Database is the interface any queryable system must implement.
+type Database interface {
+	// Execute executes a slice of queries, each of which is not expected
+	// to return rows. The Raft index of the committed request is also returned.
+	Execute(er *command.ExecuteRequest) ([]*sql.Result, uint64, error)
+
+	// Load replaces the entire database with the SQLite database file in
+	// the request. The Raft index of the committed request is returned.
+	Load(lr *command.LoadRequest) (uint64, error)
+
+	// LoadChunk applies a chunk of SQL text which is part of a larger load.
+	// The progress of the load and the Raft index of the chunk are returned.
+	LoadChunk(lcr *command.LoadChunkRequest) (store.LoadProgress, uint64, error)
+}
+
+// Manager is the interface node-membership systems must implement.
+type Manager interface {
+	// Join joins the node with the given ID, reachable at addr, to the cluster.
+	Join(id, addr string, voter bool) error
+
+	// Remove removes the node, specified by id, from the cluster.
+	Remove(id string) error
+
+	// TransferLeadership transfers leadership to the node with the given ID,
+	// or to a node chosen by Raft if id is empty.
+	TransferLeadership(id string) error
+
+	// Promote makes the non-voting node with the given ID a voter. Unless
+	// force is set, the node must have caught up, having applied the log up
+	// to appliedIndex.
+	Promote(id string, appliedIndex uint64, force bool) error
+
+	// Demote makes the voting node with the given ID a non-voter.
+	Demote(id string) error
+
+	// NodeStatus returns a summary of the state of this node.
+	NodeStatus() (*store.NodeStatus, error)
 }
 
 // Transport is the interface the network layer must provide.
@@ -48,25 +144,39 @@ type Transport interface {
 	Dial(address string, timeout time.Duration) (net.Conn, error)
 }
 
-// Service provides information about the node and cluster.
+// Service provides information about the node and cluster. It also allows
+// a node to forward requests which must be handled by the leader.
 type Service struct {
 	tn      Transport // Network layer this service uses
 	addr    net.Addr  // Address on which this service is listening
 	timeout time.Duration
 
+	db  Database // The local database, which handles forwarded requests.
+	mgr Manager  // The local membership manager, which handles forwarded requests.
+
+	credentialStore httpd.CredentialStore // Credentials of users, nil if auth is disabled.
+
 	mu      sync.RWMutex
 	apiAddr string // host:port this node serves the HTTP API.
+	https   bool   // Serving HTTPS?
+	version string // Version of tqlite this node runs.
+	secret  string // Join secret, if joining nodes must present a token.
 
 	logger *log.Logger
 }
 
-// New returns a new instance of the cluster service
-func New(tn Transport) *Service {
+// New returns a new instance of the cluster service. If credentials is set,
+// commands which change the state of the cluster must carry the credentials
+// of a user permitted to make the change, as the HTTP API would require.
+func New(tn Transport, db Database, mgr Manager, credentials httpd.CredentialStore) *Service {
 	return &Service{
-		tn:      tn,
-		addr:    tn.Addr(),
-		timeout: 10 * time.Second,
-		logger:  log.New(os.Stderr, "[cluster] ", log.LstdFlags),
+		tn:              tn,
+		addr:            tn.Addr(),
+		timeout:         10 * time.Second,
+		db:              db,
+		mgr:             mgr,
+		credentialStore: credentials,
+		logger:          log.New(os.Stderr, "[cluster] ", log.LstdFlags),
 	}
 }
 
@@ -102,50 +212,316 @@ func (s *Service) GetAPIAddr() string {
 	return s.apiAddr
 }
 
+// EnableHTTPS tells the cluster service the API serves HTTPS.
+func (s *Service) EnableHTTPS(b bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.https = b
+}
+
+// SetJoinSecret sets the secret with which the join tokens of nodes, whose
+// joins are forwarded to this node, must be created. If empty, no token is
+// required.
+func (s *Service) SetJoinSecret(secret string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.secret = secret
+}
+
+// SetVersion sets the version of tqlite the cluster service reports.
+func (s *Service) SetVersion(v string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version = v
+}
+
 // GetNodeAPIAddr retrieves the API Address for the node at nodeAddr
 func (s *Service) GetNodeAPIAddr(nodeAddr string) (string, error) {
 	stats.Add(numGetNodeAPI, 1)
 
-	conn, err := s.tn.Dial(nodeAddr, s.timeout)
+	c := &Command{
+		Type: Command_COMMAND_TYPE_GET_NODE_API_URL,
+	}
+	b, err := s.send(nodeAddr, s.timeout, c)
 	if err != nil {
-		return "", fmt.Errorf("dial connection: %s", err)
+		return "", err
 	}
-	defer conn.Close()
 
-	// Send the request
+	a := &Address{}
+	err = proto.Unmarshal(b, a)
+	if err != nil {
+		return "", fmt.Errorf("protobuf unmarshal: %s", err)
+	}
+
+	return a.Url, nil
+}
+
+// Execute performs an Execute on a remote node, typically the leader.
+func (s *Service) Execute(er *command.ExecuteRequest, nodeAddr string, creds *command.Credentials, timeout time.Duration) ([]*sql.Result, uint64, error) {
+	stats.Add(numExecute, 1)
+	return s.execute(Command_COMMAND_TYPE_EXECUTE, er, nodeAddr, creds, timeout)
+}
+
+// LoadDatabase requests that the remote node, typically the leader, replaces
+// the entire database with the SQLite database file in the request.
+func (s *Service) LoadDatabase(lr *command.LoadRequest, nodeAddr string, creds *command.Credentials, timeout time.Duration) (uint64, error) {
+	stats.Add(numLoadDatabase, 1)
+
 	c := &Command{
-		Type: Command_COMMAND_TYPE_GET_NODE_API_URL,
+		Type: Command_COMMAND_TYPE_LOAD_DATABASE,
+		Request: &Command_LoadRequest{
+			LoadRequest: lr,
+		},
+		Credentials: creds,
 	}
-	p, err := proto.Marshal(c)
+	b, err := s.send(nodeAddr, timeout, c)
 	if err != nil {
-		return "", fmt.Errorf("command marshal: %s", err)
+		return 0, err
 	}
 
-	// Write length of Protobuf, the Protobuf
-	b := make([]byte, 4)
-	binary.LittleEndian.PutUint16(b[0:], uint16(len(p)))
+	a := &CommandLoadDatabaseResponse{}
+	if err := proto.Unmarshal(b, a); err != nil {
+		return 0, fmt.Errorf("protobuf unmarshal: %s", err)
+	}
+	if a.Error != "" {
+		return 0, errors.New(a.Error)
+	}
+	return a.RaftIndex, nil
+}
 
-	_, err = conn.Write(b)
+// LoadChunk requests that the remote node, typically the leader, applies a
+// chunk of SQL text which is part of a larger load.
+func (s *Service) LoadChunk(lcr *command.LoadChunkRequest, nodeAddr string, creds *command.Credentials, timeout time.Duration) (store.LoadProgress, uint64, error) {
+	stats.Add(numLoadChunk, 1)
+
+	c := &Command{
+		Type: Command_COMMAND_TYPE_LOAD_CHUNK,
+		Request: &Command_LoadChunkRequest{
+			LoadChunkRequest: lcr,
+		},
+		Credentials: creds,
+	}
+	b, err := s.send(nodeAddr, timeout, c)
 	if err != nil {
-		return "", fmt.Errorf("write protobuf length: %s", err)
+		return store.LoadProgress{}, 0, err
 	}
-	_, err = conn.Write(p)
+
+	a := &CommandLoadChunkResponse{}
+	if err := proto.Unmarshal(b, a); err != nil {
+		return store.LoadProgress{}, 0, fmt.Errorf("protobuf unmarshal: %s", err)
+	}
+	p := store.LoadProgress{
+		ID:         lcr.LoadId,
+		Offset:     a.Offset,
+		Chunks:     a.Chunks,
+		Statements: a.Statements,
+		Done:       a.Done,
+	}
+	if a.Error != "" {
+		return p, 0, errors.New(a.Error)
+	}
+	return p, a.RaftIndex, nil
+}
+
+// Join requests that the remote node, typically the leader, joins the node
+// with the given ID and address to the cluster. token is the join token the
+// node presented, created at t.
+func (s *Service) Join(id, addr string, voter bool, token string, t time.Time, nodeAddr string, creds *command.Credentials, timeout time.Duration) error {
+	stats.Add(numJoin, 1)
+
+	c := &Command{
+		Type: Command_COMMAND_TYPE_JOIN,
+		Request: &Command_JoinRequest{
+			JoinRequest: &JoinRequest{
+				Id:        id,
+				Address:   addr,
+				Voter:     voter,
+				Token:     token,
+				Timestamp: t.Unix(),
+			},
+		},
+		Credentials: creds,
+	}
+	b, err := s.send(nodeAddr, timeout, c)
 	if err != nil {
-		return "", fmt.Errorf("write protobuf: %s", err)
+		return err
 	}
 
-	b, err = ioutil.ReadAll(conn)
+	a := &CommandJoinResponse{}
+	if err := proto.Unmarshal(b, a); err != nil {
+		return fmt.Errorf("protobuf unmarshal: %s", err)
+	}
+	if a.Error != "" {
+		return errors.New(a.Error)
+	}
+	return nil
+}
+
+// Remove requests that the remote node, typically the leader, removes the
+// node with the given ID from the cluster.
+func (s *Service) Remove(id string, nodeAddr string, creds *command.Credentials, timeout time.Duration) error {
+	stats.Add(numRemove, 1)
+
+	c := &Command{
+		Type: Command_COMMAND_TYPE_REMOVE,
+		Request: &Command_RemoveRequest{
+			RemoveRequest: &RemoveRequest{
+				Id: id,
+			},
+		},
+		Credentials: creds,
+	}
+	b, err := s.send(nodeAddr, timeout, c)
 	if err != nil {
-		return "", fmt.Errorf("read protobuf bytes: %s", err)
+		return err
 	}
 
-	a := &Address{}
-	err = proto.Unmarshal(b, a)
+	a := &CommandRemoveResponse{}
+	if err := proto.Unmarshal(b, a); err != nil {
+		return fmt.Errorf("protobuf unmarshal: %s", err)
+	}
+	if a.Error != "" {
+		return errors.New(a.Error)
+	}
+	return nil
+}
+
+// TransferLeadership requests that the remote node, typically the leader,
+// transfers leadership to the node with the given ID.
+func (s *Service) TransferLeadership(id string, nodeAddr string, creds *command.Credentials, timeout time.Duration) error {
+	stats.Add(numTransfer, 1)
+
+	c := &Command{
+		Type: Command_COMMAND_TYPE_TRANSFER_LEADERSHIP,
+		Request: &Command_TransferLeadershipRequest{
+			TransferLeadershipRequest: &TransferLeadershipRequest{
+				Id: id,
+			},
+		},
+		Credentials: creds,
+	}
+	b, err := s.send(nodeAddr, timeout, c)
 	if err != nil {
-		return "", fmt.Errorf("protobuf unmarshal: %s", err)
+		return err
 	}
 
-	return a.Url, nil
+	a := &CommandTransferLeadershipResponse{}
+	if err := proto.Unmarshal(b, a); err != nil {
+		return fmt.Errorf("protobuf unmarshal: %s", err)
+	}
+	if a.Error != "" {
+		return errors.New(a.Error)
+	}
+	return nil
+}
+
+// Promote requests that the remote node, typically the leader, promotes
+// the node with the given ID to voter.
+func (s *Service) Promote(id string, appliedIndex uint64, force bool, nodeAddr string, creds *command.Credentials, timeout time.Duration) error {
+	stats.Add(numPromote, 1)
+
+	c := &Command{
+		Type: Command_COMMAND_TYPE_PROMOTE,
+		Request: &Command_PromoteRequest{
+			PromoteRequest: &PromoteRequest{
+				Id:           id,
+				AppliedIndex: appliedIndex,
+				Force:        force,
+			},
+		},
+		Credentials: creds,
+	}
+	b, err := s.send(nodeAddr, timeout, c)
+	if err != nil {
+		return err
+	}
+
+	a := &CommandPromoteResponse{}
+	if err := proto.Unmarshal(b, a); err != nil {
+		return fmt.Errorf("protobuf unmarshal: %s", err)
+	}
+	if a.Error != "" {
+		return errors.New(a.Error)
+	}
+	return nil
+}
+
+// Demote requests that the remote node, typically the leader, demotes the
+// node with the given ID to non-voter.
+func (s *Service) Demote(id string, nodeAddr string, creds *command.Credentials, timeout time.Duration) error {
+	stats.Add(numDemote, 1)
+
+	c := &Command{
+		Type: Command_COMMAND_TYPE_DEMOTE,
+		Request: &Command_DemoteRequest{
+			DemoteRequest: &DemoteRequest{
+				Id: id,
+			},
+		},
+		Credentials: creds,
+	}
+	b, err := s.send(nodeAddr, timeout, c)
+	if err != nil {
+		return err
+	}
+
+	a := &CommandDemoteResponse{}
+	if err := proto.Unmarshal(b, a); err != nil {
+		return fmt.Errorf("protobuf unmarshal: %s", err)
+	}
+	if a.Error != "" {
+		return errors.New(a.Error)
+	}
+	return nil
+}
+
+// GetNodeStatus retrieves a summary of the state of the node at nodeAddr.
+func (s *Service) GetNodeStatus(nodeAddr string, timeout time.Duration) (*store.NodeStatus, error) {
+	stats.Add(numGetNodeStatus, 1)
+
+	c := &Command{
+		Type: Command_COMMAND_TYPE_GET_NODE_STATUS,
+	}
+	b, err := s.send(nodeAddr, timeout, c)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &CommandNodeStatusResponse{}
+	if err := proto.Unmarshal(b, a); err != nil {
+		return nil, fmt.Errorf("protobuf unmarshal: %s", err)
+	}
+	if a.Error != "" {
+		return nil, errors.New(a.Error)
+	}
+	if a.Status == nil {
+		return nil, errors.New("node status missing from response")
+	}
+
+	st := &store.NodeStatus{
+		ID:           a.Status.Id,
+		Addr:         a.Status.RaftAddr,
+		State:        a.Status.State,
+		Term:         a.Status.Term,
+		CommitIndex:  a.Status.CommitIndex,
+		AppliedIndex: a.Status.AppliedIndex,
+		LastIndex:    a.Status.LastIndex,
+		DBSize:       a.Status.DbSize,
+		Snapshots:    int(a.Status.Snapshots),
+		APIAddr:      a.Status.ApiAddr,
+		Version:      a.Status.Version,
+	}
+	if a.Status.LastContact != 0 {
+		st.LastContact = time.Unix(0, a.Status.LastContact)
+	}
+	if cs := a.Status.Checksum; cs != nil {
+		st.Checksum = &store.Checksum{
+			Index:  cs.Index,
+			Tables: cs.Tables,
+			Error:  cs.Error,
+		}
+	}
+	return st, nil
 }
 
 // Stats returns status of the Service.
@@ -159,6 +535,67 @@ func (s *Service) Stats() (map[string]interface{}, error) {
 	return st, nil
 }
 
+func (s *Service) execute(typ Command_Type, er *command.ExecuteRequest, nodeAddr string, creds *command.Credentials, timeout time.Duration) ([]*sql.Result, uint64, error) {
+	c := &Command{
+		Type: typ,
+		Request: &Command_ExecuteRequest{
+			ExecuteRequest: er,
+		},
+		Credentials: creds,
+	}
+	b, err := s.send(nodeAddr, timeout, c)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	a := &CommandExecuteResponse{}
+	if err := proto.Unmarshal(b, a); err != nil {
+		return nil, 0, fmt.Errorf("protobuf unmarshal: %s", err)
+	}
+	if a.Error != "" {
+		return nil, 0, fromErrorCode(a.Error, a.ErrorCode)
+	}
+	return fromExecuteResults(a.Results), a.RaftIndex, nil
+}
+
+// send writes the given command to the node at nodeAddr, and returns the
+// bytes of the response.
+func (s *Service) send(nodeAddr string, timeout time.Duration, c *Command) ([]byte, error) {
+	conn, err := s.tn.Dial(nodeAddr, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("dial connection: %s", err)
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, fmt.Errorf("set deadline: %s", err)
+	}
+
+	p, err := proto.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("command marshal: %s", err)
+	}
+
+	// Write length of Protobuf, the Protobuf
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b[0:], uint32(len(p)))
+
+	_, err = conn.Write(b)
+	if err != nil {
+		return nil, fmt.Errorf("write protobuf length: %s", err)
+	}
+	_, err = conn.Write(p)
+	if err != nil {
+		return nil, fmt.Errorf("write protobuf: %s", err)
+	}
+
+	b, err = ioutil.ReadAll(conn)
+	if err != nil {
+		return nil, fmt.Errorf("read protobuf bytes: %s", err)
+	}
+	return b, nil
+}
+
 func (s *Service) serve() error {
 	for {
 		conn, err := s.tn.Accept()
@@ -178,35 +615,370 @@ func (s *Service) handleConn(conn net.Conn) {
 	if err != nil {
 		return
 	}
-	sz := binary.LittleEndian.Uint16(b[0:])
+	sz := binary.LittleEndian.Uint32(b[0:])
+	if sz > maxCommandSize {
+		stats.Add(numOversizedReq, 1)
+		s.logger.Printf("rejected command of %d bytes from %s", sz, conn.RemoteAddr())
+		return
+	}
 
-	b = make([]byte, sz)
-	_, err = io.ReadFull(conn, b)
-	if err != nil {
+	// Read the command as it arrives, rather than allocating the size the
+	// peer claims up front.
+	b, err = ioutil.ReadAll(io.LimitReader(conn, int64(sz)))
+	if err != nil || len(b) != int(sz) {
 		return
 	}
 
 	c := &Command{}
 	err = proto.Unmarshal(b, c)
 	if err != nil {
-		conn.Close()
+		return
 	}
 
 	switch c.Type {
 	case Command_COMMAND_TYPE_GET_NODE_API_URL:
 		stats.Add(numGetNodeAPIRequest, 1)
-		s.mu.RLock()
-		defer s.mu.RUnlock()
-
 		a := &Address{}
-		scheme := "http"
-		a.Url = fmt.Sprintf("%s://%s", scheme, s.apiAddr)
+		a.Url = s.apiURL()
 
 		b, err = proto.Marshal(a)
 		if err != nil {
-			conn.Close()
+			return
 		}
 		conn.Write(b)
 		stats.Add(numGetNodeAPIResponse, 1)
+
+	case Command_COMMAND_TYPE_EXECUTE:
+		stats.Add(numExecuteRequest, 1)
+		resp := &CommandExecuteResponse{}
+
+		er := c.GetExecuteRequest()
+		if !s.checkCommandPerm(conn, c, httpd.PermExecute) {
+			resp.Error = ErrUnauthorized.Error()
+		} else if er == nil {
+			resp.Error = "ExecuteRequest is nil"
+		} else {
+			results, idx, err := s.db.Execute(er)
+			if err != nil {
+				resp.Error = err.Error()
+				resp.ErrorCode = toErrorCode(err)
+			} else {
+				resp.Results = toExecuteResults(results)
+				resp.RaftIndex = idx
+			}
+		}
+
+		b, err = proto.Marshal(resp)
+		if err != nil {
+			return
+		}
+		conn.Write(b)
+
+	case Command_COMMAND_TYPE_LOAD_DATABASE:
+		stats.Add(numLoadDatabaseReq, 1)
+		resp := &CommandLoadDatabaseResponse{}
+
+		lr := c.GetLoadRequest()
+		if !s.checkCommandPerm(conn, c, httpd.PermLoad) {
+			resp.Error = ErrUnauthorized.Error()
+		} else if lr == nil {
+			resp.Error = "LoadRequest is nil"
+		} else {
+			idx, err := s.db.Load(lr)
+			if err != nil {
+				resp.Error = err.Error()
+			} else {
+				resp.RaftIndex = idx
+			}
+		}
+
+		b, err = proto.Marshal(resp)
+		if err != nil {
+			return
+		}
+		conn.Write(b)
+
+	case Command_COMMAND_TYPE_LOAD_CHUNK:
+		stats.Add(numLoadChunkReq, 1)
+		resp := &CommandLoadChunkResponse{}
+
+		lcr := c.GetLoadChunkRequest()
+		if !s.checkCommandPerm(conn, c, httpd.PermLoad) {
+			resp.Error = ErrUnauthorized.Error()
+		} else if lcr == nil {
+			resp.Error = "LoadChunkRequest is nil"
+		} else {
+			p, idx, err := s.db.LoadChunk(lcr)
+			resp.Offset = p.Offset
+			resp.Chunks = p.Chunks
+			resp.Statements = p.Statements
+			resp.Done = p.Done
+			if err != nil {
+				resp.Error = err.Error()
+			} else {
+				resp.RaftIndex = idx
+			}
+		}
+
+		b, err = proto.Marshal(resp)
+		if err != nil {
+			return
+		}
+		conn.Write(b)
+
+	case Command_COMMAND_TYPE_JOIN:
+		stats.Add(numJoinRequest, 1)
+		resp := &CommandJoinResponse{}
+
+		jr := c.GetJoinRequest()
+		if !s.checkCommandPerm(conn, c, httpd.PermJoin) {
+			resp.Error = ErrUnauthorized.Error()
+		} else if jr == nil {
+			resp.Error = "JoinRequest is nil"
+		} else if err := s.checkJoinToken(conn, jr); err != nil {
+			resp.Error = err.Error()
+		} else if err := s.mgr.Join(jr.Id, jr.Address, jr.Voter); err != nil {
+			resp.Error = err.Error()
+		}
+
+		b, err = proto.Marshal(resp)
+		if err != nil {
+			return
+		}
+		conn.Write(b)
+
+	case Command_COMMAND_TYPE_REMOVE:
+		stats.Add(numRemoveRequest, 1)
+		resp := &CommandRemoveResponse{}
+
+		rr := c.GetRemoveRequest()
+		if !s.checkCommandPerm(conn, c, httpd.PermRemove) {
+			resp.Error = ErrUnauthorized.Error()
+		} else if rr == nil {
+			resp.Error = "RemoveRequest is nil"
+		} else if err := s.mgr.Remove(rr.Id); err != nil {
+			resp.Error = err.Error()
+		}
+
+		b, err = proto.Marshal(resp)
+		if err != nil {
+			return
+		}
+		conn.Write(b)
+
+	case Command_COMMAND_TYPE_PROMOTE:
+		stats.Add(numPromoteRequest, 1)
+		resp := &CommandPromoteResponse{}
+
+		pr := c.GetPromoteRequest()
+		if !s.checkCommandPerm(conn, c, httpd.PermJoin) {
+			resp.Error = ErrUnauthorized.Error()
+		} else if pr == nil {
+			resp.Error = "PromoteRequest is nil"
+		} else if err := s.mgr.Promote(pr.Id, pr.AppliedIndex, pr.Force); err != nil {
+			resp.Error = err.Error()
+		}
+
+		b, err = proto.Marshal(resp)
+		if err != nil {
+			return
+		}
+		conn.Write(b)
+
+	case Command_COMMAND_TYPE_DEMOTE:
+		stats.Add(numDemoteRequest, 1)
+		resp := &CommandDemoteResponse{}
+
+		dr := c.GetDemoteRequest()
+		if !s.checkCommandPerm(conn, c, httpd.PermRemove) {
+			resp.Error = ErrUnauthorized.Error()
+		} else if dr == nil {
+			resp.Error = "DemoteRequest is nil"
+		} else if err := s.mgr.Demote(dr.Id); err != nil {
+			resp.Error = err.Error()
+		}
+
+		b, err = proto.Marshal(resp)
+		if err != nil {
+			return
+		}
+		conn.Write(b)
+
+	case Command_COMMAND_TYPE_TRANSFER_LEADERSHIP:
+		stats.Add(numTransferRequest, 1)
+		resp := &CommandTransferLeadershipResponse{}
+
+		tr := c.GetTransferLeadershipRequest()
+		if !s.checkCommandPerm(conn, c, httpd.PermLeadership) {
+			resp.Error = ErrUnauthorized.Error()
+		} else if tr == nil {
+			resp.Error = "TransferLeadershipRequest is nil"
+		} else if err := s.mgr.TransferLeadership(tr.Id); err != nil {
+			resp.Error = err.Error()
+		}
+
+		b, err = proto.Marshal(resp)
+		if err != nil {
+			return
+		}
+		conn.Write(b)
+
+	case Command_COMMAND_TYPE_GET_NODE_STATUS:
+		stats.Add(numGetNodeStatusReq, 1)
+		resp := &CommandNodeStatusResponse{}
+
+		if st, err := s.mgr.NodeStatus(); err != nil {
+			resp.Error = err.Error()
+		} else {
+			s.mu.RLock()
+			version := s.version
+			s.mu.RUnlock()
+			resp.Status = &NodeStatus{
+				Id:           st.ID,
+				RaftAddr:     st.Addr,
+				ApiAddr:      s.apiURL(),
+				State:        st.State,
+				Term:         st.Term,
+				CommitIndex:  st.CommitIndex,
+				AppliedIndex: st.AppliedIndex,
+				LastIndex:    st.LastIndex,
+				DbSize:       st.DBSize,
+				Snapshots:    uint64(st.Snapshots),
+				Version:      version,
+			}
+			if !st.LastContact.IsZero() {
+				resp.Status.LastContact = st.LastContact.UnixNano()
+			}
+			if st.Checksum != nil {
+				resp.Status.Checksum = &Checksum{
+					Index:  st.Checksum.Index,
+					Tables: st.Checksum.Tables,
+					Error:  st.Checksum.Error,
+				}
+			}
+		}
+
+		b, err = proto.Marshal(resp)
+		if err != nil {
+			return
+		}
+		conn.Write(b)
+	}
+}
+
+// checkCommandPerm returns whether the credentials c carries are valid, and
+// the user is authorized with the given perm. Unauthorized commands are
+// counted and logged.
+func (s *Service) checkCommandPerm(conn net.Conn, c *Command, perm string) bool {
+	if s.credentialStore == nil {
+		return true
 	}
+	creds := c.GetCredentials()
+	if creds != nil && s.credentialStore.Check(creds.Username, creds.Password) &&
+		s.credentialStore.HasAnyPerm(creds.Username, perm, httpd.PermAll) {
+		return true
+	}
+	stats.Add(numUnauthorizedReq, 1)
+	s.logger.Printf("rejected unauthorized %s command from %s", c.Type, conn.RemoteAddr())
+	return false
+}
+
+// checkJoinToken returns an error unless the join secret is not set, or jr
+// carries a valid join token created with it. Rejections are counted and
+// logged.
+func (s *Service) checkJoinToken(conn net.Conn, jr *JoinRequest) error {
+	s.mu.RLock()
+	secret := s.secret
+	s.mu.RUnlock()
+	if secret == "" {
+		return nil
+	}
+	err := httpd.VerifyJoinToken(secret, jr.Token, jr.Id, jr.Address, jr.Voter, time.Unix(jr.Timestamp, 0))
+	if err != nil {
+		stats.Add(numJoinRejections, 1)
+		s.logger.Printf("rejected join request of node %s at %s from %s: %s",
+			jr.Id, jr.Address, conn.RemoteAddr(), err.Error())
+	}
+	return err
+}
+
+// apiURL returns the URL of the HTTP API of this node.
+func (s *Service) apiURL() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	scheme := "http"
+	if s.https {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s", scheme, s.apiAddr)
+}
+
+// toExecuteResults converts database results to their Protobuf form.
+func toExecuteResults(results []*sql.Result) []*ExecuteResult {
+	ers := make([]*ExecuteResult, len(results))
+	for i := range results {
+		ers[i] = &ExecuteResult{
+			LastInsertId: results[i].LastInsertID,
+			RowsAffected: results[i].RowsAffected,
+			Error:        results[i].Error,
+			Time:         results[i].Time,
+		}
+	}
+	return ers
+}
+
+// fromExecuteResults converts Protobuf results to their database form.
+func fromExecuteResults(ers []*ExecuteResult) []*sql.Result {
+	results := make([]*sql.Result, len(ers))
+	for i := range ers {
+		results[i] = &sql.Result{
+			LastInsertID: ers[i].LastInsertId,
+			RowsAffected: ers[i].RowsAffected,
+			Error:        ers[i].Error,
+			Time:         ers[i].Time,
+		}
+	}
+	return results
+}
+
+// toErrorCode returns the code which identifies err to the node which
+// forwarded the request.
+func toErrorCode(err error) CommandExecuteResponse_ErrorCode {
+	switch {
+	case errors.Is(err, sql.ErrPreconditionFailed):
+		return CommandExecuteResponse_ERROR_CODE_PRECONDITION_FAILED
+	case errors.Is(err, store.ErrIdempotencyKeyReused):
+		return CommandExecuteResponse_ERROR_CODE_IDEMPOTENCY_KEY_REUSED
+	default:
+		return CommandExecuteResponse_ERROR_CODE_NONE
+	}
+}
+
+// fromErrorCode returns the error with the given text and code, as returned
+// to a forwarded request. As far as errors.Is is concerned, it is the error
+// the code identifies.
+func fromErrorCode(msg string, code CommandExecuteResponse_ErrorCode) error {
+	switch code {
+	case CommandExecuteResponse_ERROR_CODE_PRECONDITION_FAILED:
+		return &forwardedError{msg: msg, err: sql.ErrPreconditionFailed}
+	case CommandExecuteResponse_ERROR_CODE_IDEMPOTENCY_KEY_REUSED:
+		return &forwardedError{msg: msg, err: store.ErrIdempotencyKeyReused}
+	default:
+		return errors.New(msg)
+	}
+}
+
+// forwardedError is an error returned to a forwarded request, which keeps the
+// text of the error on the remote node.
+type forwardedError struct {
+	msg string
+	err error
+}
+
+func (e *forwardedError) Error() string {
+	return e.msg
+}
+
+func (e *forwardedError) Unwrap() error {
+	return e.err
 }
@@ -0,0 +1,29 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	sql "github.com/minghsu0107/tqlite/db"
+	"github.com/minghsu0107/tqlite/store"
+)
+
+func Test_ErrorCode(t *testing.T) {
+	for _, target := range []error{sql.ErrPreconditionFailed, store.ErrIdempotencyKeyReused} {
+		err := fmt.Errorf("%w: detail", target)
+		fwd := fromErrorCode(err.Error(), toErrorCode(err))
+		if !errors.Is(fwd, target) {
+			t.Fatalf("forwarded error %q is not %q", fwd, target)
+		}
+		if fwd.Error() != err.Error() {
+			t.Fatalf("wrong forwarded error text, exp %q, got %q", err, fwd)
+		}
+	}
+
+	// Other errors keep only their text.
+	fwd := fromErrorCode("other", toErrorCode(errors.New("other")))
+	if errors.Is(fwd, sql.ErrPreconditionFailed) || errors.Is(fwd, store.ErrIdempotencyKeyReused) {
+		t.Fatalf("forwarded error %q has wrong code", fwd)
+	}
+}
@@ -2,10 +2,16 @@ package main
 
 import (
 	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/mkideal/cli"
 )
@@ -14,10 +20,27 @@ type backupResponse struct {
 	BackupFile []byte
 }
 
+type loadProgress struct {
+	ID         string `json:"id"`
+	Offset     int64  `json:"offset"`
+	Chunks     uint64 `json:"chunks"`
+	Statements uint64 `json:"statements"`
+	Done       bool   `json:"done"`
+}
+
 type restoreResponse struct {
-	Results []*Result `json:"results"`
+	Load  *loadProgress `json:"load"`
+	Error string        `json:"error,omitempty"`
+}
+
+type restoreDatabaseResponse struct {
+	Error     string `json:"error,omitempty"`
+	RaftIndex uint64 `json:"raft_index,omitempty"`
 }
 
+// sqliteHeader is the header at the start of every SQLite database file.
+const sqliteHeader = "SQLite format 3\x00"
+
 type sqliteStatus struct {
 	FkConstraint string `json:"fk_constraints"`
 }
@@ -83,22 +106,103 @@ func dump(ctx *cli.Context, filename string, argv *argT) error {
 	return nil
 }
 
-func makeRestoreRequest(b []byte) func(string) (*http.Request, error) {
+// makeRestoreRequest returns a function making requests which send the SQL
+// text in filename, followed by trailer, starting at the given offset.
+func makeRestoreRequest(ctx *cli.Context, filename, trailer string, offset int64) func(string) (*http.Request, error) {
 	return func(urlStr string) (*http.Request, error) {
-		req, err := http.NewRequest("POST", urlStr, bytes.NewReader(b))
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		fi, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		total := fi.Size() + int64(len(trailer))
+
+		var body io.Reader
+		if offset <= fi.Size() {
+			if _, err := f.Seek(offset, io.SeekStart); err != nil {
+				f.Close()
+				return nil, err
+			}
+			body = io.MultiReader(f, strings.NewReader(trailer))
+		} else {
+			body = strings.NewReader(trailer[offset-fi.Size():])
+		}
+
+		req, err := http.NewRequest("POST", urlStr, &progressReader{
+			Reader: body,
+			Closer: f,
+			ctx:    ctx,
+			n:      offset,
+			total:  total,
+		})
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		req.ContentLength = total - offset
 		req.Header["Content-type"] = []string{"text/plain"}
+		return req, nil
+	}
+}
+
+// progressReader reports how much of the SQL text has been sent as it is read.
+type progressReader struct {
+	io.Reader
+	io.Closer
+	ctx      *cli.Context
+	n, total int64
+	pct      int64
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.Reader.Read(b)
+	p.n += int64(n)
+	if p.total > 0 {
+		if pct := p.n * 100 / p.total; pct != p.pct {
+			p.pct = pct
+			p.ctx.String("\r%d%% sent", pct)
+		}
+	}
+	return n, err
+}
+
+func makeRestoreDatabaseRequest(b []byte) func(string) (*http.Request, error) {
+	return func(urlStr string) (*http.Request, error) {
+		req, err := http.NewRequest("POST", urlStr, bytes.NewReader(b))
 		if err != nil {
 			return nil, err
 		}
+		req.Header["Content-type"] = []string{"application/octet-stream"}
 		return req, nil
 	}
 }
 
-func restore(ctx *cli.Context, filename string, argv *argT) error {
+func restore(ctx *cli.Context, filename, loadID string, argv *argT) error {
+	hdr := make([]byte, len(sqliteHeader))
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	n, _ := io.ReadFull(f, hdr)
+	f.Close()
+	if string(hdr[:n]) == sqliteHeader {
+		restoreFile, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		return restoreDatabase(ctx, restoreFile, argv)
+	}
+
 	statusURL := fmt.Sprintf("%s://%s:%d/status", argv.Protocol, argv.Host, argv.Port)
-	client := http.Client{Transport: &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-	}}
+	tr, err := getHTTPTransport(argv)
+	if err != nil {
+		return err
+	}
+	client := http.Client{Transport: tr}
 
 	req, err := http.NewRequest("GET", statusURL, nil)
 	if err != nil {
@@ -127,45 +231,119 @@ func restore(ctx *cli.Context, filename string, argv *argT) error {
 		return fmt.Errorf("unexpected server response: store status not found")
 	}
 
-	restoreFile, err := ioutil.ReadFile(filename)
-	if err != nil {
-		return err
+	// It is cheaper to append the actual pragma command to the restore file
+	trailer := "PRAGMA foreign_keys=OFF;"
+	if statusRet.Store.SqliteStatus.FkConstraint == "enabled" {
+		trailer = "PRAGMA foreign_keys=ON;"
 	}
 
-	// It is cheaper to append the actual pragma command to the restore file
-	fkEnabled := statusRet.Store.SqliteStatus.FkConstraint == "enabled"
-	if fkEnabled {
-		restoreFile = append(restoreFile, []byte("PRAGMA foreign_keys=ON;")...)
+	// Resume the load from where it stopped, if a load ID is given.
+	var offset int64
+	if loadID == "" {
+		if loadID, err = newLoadID(); err != nil {
+			return err
+		}
 	} else {
-		restoreFile = append(restoreFile, []byte("PRAGMA foreign_keys=OFF;")...)
+		p, err := getLoadProgress(ctx, loadID, argv)
+		if err != nil {
+			return err
+		}
+		if p.Done {
+			ctx.String("load %s is already complete\n", loadID)
+			return nil
+		}
+		offset = p.Offset
+		ctx.String("resuming load %s at offset %d\n", loadID, offset)
 	}
 
 	queryStr := url.Values{}
+	queryStr.Set("load_id", loadID)
+	queryStr.Set("offset", strconv.FormatInt(offset, 10))
 	restoreURL := url.URL{
 		Scheme:   argv.Protocol,
 		Host:     fmt.Sprintf("%s:%d", argv.Host, argv.Port),
 		Path:     fmt.Sprintf("%sdb/load", argv.Prefix),
 		RawQuery: queryStr.Encode(),
 	}
-	response, err := sendRequest(ctx, makeRestoreRequest(restoreFile), restoreURL.String(), argv)
+	response, err := sendRequest(ctx, makeRestoreRequest(ctx, filename, trailer, offset), restoreURL.String(), argv)
+	ctx.String("\n")
 	if err != nil {
-		return err
+		return fmt.Errorf("%s, resume with \".restore %s %s\"", err, filename, loadID)
 	}
 
 	restoreRet := &restoreResponse{}
 	if err := parseResponse(response, &restoreRet); err != nil {
 		return err
 	}
-	if len(restoreRet.Results) < 1 {
-		return fmt.Errorf("unexpected results length: %d", len(restoreRet.Results))
+	if restoreRet.Load == nil {
+		return fmt.Errorf("unexpected server response: load progress not found")
+	}
+	if restoreRet.Error != "" {
+		ctx.String("Error: %s\n", restoreRet.Error)
+		ctx.String("load %s stopped at offset %d, resume with \".restore %s %s\"\n",
+			loadID, restoreRet.Load.Offset, filename, loadID)
+		return nil
+	}
+
+	ctx.String("statements loaded: %d\n", restoreRet.Load.Statements)
+	ctx.String("database restored successfully\n")
+	return nil
+}
+
+// getLoadProgress returns the progress of the load with the given ID.
+func getLoadProgress(ctx *cli.Context, loadID string, argv *argT) (*loadProgress, error) {
+	queryStr := url.Values{}
+	queryStr.Set("load_id", loadID)
+	u := url.URL{
+		Scheme:   argv.Protocol,
+		Host:     fmt.Sprintf("%s:%d", argv.Host, argv.Port),
+		Path:     fmt.Sprintf("%sdb/load", argv.Prefix),
+		RawQuery: queryStr.Encode(),
+	}
+	response, err := sendRequest(ctx, makeBackupRequest, u.String(), argv)
+	if err != nil {
+		return nil, fmt.Errorf("get progress of load %s: %s", loadID, err)
+	}
+	ret := &restoreResponse{}
+	if err := parseResponse(response, &ret); err != nil {
+		return nil, err
+	}
+	if ret.Load == nil {
+		return nil, fmt.Errorf("unexpected server response: load progress not found")
+	}
+	return ret.Load, nil
+}
+
+// newLoadID returns a random load ID.
+func newLoadID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// restoreDatabase replaces the database with the SQLite database file b.
+func restoreDatabase(ctx *cli.Context, b []byte, argv *argT) error {
+	restoreURL := url.URL{
+		Scheme: argv.Protocol,
+		Host:   fmt.Sprintf("%s:%d", argv.Host, argv.Port),
+		Path:   fmt.Sprintf("%sdb/load", argv.Prefix),
+	}
+	response, err := sendRequest(ctx, makeRestoreDatabaseRequest(b), restoreURL.String(), argv)
+	if err != nil {
+		return err
+	}
+
+	restoreRet := &restoreDatabaseResponse{}
+	if err := parseResponse(response, &restoreRet); err != nil {
+		return err
 	}
-	if resultError := restoreRet.Results[0].Error; resultError != "" {
-		ctx.String("Error: %s\n", resultError)
+	if restoreRet.Error != "" {
+		ctx.String("Error: %s\n", restoreRet.Error)
 		return nil
 	}
 
-	ctx.String("last inserted ID: %d\n", restoreRet.Results[0].LastInsertID)
-	ctx.String("rows affected: %d\n", restoreRet.Results[0].RowsAffected)
 	ctx.String("database restored successfully\n")
 	return nil
 }
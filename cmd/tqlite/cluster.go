@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/mkideal/cli"
+	"github.com/mkideal/pkg/textutil"
+)
+
+// clusterNode is the state of a node, as reported by /cluster.
+type clusterNode struct {
+	ID           string     `json:"id"`
+	Addr         string     `json:"addr"`
+	APIAddr      string     `json:"api_addr"`
+	Suffrage     string     `json:"suffrage"`
+	Reachable    bool       `json:"reachable"`
+	Leader       bool       `json:"leader"`
+	State        string     `json:"state"`
+	Term         uint64     `json:"term"`
+	CommitIndex  uint64     `json:"commit_index"`
+	AppliedIndex uint64     `json:"applied_index"`
+	LastIndex    uint64     `json:"last_index"`
+	Lag          *uint64    `json:"lag"`
+	LastContact  *time.Time `json:"last_contact"`
+	DBSize       int64      `json:"db_size"`
+	Snapshots    int        `json:"snapshots"`
+	Version      string     `json:"version"`
+	Error        string     `json:"error"`
+}
+
+type clusterResponse struct {
+	Leader string         `json:"leader"`
+	Nodes  []*clusterNode `json:"nodes"`
+}
+
+var clusterColumns = []string{"id", "state", "suffrage", "raft_addr", "api_addr", "term",
+	"commit", "applied", "last", "lag", "last_contact", "db_size", "snapshots", "version"}
+
+// clusterStatus shows the state of every node in the cluster as a table.
+func clusterStatus(ctx *cli.Context, client *http.Client, argv *argT) error {
+	u := url.URL{
+		Scheme: argv.Protocol,
+		Host:   fmt.Sprintf("%s:%d", argv.Host, argv.Port),
+		Path:   fmt.Sprintf("%scluster", argv.Prefix),
+	}
+
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return err
+	}
+	response, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return fmt.Errorf("unauthorized")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server responded with %s: %s", resp.Status, response)
+	}
+
+	ret := &clusterResponse{}
+	if err := parseResponse(&response, ret); err != nil {
+		return err
+	}
+
+	rows := &Rows{Columns: clusterColumns}
+	for _, n := range ret.Nodes {
+		if !n.Reachable {
+			rows.Values = append(rows.Values, []interface{}{n.ID, "Unreachable", n.Suffrage, n.Addr,
+				"", "", "", "", "", "", "", "", "", ""})
+			continue
+		}
+		lag, lastContact := "", ""
+		if n.Lag != nil {
+			lag = fmt.Sprint(*n.Lag)
+		}
+		if n.LastContact != nil {
+			lastContact = fmt.Sprintf("%s ago", time.Since(*n.LastContact).Round(time.Millisecond))
+		}
+		rows.Values = append(rows.Values, []interface{}{n.ID, n.State, n.Suffrage, n.Addr, n.APIAddr,
+			n.Term, n.CommitIndex, n.AppliedIndex, n.LastIndex, lag, lastContact, n.DBSize, n.Snapshots, n.Version})
+	}
+	textutil.WriteTable(ctx, rows, headerRender)
+
+	for _, n := range ret.Nodes {
+		if n.Error != "" {
+			ctx.String("node %s: %s\n", n.ID, n.Error)
+		}
+	}
+	return nil
+}
@@ -2,6 +2,8 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -22,20 +24,26 @@ const maxRedirect = 21
 
 type argT struct {
 	cli.Helper
-	Protocol string `cli:"s,scheme" usage:"protocol scheme" dft:"http"`
-	Host     string `cli:"H,host" usage:"tqlited host address" dft:"127.0.0.1"`
-	Port     uint16 `cli:"p,port" usage:"tqlited host port" dft:"4001"`
-	Prefix   string `cli:"P,prefix" usage:"tqlited HTTP URL prefix" dft:"/"`
-	Version  bool   `cli:"v,version" usage:"display CLI version"`
+	Protocol    string `cli:"s,scheme" usage:"protocol scheme" dft:"http"`
+	Host        string `cli:"H,host" usage:"tqlited host address" dft:"127.0.0.1"`
+	Port        uint16 `cli:"p,port" usage:"tqlited host port" dft:"4001"`
+	Prefix      string `cli:"P,prefix" usage:"tqlited HTTP URL prefix" dft:"/"`
+	Insecure    bool   `cli:"i,insecure" usage:"do not verify tqlited HTTPS certificate" dft:"false"`
+	CACert      string `cli:"c,ca-cert" usage:"path to trusted X.509 root CA certificate"`
+	ClientCert  string `cli:"client-cert" usage:"path to client X.509 certificate, presented to tqlited"`
+	ClientKey   string `cli:"client-key" usage:"path to client X.509 private key"`
+	Credentials string `cli:"u,user" usage:"set basic auth credentials in form username:password"`
+	Version     bool   `cli:"v,version" usage:"display CLI version"`
 }
 
 var cliHelp = []string{
 	`.backup <file>            Write database backup to SQLite file`,
+	`.cluster                  Show Raft and database state of every node in cluster`,
 	`.dump <file>              Dump the database in SQL text format to a file`,
 	`.expvar                   Show expvar (Go runtime) information for connected node`,
 	`.help                     Show this message`,
 	`.indexes                  Show names of all indexes`,
-	`.restore <file>           Restore the database from a SQLite dump file`,
+	`.restore <file> [load ID] Restore the database from a SQLite file or dump, resuming the load if given`,
 	`.nodes                    Show connection status of all nodes in cluster`,
 	`.schema                   Show CREATE statements for all tables`,
 	`.status                   Show status and diagnostic information for connected node`,
@@ -43,6 +51,8 @@ var cliHelp = []string{
 	`.tables                   List names of tables`,
 	`.timer on|off             Turn query timer on or off`,
 	`.remove <raft ID>         Remove a node from the cluster`,
+	`.stepdown [raft ID]       Transfer leadership to the given node, or any node`,
+	`.verify                   Check that every node in cluster holds the same data as the leader`,
 }
 
 func main() {
@@ -108,21 +118,31 @@ func main() {
 			cmd = strings.ToUpper(cmd)
 			switch cmd {
 			case ".TABLES":
-				err = queryWithClient(ctx, client, argv, timer, `SELECT name FROM sqlite_master WHERE type="table"`)
+				err = queryWithClient(ctx, client, argv, timer, `SELECT name FROM sqlite_master WHERE type="table" AND name NOT LIKE '\_tqlite\_%' ESCAPE '\'`)
 			case ".INDEXES":
-				err = queryWithClient(ctx, client, argv, timer, `SELECT sql FROM sqlite_master WHERE type="index"`)
+				err = queryWithClient(ctx, client, argv, timer, `SELECT sql FROM sqlite_master WHERE type="index" AND tbl_name NOT LIKE '\_tqlite\_%' ESCAPE '\'`)
 			case ".SCHEMA":
-				err = queryWithClient(ctx, client, argv, timer, "SELECT sql FROM sqlite_master")
+				err = queryWithClient(ctx, client, argv, timer, `SELECT sql FROM sqlite_master WHERE tbl_name NOT LIKE '\_tqlite\_%' ESCAPE '\'`)
 			case ".TIMER":
 				err = toggleTimer(line[index+1:], &timer)
 			case ".STATUS":
 				err = status(ctx, cmd, line, argv)
 			case ".NODES":
 				err = nodes(ctx, cmd, line, argv)
+			case ".CLUSTER":
+				err = clusterStatus(ctx, client, argv)
+			case ".VERIFY":
+				err = verify(ctx, client, argv)
 			case ".EXPVAR":
 				err = expvar(ctx, cmd, line, argv)
 			case ".REMOVE":
 				err = removeNode(client, line[index+1:], argv, timer)
+			case ".STEPDOWN":
+				id := ""
+				if index != -1 {
+					id = strings.TrimSpace(line[index+1:])
+				}
+				err = stepdown(ctx, client, id, argv)
 			case ".BACKUP":
 				if index == -1 || index == len(line)-1 {
 					err = fmt.Errorf("Please specify an output file for the backup")
@@ -130,11 +150,19 @@ func main() {
 				}
 				err = backup(ctx, line[index+1:], argv)
 			case ".RESTORE":
-				if index == -1 || index == len(line)-1 {
+				var args []string
+				if index != -1 {
+					args = strings.Fields(line[index+1:])
+				}
+				if len(args) == 0 {
 					err = fmt.Errorf("Please specify an input file to restore from")
 					break
 				}
-				err = restore(ctx, line[index+1:], argv)
+				loadID := ""
+				if len(args) > 1 {
+					loadID = args[1]
+				}
+				err = restore(ctx, args[0], loadID, argv)
 			case ".SYSDUMP":
 				if index == -1 || index == len(line)-1 {
 					err = fmt.Errorf("Please specify an output file for the sysdump")
@@ -220,10 +248,69 @@ func sysdump(ctx *cli.Context, filename string, argv *argT) error {
 	return f.Close()
 }
 
+// getHTTPTransport returns an HTTP transport configured with the TLS options
+// and credentials given on the command line.
+func getHTTPTransport(argv *argT) (http.RoundTripper, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: argv.Insecure,
+	}
+	if argv.CACert != "" {
+		asn1Data, err := ioutil.ReadFile(argv.CACert)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if ok := config.RootCAs.AppendCertsFromPEM(asn1Data); !ok {
+			return nil, fmt.Errorf("failed to parse CA certificate(s) in %q", argv.CACert)
+		}
+	}
+	if argv.ClientCert != "" || argv.ClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(argv.ClientCert, argv.ClientKey)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	tr := &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: config,
+	}
+	if argv.Credentials == "" {
+		return tr, nil
+	}
+
+	creds := strings.SplitN(argv.Credentials, ":", 2)
+	if len(creds) != 2 {
+		return nil, fmt.Errorf("invalid Basic Auth credentials format")
+	}
+	return &basicAuthTransport{
+		RoundTripper: tr,
+		username:     creds[0],
+		password:     creds[1],
+	}, nil
+}
+
+// basicAuthTransport sets Basic Auth credentials on every request it sends.
+type basicAuthTransport struct {
+	http.RoundTripper
+	username string
+	password string
+}
+
+func (b *basicAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the request it is given.
+	req = req.Clone(req.Context())
+	req.SetBasicAuth(b.username, b.password)
+	return b.RoundTripper.RoundTrip(req)
+}
+
 func getHTTPClient(argv *argT) (*http.Client, error) {
-	client := http.Client{Transport: &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-	}}
+	tr, err := getHTTPTransport(argv)
+	if err != nil {
+		return nil, err
+	}
+	client := http.Client{Transport: tr}
 
 	// Explicitly handle redirects.
 	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
@@ -261,9 +348,11 @@ func getVersionWithClient(client *http.Client, argv *argT) (string, error) {
 func sendRequest(ctx *cli.Context, makeNewRequest func(string) (*http.Request, error), urlStr string, argv *argT) (*[]byte, error) {
 	url := urlStr
 
-	client := http.Client{Transport: &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-	}}
+	tr, err := getHTTPTransport(argv)
+	if err != nil {
+		return nil, err
+	}
+	client := http.Client{Transport: tr}
 
 	// Explicitly handle redirects.
 	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
@@ -338,9 +427,11 @@ func cliJSON(ctx *cli.Context, cmd, line, url string, argv *argT) error {
 		}
 	}
 
-	client := http.Client{Transport: &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-	}}
+	tr, err := getHTTPTransport(argv)
+	if err != nil {
+		return err
+	}
+	client := http.Client{Transport: tr}
 
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -378,11 +469,13 @@ func cliJSON(ctx *cli.Context, cmd, line, url string, argv *argT) error {
 }
 
 func urlsToWriter(urls []string, w io.Writer, argv *argT) error {
+	tr, err := getHTTPTransport(argv)
+	if err != nil {
+		return err
+	}
 	client := http.Client{
-		Transport: &http.Transport{
-			Proxy: http.ProxyFromEnvironment,
-		},
-		Timeout: 10 * time.Second,
+		Transport: tr,
+		Timeout:   10 * time.Second,
 	}
 
 	for i := range urls {
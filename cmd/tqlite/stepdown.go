@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/mkideal/cli"
+)
+
+func stepdown(ctx *cli.Context, client *http.Client, id string, argv *argT) error {
+	u := url.URL{
+		Scheme: argv.Protocol,
+		Host:   fmt.Sprintf("%s:%d", argv.Host, argv.Port),
+		Path:   fmt.Sprintf("%sleader/transfer", argv.Prefix),
+	}
+	urlStr := u.String()
+
+	b, err := json.Marshal(map[string]string{
+		"id": id,
+	})
+	if err != nil {
+		return err
+	}
+
+	nRedirect := 0
+	for {
+		req, err := http.NewRequest("POST", urlStr, bytes.NewReader(b))
+		if err != nil {
+			return err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		response, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusUnauthorized {
+			return fmt.Errorf("unauthorized")
+		}
+
+		if resp.StatusCode == http.StatusMovedPermanently {
+			nRedirect++
+			if nRedirect > maxRedirect {
+				return fmt.Errorf("maximum leader redirect limit exceeded")
+			}
+			urlStr = resp.Header["Location"][0]
+			continue
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("server responded with %s: %s", resp.Status, response)
+		}
+
+		ctx.String("leadership transferred successfully\n")
+		return nil
+	}
+}
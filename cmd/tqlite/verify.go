@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mkideal/cli"
+	"github.com/mkideal/pkg/textutil"
+)
+
+// verifyNode is the outcome of the verification of a node, as reported by
+// /verify.
+type verifyNode struct {
+	ID     string   `json:"id"`
+	Addr   string   `json:"addr"`
+	Status string   `json:"status"`
+	Digest string   `json:"digest"`
+	Tables []string `json:"mismatched_tables"`
+	Error  string   `json:"error"`
+}
+
+type verifyResponse struct {
+	Index  uint64        `json:"index"`
+	Status string        `json:"status"`
+	Nodes  []*verifyNode `json:"nodes"`
+}
+
+var verifyColumns = []string{"id", "raft_addr", "status", "digest", "mismatched_tables"}
+
+// verify checks that every node in the cluster holds the same data as the
+// leader, and shows the outcome for each node as a table.
+func verify(ctx *cli.Context, client *http.Client, argv *argT) error {
+	u := url.URL{
+		Scheme: argv.Protocol,
+		Host:   fmt.Sprintf("%s:%d", argv.Host, argv.Port),
+		Path:   fmt.Sprintf("%sverify", argv.Prefix),
+	}
+	urlStr := u.String()
+
+	nRedirect := 0
+	for {
+		resp, err := client.Post(urlStr, "application/json", nil)
+		if err != nil {
+			return err
+		}
+		response, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusUnauthorized {
+			return fmt.Errorf("unauthorized")
+		}
+
+		if resp.StatusCode == http.StatusMovedPermanently {
+			nRedirect++
+			if nRedirect > maxRedirect {
+				return fmt.Errorf("maximum leader redirect limit exceeded")
+			}
+			urlStr = resp.Header["Location"][0]
+			continue
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("server responded with %s: %s", resp.Status, response)
+		}
+
+		ret := &verifyResponse{}
+		if err := parseResponse(&response, ret); err != nil {
+			return err
+		}
+
+		rows := &Rows{Columns: verifyColumns}
+		for _, n := range ret.Nodes {
+			rows.Values = append(rows.Values, []interface{}{n.ID, n.Addr, n.Status, n.Digest,
+				strings.Join(n.Tables, ", ")})
+		}
+		textutil.WriteTable(ctx, rows, headerRender)
+
+		for _, n := range ret.Nodes {
+			if n.Error != "" {
+				ctx.String("node %s: %s\n", n.ID, n.Error)
+			}
+		}
+		ctx.String("cluster is %s at index %d\n", ret.Status, ret.Index)
+		return nil
+	}
+}
@@ -2,42 +2,71 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net"
+	"net/url"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"runtime"
 	"runtime/pprof"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/minghsu0107/tqlite/auth"
+	"github.com/minghsu0107/tqlite/backup"
 	"github.com/minghsu0107/tqlite/cluster"
 	"github.com/minghsu0107/tqlite/cmd"
+	"github.com/minghsu0107/tqlite/command"
+	"github.com/minghsu0107/tqlite/disco"
 	httpd "github.com/minghsu0107/tqlite/http"
 	"github.com/minghsu0107/tqlite/store"
 	"github.com/minghsu0107/tqlite/tcp"
+	"github.com/minghsu0107/tqlite/verify"
 )
 
 var httpAddr string
 var httpAdv string
+var x509Cert string
+var x509Key string
+var x509CACert string
+var noVerify bool
 var joinSrcIP string
+var authFile string
+var joinSecret string
+var nodeX509Cert string
+var nodeX509Key string
+var nodeX509CACert string
+var nodeNoVerify bool
 var nodeID string
 var raftAddr string
 var raftAdv string
 var joinAddr string
 var joinAttempts int
 var joinInterval string
+var bootstrapExpect int
+var bootstrapExpectTimeout string
+var discoMode string
+var discoName string
+var discoPort int
+var discoFile string
+var discoDNSServer string
 var expvar bool
 var pprofEnabled bool
 var dsn string
 var onDisk bool
+var onDiskWAL bool
 var raftLogLevel string
 var raftNonVoter bool
 var raftSnapThreshold uint64
 var raftSnapInterval string
+var raftSnapFull uint64
 var raftLeaderLeaseTimeout string
 var raftHeartbeatTimeout string
 var raftElectionTimeout string
@@ -45,12 +74,39 @@ var raftApplyTimeout string
 var raftOpenTimeout string
 var raftWaitForLeader bool
 var raftShutdownOnRemove bool
+var raftStepdownOnShutdown bool
+var raftAutopilotInterval string
+var raftReapNonVoterTimeout string
+var raftReapVoterTimeout string
+var raftStabilizationTime string
+var raftReapMinVoters int
+var raftPromoteMaxLag uint64
+var txTimeout string
+var txMaxStatements int
+var idempotencyRetention string
+var deterministicSQL bool
+var verifyInterval string
+var verifyTimeout string
+var verifyMinInterval string
 var compressionSize int
 var compressionBatch int
+var backupInterval string
+var backupFormat string
+var backupDir string
+var backupS3Endpoint string
+var backupS3Region string
+var backupS3Bucket string
+var backupS3Prefix string
+var backupRetainCount int
+var backupRetainAge string
 var showVersion bool
 var cpuProfile string
 var memProfile string
 
+// promotionCheckInterval is how often a node which joined as a non-voter
+// asks to be promoted to voter.
+const promotionCheckInterval = 2 * time.Second
+
 const name = `tqlited`
 const desc = `tqlite is a lightweight, distributed relational database, which uses SQLite as its
 storage engine. It provides an easy-to-use, fault-tolerant store for relational data.`
@@ -59,16 +115,34 @@ func init() {
 	flag.StringVar(&nodeID, "node-id", "", "Unique name for node. If not set, set to Raft address")
 	flag.StringVar(&httpAddr, "http-addr", "localhost:4001", "HTTP server bind address")
 	flag.StringVar(&httpAdv, "http-adv-addr", "", "Advertised HTTP address. If not set, same as HTTP server")
+	flag.StringVar(&x509Cert, "http-cert", "", "Path to X.509 certificate for HTTP endpoint. Enables HTTPS if set with key")
+	flag.StringVar(&x509Key, "http-key", "", "Path to X.509 private key for HTTP endpoint")
+	flag.StringVar(&x509CACert, "http-ca-cert", "", "Path to X.509 CA certificate. If set, HTTP clients must present a certificate signed by it, and it is trusted when joining")
+	flag.BoolVar(&noVerify, "http-no-verify", false, "Skip verification of remote HTTPS certificates when joining a cluster")
+	flag.StringVar(&nodeX509Cert, "node-cert", "", "Path to X.509 certificate for node-to-node communication. Enables encryption if set with key")
+	flag.StringVar(&nodeX509Key, "node-key", "", "Path to X.509 private key for node-to-node communication")
+	flag.StringVar(&nodeX509CACert, "node-ca-cert", "", "Path to X.509 CA certificate for node-to-node communication. Nodes must present a certificate signed by it")
+	flag.BoolVar(&nodeNoVerify, "node-no-verify", false, "Skip verification of the certificates of other nodes, allowing encryption without -node-ca-cert. Insecure")
+	flag.StringVar(&authFile, "auth", "", "Path to authentication and authorization file. If not set, not enabled")
+	flag.StringVar(&joinSecret, "join-secret", "", "Secret shared by all nodes. If set, nodes must present a token created with it to join the cluster")
 	flag.StringVar(&joinSrcIP, "join-source-ip", "", "Set source IP address during Join request")
 	flag.StringVar(&raftAddr, "raft-addr", "localhost:4002", "Raft communication bind address")
 	flag.StringVar(&raftAdv, "raft-adv-addr", "", "Advertised Raft communication address. If not set, same as Raft bind")
 	flag.StringVar(&joinAddr, "join", "", "Comma-delimited list of nodes, through which a cluster can be joined (proto://host:port)")
 	flag.IntVar(&joinAttempts, "join-attempts", 5, "Number of join attempts to make")
 	flag.StringVar(&joinInterval, "join-interval", "5s", "Period between join attempts")
+	flag.IntVar(&bootstrapExpect, "bootstrap-expect", 0, "Number of nodes which bootstrap a new cluster together, found through discovery or -join addresses. 0 disables")
+	flag.StringVar(&bootstrapExpectTimeout, "bootstrap-expect-timeout", "120s", "Maximum time for bootstrap process")
+	flag.StringVar(&discoMode, "disco-mode", "", "Choose discovery mode for bootstrapping: dns, dns-srv, or file. If not set, -join addresses are used")
+	flag.StringVar(&discoName, "disco-name", "", "Name looked up in dns and dns-srv discovery modes")
+	flag.IntVar(&discoPort, "disco-port", 4001, "HTTP API port of the nodes found in dns discovery mode")
+	flag.StringVar(&discoFile, "disco-file", "", "Path to file of node HTTP API addresses, one per line, in file discovery mode. Reread until bootstrap completes")
+	flag.StringVar(&discoDNSServer, "disco-dns-server", "", "Address, as host:port, of DNS server queried in dns and dns-srv discovery modes. If not set, the system resolver is used")
 	flag.BoolVar(&expvar, "expvar", true, "Serve expvar data on HTTP server")
 	flag.BoolVar(&pprofEnabled, "pprof", true, "Serve pprof data on HTTP server")
 	flag.StringVar(&dsn, "dsn", "", `SQLite DSN parameters. E.g. "cache=shared&mode=memory"`)
 	flag.BoolVar(&onDisk, "on-disk", false, "Use an on-disk SQLite database")
+	flag.BoolVar(&onDiskWAL, "on-disk-wal", false, "Run the on-disk SQLite database in WAL mode, enabling incremental snapshots")
 	flag.BoolVar(&showVersion, "version", false, "Show version information and exit")
 	flag.BoolVar(&raftNonVoter, "raft-non-voter", false, "Configure as non-voting node")
 	flag.StringVar(&raftHeartbeatTimeout, "raft-timeout", "1s", "Raft heartbeat timeout")
@@ -78,11 +152,35 @@ func init() {
 	flag.BoolVar(&raftWaitForLeader, "raft-leader-wait", true, "Node waits for a leader before answering requests")
 	flag.Uint64Var(&raftSnapThreshold, "raft-snap", 8192, "Number of outstanding log entries that trigger snapshot")
 	flag.StringVar(&raftSnapInterval, "raft-snap-int", "30s", "Snapshot threshold check interval")
+	flag.Uint64Var(&raftSnapFull, "raft-snap-full", 8, "In WAL mode, number of snapshots after which a full snapshot is taken")
 	flag.StringVar(&raftLeaderLeaseTimeout, "raft-leader-lease-timeout", "0s", "Raft leader lease timeout. Use 0s for Raft default")
 	flag.BoolVar(&raftShutdownOnRemove, "raft-remove-shutdown", false, "Shutdown Raft if node removed")
+	flag.BoolVar(&raftStepdownOnShutdown, "raft-shutdown-stepdown", false, "Step down from leadership, if leader, before shutting down")
+	flag.StringVar(&raftAutopilotInterval, "raft-autopilot-int", "10s", "Interval between autopilot checks of node health")
+	flag.StringVar(&raftReapNonVoterTimeout, "raft-reap-non-voter-timeout", "0s", "Remove non-voting nodes unreachable by the leader for this long. 0s disables removal")
+	flag.StringVar(&raftReapVoterTimeout, "raft-reap-voter-timeout", "0s", "Remove voting nodes unreachable by the leader for this long, if quorum allows. 0s disables removal")
+	flag.IntVar(&raftReapMinVoters, "raft-reap-min-voters", 3, "Never remove unreachable voting nodes if fewer than this many voting nodes would remain")
+	flag.Uint64Var(&raftPromoteMaxLag, "raft-promote-max-lag", 1024, "Promote a joining node to voter once its applied log index is within this many entries of the leader's")
+	flag.StringVar(&raftStabilizationTime, "raft-stabilization-time", "10s", "Time leadership and a node's health must be stable before autopilot acts on the node")
 	flag.StringVar(&raftLogLevel, "raft-log-level", "INFO", "Minimum log level for Raft module")
+	flag.StringVar(&txTimeout, "tx-timeout", "30s", "Abort interactive transactions idle for this long")
+	flag.IntVar(&txMaxStatements, "tx-max-statements", 1000, "Maximum number of statements made in an interactive transaction")
+	flag.StringVar(&idempotencyRetention, "idempotency-retention", "1h", "Time for which the results of execute requests with an Idempotency-Key header are kept")
+	flag.StringVar(&verifyInterval, "verify-interval", "0s", "Interval between checks, by the leader, that every node holds the same data. Each check makes every node copy its database, and read the copy. Use 0s to disable")
+	flag.StringVar(&verifyTimeout, "verify-timeout", "30s", "Time allowed for each node to report its checksum when checking that nodes hold the same data")
+	flag.StringVar(&verifyMinInterval, "verify-min-interval", "10s", "Minimum time between checks that every node holds the same data, background or requested")
+	flag.BoolVar(&deterministicSQL, "deterministic-sql", true, "Have non-deterministic SQL functions, such as RANDOM() and datetime('now'), return the same values on every node")
 	flag.IntVar(&compressionSize, "compression-size", 150, "Request query size for compression attempt")
 	flag.IntVar(&compressionBatch, "compression-batch", 5, "Request batch threshold for compression attempt")
+	flag.StringVar(&backupInterval, "backup-interval", "0s", "Interval between automatic backups taken by the leader. Use 0s to disable")
+	flag.StringVar(&backupFormat, "backup-format", "binary", "Format of automatic backups, binary or sql")
+	flag.StringVar(&backupDir, "backup-dir", "", "Local directory to store automatic backups in")
+	flag.StringVar(&backupS3Endpoint, "backup-s3-endpoint", "https://s3.amazonaws.com", "URL of S3-compatible service to store automatic backups in")
+	flag.StringVar(&backupS3Region, "backup-s3-region", "us-east-1", "Region of S3-compatible service")
+	flag.StringVar(&backupS3Bucket, "backup-s3-bucket", "", "Bucket to store automatic backups in. Credentials are read from AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	flag.StringVar(&backupS3Prefix, "backup-s3-prefix", "", "Key prefix of automatic backups in the bucket")
+	flag.IntVar(&backupRetainCount, "backup-retain-count", 0, "Number of automatic backups to keep. Use 0 for no limit")
+	flag.StringVar(&backupRetainAge, "backup-retain-age", "0s", "Age after which automatic backups are deleted. Use 0s for no limit")
 	flag.StringVar(&cpuProfile, "cpu-profile", "", "Path to file for CPU profiling information")
 	flag.StringVar(&memProfile, "mem-profile", "", "Path to file for memory profiling information")
 
@@ -138,19 +236,16 @@ func main() {
 	}
 	raftTn := mux.Listen(cluster.MuxRaftHeader)
 
-	// Create cluster service, so nodes can learn information about each other. This can be started
-	// now since it doesn't require a functioning Store yet.
-	clstr, err := clusterService(mux.Listen(cluster.MuxClusterHeader))
-	if err != nil {
-		log.Fatalf("failed to create cluster service: %s", err.Error())
-	}
-
 	// Create and open the store.
 	dataPath, err = filepath.Abs(dataPath)
 	if err != nil {
 		log.Fatalf("failed to determine absolute data path: %s", err.Error())
 	}
+	if onDiskWAL && !onDisk {
+		log.Fatalf("WAL mode requires an on-disk SQLite database")
+	}
 	dbConf := store.NewDBConfig(dsn, !onDisk)
+	dbConf.WAL = onDiskWAL
 
 	str := store.New(raftTn, &store.StoreConfig{
 		DBConf: dbConf,
@@ -163,6 +258,7 @@ func main() {
 	str.RaftLogLevel = raftLogLevel
 	str.ShutdownOnRemove = raftShutdownOnRemove
 	str.SnapshotThreshold = raftSnapThreshold
+	str.FullSnapshotEvery = raftSnapFull
 	str.SnapshotInterval, err = time.ParseDuration(raftSnapInterval)
 	if err != nil {
 		log.Fatalf("failed to parse Raft Snapsnot interval %s: %s", raftSnapInterval, err.Error())
@@ -183,6 +279,52 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to parse Raft apply timeout %s: %s", raftApplyTimeout, err.Error())
 	}
+	str.AutopilotInterval, err = time.ParseDuration(raftAutopilotInterval)
+	if err != nil || str.AutopilotInterval <= 0 {
+		log.Fatalf("failed to parse autopilot interval %s: must be a positive duration", raftAutopilotInterval)
+	}
+	str.NonVoterReapTimeout, err = time.ParseDuration(raftReapNonVoterTimeout)
+	if err != nil {
+		log.Fatalf("failed to parse non-voter reap timeout %s: %s", raftReapNonVoterTimeout, err.Error())
+	}
+	str.VoterReapTimeout, err = time.ParseDuration(raftReapVoterTimeout)
+	if err != nil {
+		log.Fatalf("failed to parse voter reap timeout %s: %s", raftReapVoterTimeout, err.Error())
+	}
+	str.ReapMinVoters = raftReapMinVoters
+	str.PromoteMaxLag = raftPromoteMaxLag
+	str.ServerStabilizationTime, err = time.ParseDuration(raftStabilizationTime)
+	if err != nil {
+		log.Fatalf("failed to parse server stabilization time %s: %s", raftStabilizationTime, err.Error())
+	}
+	str.TxTimeout, err = time.ParseDuration(txTimeout)
+	if err != nil || str.TxTimeout <= 0 {
+		log.Fatalf("failed to parse transaction timeout %s: must be a positive duration", txTimeout)
+	}
+	if txMaxStatements <= 0 {
+		log.Fatalf("invalid maximum transaction statements %d: must be positive", txMaxStatements)
+	}
+	str.TxMaxStatements = txMaxStatements
+	str.IdempotencyRetention, err = time.ParseDuration(idempotencyRetention)
+	if err != nil || str.IdempotencyRetention <= 0 {
+		log.Fatalf("failed to parse idempotency retention %s: must be a positive duration", idempotencyRetention)
+	}
+	str.DeterministicSQL = deterministicSQL
+
+	// Load the credentials of users, if any, which both the HTTP API and
+	// the cluster service check.
+	credStr, err := credentialStore()
+	if err != nil {
+		log.Fatalf("failed to load credentials: %s", err.Error())
+	}
+
+	// Create cluster service, so nodes can learn information about each other, and
+	// forward requests to the leader. This can be started now since it doesn't
+	// require a functioning Store yet.
+	clstr, err := clusterService(mux.Listen(cluster.MuxClusterHeader), str, credStr)
+	if err != nil {
+		log.Fatalf("failed to create cluster service: %s", err.Error())
+	}
 
 	// Any prexisting node state?
 	var enableBootstrap bool
@@ -202,8 +344,11 @@ func main() {
 	}
 
 	// Supplying join addresses means bootstrapping a new cluster won't
-	// be required.
-	if len(joins) > 0 {
+	// be required, unless the nodes bootstrap it together.
+	if bootstrapExpect > 0 {
+		enableBootstrap = false
+		log.Printf("bootstrap-expect set, node bootstraps cluster once %d nodes are ready", bootstrapExpect)
+	} else if len(joins) > 0 {
 		enableBootstrap = false
 		log.Println("join addresses specified, node is not bootstrapping")
 	} else {
@@ -216,12 +361,30 @@ func main() {
 	}
 
 	// Now, open store.
+	str.BootstrapExpect = bootstrapExpect
 	if err := str.Open(enableBootstrap); err != nil {
 		log.Fatalf("failed to open store: %s", err.Error())
 	}
 
-	// Execute any requested join operation.
-	if len(joins) > 0 && isNew {
+	// Create the verifier, which checks that every node holds the same data.
+	vf, err := newVerifier(str, clstr)
+	if err != nil {
+		log.Fatalf("failed to create verifier: %s", err.Error())
+	}
+
+	// Start the HTTP API server, which nodes bootstrapping a cluster together
+	// notify.
+	httpServ, err := startHTTPService(str, clstr, vf, credStr)
+	if err != nil {
+		log.Fatalf("failed to start HTTP server: %s", err.Error())
+	}
+
+	// Execute any requested join or bootstrap operation.
+	if bootstrapExpect > 0 && isNew {
+		if err := bootstrap(str, clstr, joins); err != nil {
+			log.Fatalf("failed to bootstrap cluster: %s", err.Error())
+		}
+	} else if len(joins) > 0 && isNew {
 		log.Println("join addresses are:", joins)
 		advAddr := raftAddr
 		if raftAdv != "" {
@@ -233,13 +396,17 @@ func main() {
 			log.Fatalf("failed to parse Join interval %s: %s", joinInterval, err.Error())
 		}
 
+		tlsConfig, err := joinTLSConfig()
+		if err != nil {
+			log.Fatalf("failed to create TLS config for joining: %s", err.Error())
+		}
+
 		if j, err := cluster.Join(joinSrcIP, joins, str.ID(), advAddr, !raftNonVoter,
-			joinAttempts, joinDur); err != nil {
+			joinAttempts, joinDur, tlsConfig, joinSecret); err != nil {
 			log.Fatalf("failed to join cluster at %s: %s", joins, err.Error())
 		} else {
 			log.Println("successfully joined cluster at", j)
 		}
-
 	}
 
 	// Wait until the store is in full consensus.
@@ -248,16 +415,42 @@ func main() {
 	}
 	log.Println("store has reached consensus")
 
-	// Start the HTTP API server.
-	if err := startHTTPService(str, clstr); err != nil {
-		log.Fatalf("failed to start HTTP server: %s", err.Error())
+	// A node which joined as a non-voter, pending promotion, may have been
+	// restarted before it was promoted, so this is checked on every start.
+	if !raftNonVoter {
+		go requestPromotion(str, clstr, joinCredentials(joins))
+	}
+
+	// Start automatic backups, if requested.
+	bu, err := startBackups(str)
+	if err != nil {
+		log.Fatalf("failed to start automatic backups: %s", err.Error())
+	}
+	if bu != nil {
+		if err := httpServ.RegisterStatus("auto_backup", bu); err != nil {
+			log.Fatalf("failed to register backup status: %s", err.Error())
+		}
+	}
+	vf.Start()
+	if err := httpServ.RegisterStatus("verify", vf); err != nil {
+		log.Fatalf("failed to register verify status: %s", err.Error())
 	}
 	log.Println("node is ready")
 
 	// Block until signalled.
 	terminate := make(chan os.Signal, 1)
-	signal.Notify(terminate, os.Interrupt)
+	signal.Notify(terminate, os.Interrupt, syscall.SIGTERM)
 	<-terminate
+	if raftStepdownOnShutdown && str.IsLeader() {
+		log.Println("stepping down from leadership before shutdown")
+		if err := str.TransferLeadership(""); err != nil {
+			log.Printf("failed to step down from leadership: %s", err.Error())
+		}
+	}
+	if bu != nil {
+		bu.Close()
+	}
+	vf.Close()
 	if err := str.Close(true); err != nil {
 		log.Printf("failed to close store: %s", err.Error())
 	}
@@ -274,9 +467,87 @@ func determineJoinAddresses() ([]string, error) {
 		addrs = strings.Split(joinAddr, ",")
 	}
 
+	// When serving HTTPS, assume the nodes joined do too, unless told otherwise.
+	if httpsEnabled() {
+		for i := range addrs {
+			if !strings.HasPrefix(addrs[i], "http://") {
+				addrs[i] = httpd.EnsureHTTPS(addrs[i])
+			}
+		}
+	}
+
 	return addrs, nil
 }
 
+// bootstrap makes this node a member of a cluster, bootstrapping it together
+// with the other nodes once bootstrapExpect nodes are ready. The nodes are
+// found through discovery, or at the join addresses if no discovery mode is
+// set.
+func bootstrap(str *store.Store, clstr *cluster.Service, joins []string) error {
+	var provider cluster.AddressProvider
+	switch discoMode {
+	case "":
+		if len(joins) == 0 {
+			return fmt.Errorf("join addresses or a discovery mode must be set")
+		}
+		provider = disco.NewStatic(joins)
+	case "dns":
+		if discoName == "" {
+			return fmt.Errorf("discovery name must be set in dns mode")
+		}
+		provider = disco.NewDNS(discoName, discoPort, discoDNSServer)
+	case "dns-srv":
+		if discoName == "" {
+			return fmt.Errorf("discovery name must be set in dns-srv mode")
+		}
+		provider = disco.NewDNSSRV(discoName, discoDNSServer)
+	case "file":
+		if discoFile == "" {
+			return fmt.Errorf("discovery file must be set in file mode")
+		}
+		provider = disco.NewFile(discoFile)
+	default:
+		return fmt.Errorf("invalid discovery mode %s", discoMode)
+	}
+	if discoMode != "" {
+		log.Printf("discovering nodes through %s", provider)
+	}
+
+	timeout, err := time.ParseDuration(bootstrapExpectTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to parse bootstrap timeout %s: %s", bootstrapExpectTimeout, err.Error())
+	}
+	interval, err := time.ParseDuration(joinInterval)
+	if err != nil {
+		return fmt.Errorf("failed to parse Join interval %s: %s", joinInterval, err.Error())
+	}
+	tlsConfig, err := joinTLSConfig()
+	if err != nil {
+		return fmt.Errorf("failed to create TLS config for joining: %s", err.Error())
+	}
+
+	advAddr := raftAddr
+	if raftAdv != "" {
+		advAddr = raftAdv
+	}
+	bs := cluster.NewBootstrapper(provider, bootstrapExpect, tlsConfig, httpsEnabled(), joinSecret)
+	bs.SrcIP = joinSrcIP
+	bs.Interval = interval
+	bs.NonVoter = raftNonVoter
+	done := func() bool {
+		leader, _ := str.LeaderAddr()
+		return leader != ""
+	}
+	status, err := bs.Boot(str.ID(), advAddr, done, timeout)
+	if err != nil {
+		return err
+	}
+	if status == cluster.BootJoin {
+		log.Println("cluster exists already, joined it")
+	}
+	return nil
+}
+
 func waitForConsensus(str *store.Store) error {
 	openTimeout, err := time.ParseDuration(raftOpenTimeout)
 	if err != nil {
@@ -298,20 +569,187 @@ func waitForConsensus(str *store.Store) error {
 	return nil
 }
 
-func startHTTPService(str *store.Store, cltr *cluster.Service) error {
+// requestPromotion asks the leader to promote this node, if it is a
+// non-voter, to voter, until the leader does so. The leader waits until the
+// node has caught up. The request carries creds, if set.
+func requestPromotion(str *store.Store, cltr *cluster.Service, creds *command.Credentials) {
+	tck := time.NewTicker(promotionCheckInterval)
+	defer tck.Stop()
+
+	var lastErr string
+	var pending bool
+	for range tck.C {
+		nodes, err := str.Nodes()
+		if err != nil {
+			continue
+		}
+		var found bool
+		for _, n := range nodes {
+			if n.ID != str.ID() {
+				continue
+			}
+			found = true
+			if n.Suffrage == "Voter" {
+				if pending {
+					log.Println("node promoted to voter")
+				}
+				return
+			}
+		}
+		if !found {
+			continue
+		}
+		pending = true
+
+		leaderAddr, err := str.LeaderAddr()
+		if err != nil || leaderAddr == "" {
+			continue
+		}
+		err = cltr.Promote(str.ID(), str.AppliedIndex(), false, leaderAddr, creds, promotionCheckInterval)
+		if err != nil && err.Error() != lastErr {
+			log.Printf("not yet promoted to voter: %s", err.Error())
+			lastErr = err.Error()
+		}
+	}
+}
+
+// credentialStore returns the credentials of users loaded from the auth
+// file, or nil if authentication is not enabled.
+func credentialStore() (httpd.CredentialStore, error) {
+	if authFile == "" {
+		return nil, nil
+	}
+	cs, err := auth.NewCredentialsStoreFromFile(authFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load credentials from %s: %s", authFile, err.Error())
+	}
+	log.Printf("authentication enabled, credentials loaded from %s", authFile)
+	return cs, nil
+}
+
+// joinCredentials returns the credentials given in the first of joins which
+// has any, or nil if none do. The node joined with them, so they permit it to
+// ask for its promotion.
+func joinCredentials(joins []string) *command.Credentials {
+	for _, j := range joins {
+		u, err := url.Parse(httpd.NormalizeAddr(j))
+		if err != nil || u.User == nil {
+			continue
+		}
+		p, _ := u.User.Password()
+		return &command.Credentials{Username: u.User.Username(), Password: p}
+	}
+	return nil
+}
+
+func startHTTPService(str *store.Store, cltr *cluster.Service, vf *verify.Verifier, credStr httpd.CredentialStore) (*httpd.Service, error) {
 	// Create HTTP server
 	var s *httpd.Service
-	s = httpd.New(httpAddr, str, cltr)
+	s = httpd.New(httpAddr, str, cltr, credStr)
 
+	s.CertFile = x509Cert
+	s.KeyFile = x509Key
+	s.CACertFile = x509CACert
+	s.JoinSecret = joinSecret
 	s.Expvar = expvar
 	s.Pprof = pprofEnabled
+	s.Verifier = vf
 	s.BuildInfo = map[string]interface{}{
 		"commit":     cmd.Commit,
 		"branch":     cmd.Branch,
 		"version":    cmd.Version,
 		"build_time": cmd.Buildtime,
 	}
-	return s.Start()
+	return s, s.Start()
+}
+
+// startBackups starts the automatic backup loop, returning nil if automatic
+// backups are not enabled.
+func startBackups(str *store.Store) (*backup.Uploader, error) {
+	interval, err := time.ParseDuration(backupInterval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse backup interval %s: %s", backupInterval, err.Error())
+	}
+	if interval < 0 {
+		return nil, fmt.Errorf("invalid backup interval %s: must not be negative", backupInterval)
+	}
+	if interval == 0 {
+		return nil, nil
+	}
+
+	retainAge, err := time.ParseDuration(backupRetainAge)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse backup retention age %s: %s", backupRetainAge, err.Error())
+	}
+	if retainAge < 0 {
+		return nil, fmt.Errorf("invalid backup retention age %s: must not be negative", backupRetainAge)
+	}
+
+	var format store.BackupFormat
+	switch backupFormat {
+	case "binary":
+		format = store.BackupBinary
+	case "sql":
+		format = store.BackupSQL
+	default:
+		return nil, fmt.Errorf("invalid backup format %s", backupFormat)
+	}
+
+	var storage backup.Storage
+	switch {
+	case backupDir != "" && backupS3Bucket != "":
+		return nil, fmt.Errorf("only one of backup directory and S3 bucket may be set")
+	case backupDir != "":
+		storage, err = backup.NewLocalStorage(backupDir)
+	case backupS3Bucket != "":
+		storage, err = backup.NewS3Storage(&backup.S3Config{
+			Endpoint:        backupS3Endpoint,
+			Region:          backupS3Region,
+			Bucket:          backupS3Bucket,
+			Prefix:          backupS3Prefix,
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		})
+	default:
+		return nil, fmt.Errorf("backup directory or S3 bucket must be set")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	bu := backup.New(str, storage, &backup.Config{
+		Interval:    interval,
+		Format:      format,
+		RetainCount: backupRetainCount,
+		RetainAge:   retainAge,
+	})
+	bu.Start()
+	return bu, nil
+}
+
+// newVerifier returns the verifier, which checks that every node holds the
+// same data, in the background if an interval is set.
+func newVerifier(str *store.Store, cltr *cluster.Service) (*verify.Verifier, error) {
+	interval, err := time.ParseDuration(verifyInterval)
+	if err != nil || interval < 0 {
+		return nil, fmt.Errorf("failed to parse verify interval %s: must be a duration of 0s or more", verifyInterval)
+	}
+	timeout, err := time.ParseDuration(verifyTimeout)
+	if err != nil || timeout <= 0 {
+		return nil, fmt.Errorf("failed to parse verify timeout %s: must be a positive duration", verifyTimeout)
+	}
+	minInterval, err := time.ParseDuration(verifyMinInterval)
+	if err != nil || minInterval < 0 {
+		return nil, fmt.Errorf("failed to parse verify minimum interval %s: must be a duration of 0s or more", verifyMinInterval)
+	}
+	if interval > 0 && interval < minInterval {
+		return nil, fmt.Errorf("verify interval %s is less than the minimum interval %s", interval, minInterval)
+	}
+	return verify.New(str, cltr, &verify.Config{
+		Interval:    interval,
+		Timeout:     timeout,
+		MinInterval: minInterval,
+	}), nil
 }
 
 func startNodeMux(ln net.Listener) (*tcp.Mux, error) {
@@ -325,7 +763,12 @@ func startNodeMux(ln net.Listener) (*tcp.Mux, error) {
 	}
 
 	var mux *tcp.Mux
-	mux, err = tcp.NewMux(ln, adv)
+	if nodeX509Cert != "" || nodeX509Key != "" {
+		log.Printf("enabling node-to-node encryption with cert: %s, key: %s", nodeX509Cert, nodeX509Key)
+		mux, err = tcp.NewTLSMux(ln, adv, nodeX509Cert, nodeX509Key, nodeX509CACert, nodeNoVerify)
+	} else {
+		mux, err = tcp.NewMux(ln, adv)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create node-to-node mux: %s", err.Error())
 	}
@@ -335,13 +778,16 @@ func startNodeMux(ln net.Listener) (*tcp.Mux, error) {
 	return mux, nil
 }
 
-func clusterService(tn cluster.Transport) (*cluster.Service, error) {
-	c := cluster.New(tn)
+func clusterService(tn cluster.Transport, str *store.Store, credStr httpd.CredentialStore) (*cluster.Service, error) {
+	c := cluster.New(tn, str, str, credStr)
 	apiAddr := httpAddr
 	if httpAdv != "" {
 		apiAddr = httpAdv
 	}
 	c.SetAPIAddr(apiAddr)
+	c.SetVersion(cmd.Version)
+	c.EnableHTTPS(httpsEnabled())
+	c.SetJoinSecret(joinSecret)
 
 	if err := c.Open(); err != nil {
 		return nil, err
@@ -349,6 +795,38 @@ func clusterService(tn cluster.Transport) (*cluster.Service, error) {
 	return c, nil
 }
 
+// httpsEnabled returns whether the HTTP API serves HTTPS.
+func httpsEnabled() bool {
+	return x509Cert != "" && x509Key != ""
+}
+
+// joinTLSConfig returns the TLS config used to connect to other nodes when
+// joining a cluster. The node presents its own certificate, if it has one, so
+// it can join nodes which verify client certificates.
+func joinTLSConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: noVerify,
+	}
+	if httpsEnabled() {
+		cert, err := tls.LoadX509KeyPair(x509Cert, x509Key)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if x509CACert != "" {
+		asn1Data, err := ioutil.ReadFile(x509CACert)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if ok := config.RootCAs.AppendCertsFromPEM(asn1Data); !ok {
+			return nil, fmt.Errorf("failed to parse CA certificate(s) in %q", x509CACert)
+		}
+	}
+	return config, nil
+}
+
 func idOrRaftAddr() string {
 	if nodeID != "" {
 		return nodeID
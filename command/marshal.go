@@ -151,6 +151,33 @@ func UnmarshalNoop(b []byte, c *Noop) error {
 	return proto.Unmarshal(b, c)
 }
 
+// MarshalTransactionRequest marshals a TransactionRequest command.
+func MarshalTransactionRequest(tr *TransactionRequest) ([]byte, error) {
+	return proto.Marshal(tr)
+}
+
+// MarshalLoadRequest marshals a LoadRequest command. The result is always
+// gzip-compressed, as database files compress well.
+func MarshalLoadRequest(lr *LoadRequest) ([]byte, error) {
+	b, err := proto.Marshal(lr)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	gzw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, fmt.Errorf("gzip new writer: %s", err)
+	}
+	if _, err := gzw.Write(b); err != nil {
+		return nil, fmt.Errorf("gzip Write: %s", err)
+	}
+	if err := gzw.Close(); err != nil {
+		return nil, fmt.Errorf("gzip Close: %s", err)
+	}
+	return buf.Bytes(), nil
+}
+
 // UnmarshalSubCommand unmarshalls a sub command m. It assumes that
 // m is the correct type.
 func UnmarshalSubCommand(c *Command, m proto.Message) error {
@@ -158,16 +185,16 @@ func UnmarshalSubCommand(c *Command, m proto.Message) error {
 	if c.Compressed {
 		gz, err := gzip.NewReader(bytes.NewReader(b))
 		if err != nil {
-			fmt.Errorf("unmarshal sub gzip NewReader: %s", err)
+			return fmt.Errorf("unmarshal sub gzip NewReader: %s", err)
 		}
 
 		ub, err := ioutil.ReadAll(gz)
 		if err != nil {
-			fmt.Errorf("unmarshal sub gzip ReadAll: %s", err)
+			return fmt.Errorf("unmarshal sub gzip ReadAll: %s", err)
 		}
 
 		if err := gz.Close(); err != nil {
-			fmt.Errorf("unmarshal sub gzip Close: %s", err)
+			return fmt.Errorf("unmarshal sub gzip Close: %s", err)
 		}
 		b = ub
 	}
@@ -3,12 +3,20 @@
 package db
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
 	"expvar"
 	"fmt"
 	"io"
+	"math"
+	"math/rand"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/minghsu0107/tqlite/command"
@@ -17,6 +25,18 @@ import (
 
 const bkDelay = 250
 
+const (
+	// checkpointRetries is the number of times a checkpoint which would
+	// truncate the WAL is attempted before falling back to one which does not.
+	checkpointRetries = 5
+
+	// checkpointRetryDelay is the time between checkpoint attempts.
+	checkpointRetryDelay = 50 * time.Millisecond
+)
+
+// sqliteHeader is the string every SQLite database file begins with.
+const sqliteHeader = "SQLite format 3\x00"
+
 const (
 	fkChecks         = "PRAGMA foreign_keys"
 	fkChecksEnabled  = "PRAGMA foreign_keys=ON"
@@ -27,8 +47,21 @@ const (
 	numQueries         = "queries"
 	numETx             = "execute_transactions"
 	numQTx             = "query_transactions"
+	numITx             = "interactive_transactions"
+	numPrecondFailures = "precondition_failures"
+	numChecksums       = "checksums"
+	numCheckpointsBusy = "checkpoints_busy"
 )
 
+// InternalTablePrefix starts the names of the tables in which tqlite keeps
+// state of its own. Requests may not change these tables, or create others
+// like them, and they are left out of dumps.
+const InternalTablePrefix = "_tqlite_"
+
+// ErrPreconditionFailed is returned when a precondition of a request does not
+// hold, so none of its statements were executed.
+var ErrPreconditionFailed = errors.New("precondition failed")
+
 // DBVersion is the SQLite version.
 var DBVersion string
 
@@ -43,7 +76,10 @@ func init() {
 	stats.Add(numQueries, 0)
 	stats.Add(numETx, 0)
 	stats.Add(numQTx, 0)
-
+	stats.Add(numITx, 0)
+	stats.Add(numPrecondFailures, 0)
+	stats.Add(numChecksums, 0)
+	stats.Add(numCheckpointsBusy, 0)
 }
 
 // DB is the SQL database.
@@ -52,6 +88,13 @@ type DB struct {
 	path        string              // Path to database file.
 	dsn         string              // DSN, if any.
 	memory      bool                // In-memory only.
+
+	inTx     bool // Transaction started by Begin in progress?
+	internal bool // May statements change internal tables?
+
+	boundMu sync.RWMutex // Held exclusively while a request with bound values executes.
+	rand    *rand.Rand   // Random numbers for the request with bound values.
+	clock   *clock       // VFS through which SQLite takes the current time.
 }
 
 // Result represents the outcome of an operation that changes rows.
@@ -143,22 +186,114 @@ func DeserializeInMemoryWithDSN(b []byte, dsn string) (*DB, error) {
 	return db, nil
 }
 
+// IsValidSQLiteData returns whether b starts with the header of a SQLite
+// database file.
+func IsValidSQLiteData(b []byte) bool {
+	return len(b) >= len(sqliteHeader) && string(b[:len(sqliteHeader)]) == sqliteHeader
+}
+
+// CheckIntegrity runs an integrity check on the SQLite database file at path,
+// and returns an error if the database is corrupt.
+func CheckIntegrity(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	db, err := Open(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	r, err := db.QueryStringStmt("PRAGMA integrity_check")
+	if err != nil {
+		return fmt.Errorf("integrity check: %s", err)
+	}
+	if r[0].Error != "" {
+		return fmt.Errorf("integrity check: %s", r[0].Error)
+	}
+	if len(r[0].Values) != 1 || r[0].Values[0][0] != "ok" {
+		return fmt.Errorf("integrity check failed: %v", r[0].Values)
+	}
+	return nil
+}
+
 // Close closes the underlying database connection.
 func (db *DB) Close() error {
-	return db.sqlite3conn.Close()
+	if err := db.sqlite3conn.Close(); err != nil {
+		return err
+	}
+	db.clock.Close()
+	return nil
 }
 
 func open(dbPath string) (*DB, error) {
+	c, err := newClock()
+	if err != nil {
+		return nil, err
+	}
+	sep := "?"
+	if strings.Contains(dbPath, "?") {
+		sep = "&"
+	}
 	d := sqlite3.SQLiteDriver{}
-	dbc, err := d.Open(dbPath)
+	dbc, err := d.Open(dbPath + sep + "vfs=" + c.Name())
 	if err != nil {
+		c.Close()
 		return nil, err
 	}
 
-	return &DB{
+	db := &DB{
 		sqlite3conn: dbc.(*sqlite3.SQLiteConn),
 		path:        dbPath,
-	}, nil
+		clock:       c,
+	}
+	// Registering an authorizer allocates resources which are only freed
+	// when the connection closes, so register one for the connection's
+	// lifetime.
+	db.sqlite3conn.RegisterAuthorizer(db.authorize)
+	if err := db.registerFuncs(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// authorize denies statements which would end or nest a transaction started
+// by Begin, and statements which would change internal tables, unless they
+// are made by ExecuteInternal.
+func (db *DB) authorize(op int, arg1, arg2, _ string) int {
+	if db.inTx && (op == sqlite3.SQLITE_TRANSACTION || op == sqlite3.SQLITE_SAVEPOINT) {
+		return sqlite3.SQLITE_DENY
+	}
+	if db.internal {
+		return sqlite3.SQLITE_OK
+	}
+
+	var table string
+	switch op {
+	case sqlite3.SQLITE_INSERT, sqlite3.SQLITE_UPDATE, sqlite3.SQLITE_DELETE,
+		sqlite3.SQLITE_CREATE_TABLE, sqlite3.SQLITE_CREATE_TEMP_TABLE,
+		sqlite3.SQLITE_DROP_TABLE, sqlite3.SQLITE_DROP_TEMP_TABLE,
+		sqlite3.SQLITE_CREATE_VIEW, sqlite3.SQLITE_CREATE_TEMP_VIEW,
+		sqlite3.SQLITE_DROP_VIEW, sqlite3.SQLITE_DROP_TEMP_VIEW:
+		table = arg1
+	case sqlite3.SQLITE_ALTER_TABLE,
+		sqlite3.SQLITE_CREATE_INDEX, sqlite3.SQLITE_CREATE_TEMP_INDEX,
+		sqlite3.SQLITE_DROP_INDEX, sqlite3.SQLITE_DROP_TEMP_INDEX,
+		sqlite3.SQLITE_CREATE_TRIGGER, sqlite3.SQLITE_CREATE_TEMP_TRIGGER,
+		sqlite3.SQLITE_DROP_TRIGGER, sqlite3.SQLITE_DROP_TEMP_TRIGGER:
+		table = arg2
+	}
+	if IsInternalTable(table) {
+		return sqlite3.SQLITE_DENY
+	}
+	return sqlite3.SQLITE_OK
+}
+
+// IsInternalTable returns whether the table with the given name is one in
+// which tqlite keeps state of its own.
+func IsInternalTable(name string) bool {
+	return strings.HasPrefix(strings.ToLower(name), InternalTablePrefix)
 }
 
 // EnableFKConstraints allows control of foreign key constraint checks.
@@ -246,10 +381,19 @@ func (db *DB) ExecuteStringStmt(query string) ([]*Result, error) {
 
 // Execute executes queries that modify the database.
 func (db *DB) Execute(req *command.Request, xTime bool) ([]*Result, error) {
+	return db.execute(req, xTime, false)
+}
+
+// execute executes the statements of req. If req is a transaction, execution
+// stops at the first error, and, unless inTx is set, the statements run in a
+// transaction of their own, which is then rolled back. If inTx is set, they
+// run in the transaction already begun, which the caller rolls back.
+func (db *DB) execute(req *command.Request, xTime, inTx bool) ([]*Result, error) {
+	defer db.bind(req)()
 	stats.Add(numExecutions, int64(len(req.Statements)))
 
 	tx := req.Transaction
-	if tx {
+	if tx && !inTx {
 		stats.Add(numETx, 1)
 	}
 
@@ -293,13 +437,18 @@ func (db *DB) Execute(req *command.Request, xTime bool) ([]*Result, error) {
 
 		// Create the correct execution object, depending on whether a
 		// transaction was requested.
-		if tx {
+		if tx && !inTx {
 			t, err = db.sqlite3conn.Begin()
 			if err != nil {
 				return err
 			}
 		}
 
+		if err := db.checkPreconditions(req.Preconditions); err != nil {
+			rollback = true
+			return err
+		}
+
 		// Execute each statement.
 		for _, stmt := range req.Statements {
 			sql := stmt.Sql
@@ -358,6 +507,48 @@ func (db *DB) Execute(req *command.Request, xTime bool) ([]*Result, error) {
 	return allResults, err
 }
 
+// checkPreconditions returns an error if any of the given preconditions does
+// not hold. The error wraps ErrPreconditionFailed, unless a precondition could
+// not be evaluated at all.
+func (db *DB) checkPreconditions(preconditions []*command.Precondition) error {
+	for i, p := range preconditions {
+		rows, err := db.query(&command.Request{Statements: []*command.Statement{p.Statement}}, false)
+		if err != nil {
+			return fmt.Errorf("precondition %d: %s", i+1, err)
+		}
+		if len(rows) == 0 {
+			return fmt.Errorf("precondition %d: no query", i+1)
+		}
+		if rows[0].Error != "" {
+			return fmt.Errorf("precondition %d: %s", i+1, rows[0].Error)
+		}
+
+		values := rows[0].Values
+		switch p.Type {
+		case command.Precondition_PRECONDITION_TYPE_ROWS:
+			if int64(len(values)) != p.Rows {
+				stats.Add(numPrecondFailures, 1)
+				return fmt.Errorf("%w: precondition %d returned %d rows, expected %d",
+					ErrPreconditionFailed, i+1, len(values), p.Rows)
+			}
+		case command.Precondition_PRECONDITION_TYPE_VALUE:
+			if len(values) != 1 || len(values[0]) == 0 {
+				stats.Add(numPrecondFailures, 1)
+				return fmt.Errorf("%w: precondition %d returned %d rows, expected 1",
+					ErrPreconditionFailed, i+1, len(values))
+			}
+			if !valueEquals(values[0][0], p.Value) {
+				stats.Add(numPrecondFailures, 1)
+				return fmt.Errorf("%w: precondition %d returned %s, expected %s",
+					ErrPreconditionFailed, i+1, displayValue(values[0][0]), displayValue(parameterValue(p.Value)))
+			}
+		default:
+			return fmt.Errorf("precondition %d: unsupported type %s", i+1, p.Type)
+		}
+	}
+	return nil
+}
+
 // QueryStringStmt executes a single query that return rows, but don't modify database.
 func (db *DB) QueryStringStmt(query string) ([]*Rows, error) {
 	r := &command.Request{
@@ -372,6 +563,12 @@ func (db *DB) QueryStringStmt(query string) ([]*Rows, error) {
 
 // Query executes queries that return rows, but don't modify the database.
 func (db *DB) Query(req *command.Request, xTime bool) ([]*Rows, error) {
+	defer db.bind(req)()
+	return db.query(req, xTime)
+}
+
+// query is Query, for use while the values bound to req are bound.
+func (db *DB) query(req *command.Request, xTime bool) ([]*Rows, error) {
 	stats.Add(numQueries, int64(len(req.Statements)))
 
 	tx := req.Transaction
@@ -461,6 +658,70 @@ func (db *DB) Query(req *command.Request, xTime bool) ([]*Rows, error) {
 	return allRows, err
 }
 
+// Tx is a transaction on the database. Requests executed and queried through
+// it run inside the transaction, and statements which would end the
+// transaction, or create savepoints within it, are denied.
+type Tx struct {
+	db *DB
+}
+
+// Begin starts a transaction. No other use may be made of the database until
+// the transaction is committed or rolled back.
+func (db *DB) Begin() (*Tx, error) {
+	if _, err := db.sqlite3conn.Exec("BEGIN", nil); err != nil {
+		return nil, err
+	}
+	db.inTx = true
+	stats.Add(numITx, 1)
+	return &Tx{db: db}, nil
+}
+
+// Execute executes the statements of req, which modify the database, within
+// the transaction. If req is a transaction, execution stops at the first
+// error, but it is up to the caller to roll back.
+func (t *Tx) Execute(req *command.Request, xTime bool) ([]*Result, error) {
+	return t.db.execute(req, xTime, true)
+}
+
+// ExecuteInternal executes the statements of req within the transaction, as
+// Execute does, allowing them to change internal tables.
+func (t *Tx) ExecuteInternal(req *command.Request) ([]*Result, error) {
+	t.db.internal = true
+	defer func() { t.db.internal = false }()
+	return t.Execute(req, false)
+}
+
+// Query executes the queries of req within the transaction. The transaction
+// flag of req is ignored.
+func (t *Tx) Query(req *command.Request, xTime bool) ([]*Rows, error) {
+	return t.db.Query(&command.Request{
+		Statements: req.Statements,
+		RandomSeed: req.RandomSeed,
+		Now:        req.Now,
+	}, xTime)
+}
+
+// Commit commits the transaction. If the commit fails, the transaction is
+// rolled back.
+func (t *Tx) Commit() error {
+	err := t.end("COMMIT")
+	if err != nil && t.db.TransactionActive() {
+		t.db.sqlite3conn.Exec("ROLLBACK", nil)
+	}
+	return err
+}
+
+// Rollback rolls back the transaction.
+func (t *Tx) Rollback() error {
+	return t.end("ROLLBACK")
+}
+
+func (t *Tx) end(stmt string) error {
+	t.db.inTx = false
+	_, err := t.db.sqlite3conn.Exec(stmt, nil)
+	return err
+}
+
 // Backup writes a consistent snapshot of the database to the given file.
 // This function can be called when changes to the database are in flight.
 func (db *DB) Backup(path string) error {
@@ -482,6 +743,99 @@ func (db *DB) Backup(path string) error {
 	return nil
 }
 
+// EnableWAL switches the database to WAL journal mode. Automatic checkpointing
+// is disabled, so the WAL holds every change made since the last call to
+// Checkpoint.
+func (db *DB) EnableWAL() error {
+	r, err := db.QueryStringStmt("PRAGMA journal_mode=WAL")
+	if err != nil {
+		return fmt.Errorf("enable WAL: %s", err)
+	}
+	if r[0].Error != "" {
+		return fmt.Errorf("enable WAL: %s", r[0].Error)
+	}
+	if len(r[0].Values) != 1 || r[0].Values[0][0] != "wal" {
+		return fmt.Errorf("enable WAL: journal mode not changed")
+	}
+
+	if _, err := db.sqlite3conn.Exec("PRAGMA wal_autocheckpoint=0", nil); err != nil {
+		return fmt.Errorf("disable WAL autocheckpoint: %s", err)
+	}
+	return nil
+}
+
+// Checkpoint copies all frames in the WAL into the database file, and then
+// truncates the WAL to zero bytes. While readers keep the WAL from being
+// truncated, the checkpoint is retried. Should they still do so after
+// checkpointRetries attempts, the frames are copied but the WAL is left as
+// it is, so it still holds every change since it was last truncated. It is
+// up to the caller to ensure no transaction is in progress when this
+// function is called.
+func (db *DB) Checkpoint() error {
+	for i := 0; i < checkpointRetries; i++ {
+		done, err := db.checkpoint("TRUNCATE")
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+		time.Sleep(checkpointRetryDelay)
+	}
+
+	stats.Add(numCheckpointsBusy, 1)
+	_, err := db.checkpoint("PASSIVE")
+	return err
+}
+
+// checkpoint runs a checkpoint of the WAL in the given mode, and returns
+// whether it completed, rather than being kept from doing so by readers.
+func (db *DB) checkpoint(mode string) (bool, error) {
+	rows, err := db.sqlite3conn.Query(fmt.Sprintf("PRAGMA wal_checkpoint(%s)", mode), nil)
+	if err != nil {
+		return false, fmt.Errorf("checkpoint WAL: %s", err)
+	}
+	defer rows.Close()
+
+	dest := make([]driver.Value, len(rows.Columns()))
+	if err := rows.Next(dest); err != nil {
+		if e, ok := err.(sqlite3.Error); ok && (e.Code == sqlite3.ErrBusy || e.Code == sqlite3.ErrLocked) {
+			return false, nil
+		}
+		return false, fmt.Errorf("checkpoint WAL: %s", err)
+	}
+	return dest[0] == int64(0), nil
+}
+
+// WALPath returns the path of the WAL file belonging to the on-disk database
+// at dbPath.
+func WALPath(dbPath string) string {
+	return dbPath + "-wal"
+}
+
+// ReplayWAL applies the WAL file at walPath, as copied from a database which
+// was identical to the database at dbPath, to the database at dbPath. The WAL
+// file is consumed, and the database is left in rollback journal mode.
+func ReplayWAL(dbPath, walPath string) error {
+	if err := os.Rename(walPath, WALPath(dbPath)); err != nil {
+		return fmt.Errorf("replay WAL: %s", err)
+	}
+
+	db, err := Open(dbPath)
+	if err != nil {
+		return fmt.Errorf("replay WAL: %s", err)
+	}
+	if err := db.Checkpoint(); err != nil {
+		db.Close()
+		return fmt.Errorf("replay WAL: %s", err)
+	}
+	if _, err := db.sqlite3conn.Exec("PRAGMA journal_mode=DELETE", nil); err != nil {
+		db.Close()
+		return fmt.Errorf("replay WAL: %s", err)
+	}
+	return db.Close()
+}
+
 // Copy copies the contents of the database to the given database. All other
 // attributes of the given database remain untouched e.g. whether it's an
 // on-disk database. This function can be called when changes to the source
@@ -548,7 +902,7 @@ func (db *DB) Dump(w io.Writer) error {
 			stmt = `DELETE FROM "sqlite_sequence";`
 		} else if table == "sqlite_stat1" {
 			stmt = `ANALYZE "sqlite_master";`
-		} else if strings.HasPrefix(table, "sqlite_") {
+		} else if strings.HasPrefix(table, "sqlite_") || IsInternalTable(table) {
 			continue
 		} else {
 			stmt = v[2].(string)
@@ -585,7 +939,7 @@ func (db *DB) Dump(w io.Writer) error {
 	}
 
 	// Do indexes, triggers, and views.
-	query = `SELECT "name", "type", "sql" FROM "sqlite_master"
+	query = `SELECT "name", "type", "sql", "tbl_name" FROM "sqlite_master"
 			  WHERE "sql" NOT NULL AND "type" IN ('index', 'trigger', 'view')`
 	rows, err = db.QueryStringStmt(query)
 	if err != nil {
@@ -593,6 +947,9 @@ func (db *DB) Dump(w io.Writer) error {
 	}
 	row = rows[0]
 	for _, v := range row.Values {
+		if t, _ := v[3].(string); IsInternalTable(t) {
+			continue
+		}
 		if _, err := w.Write([]byte(fmt.Sprintf("%s;\n", v[2]))); err != nil {
 			return err
 		}
@@ -605,6 +962,103 @@ func (db *DB) Dump(w io.Writer) error {
 	return nil
 }
 
+// Checksum returns a checksum of the rows of every table in the database,
+// keyed by table name, along with a checksum of the schema, keyed by
+// "sqlite_master". The checksum of a table is the sum of the hashes of its
+// rows, so databases with the same content have the same checksums, whatever
+// order the rows were written in. Virtual tables are skipped, as their
+// content is held in ordinary tables.
+func (db *DB) Checksum() (map[string]string, error) {
+	stats.Add(numChecksums, 1)
+	sums := make(map[string]string)
+
+	// The root pages of tables are left out, as they depend on the history
+	// of the database file, not just on its content.
+	sum, err := db.checksumRows(`SELECT "type", "name", "tbl_name", "sql" FROM "sqlite_master"`)
+	if err != nil {
+		return nil, fmt.Errorf("checksum of schema: %s", err)
+	}
+	sums["sqlite_master"] = sum
+
+	rows, err := db.QueryStringStmt(`SELECT "name" FROM "sqlite_master"
+		WHERE "type" = 'table' AND "sql" NOT LIKE 'CREATE VIRTUAL TABLE%'`)
+	if err != nil {
+		return nil, err
+	}
+	if rows[0].Error != "" {
+		return nil, errors.New(rows[0].Error)
+	}
+	for _, v := range rows[0].Values {
+		table := v[0].(string)
+		sum, err := db.checksumRows(fmt.Sprintf(`SELECT * FROM "%s"`, strings.Replace(table, `"`, `""`, -1)))
+		if err != nil {
+			return nil, fmt.Errorf("checksum of table %s: %s", table, err)
+		}
+		sums[table] = sum
+	}
+	return sums, nil
+}
+
+// checksumRows returns the hex-encoded sum, modulo 2^256, of the SHA-256
+// hashes of the rows returned by query. Values are hashed along with their
+// types, so that, for example, 1 and '1' differ.
+func (db *DB) checksumRows(query string) (string, error) {
+	rs, err := db.sqlite3conn.Query(query, nil)
+	if err != nil {
+		return "", err
+	}
+	defer rs.Close()
+
+	var sum [sha256.Size]byte
+	dest := make([]driver.Value, len(rs.Columns()))
+	for {
+		if err := rs.Next(dest); err != nil {
+			if err != io.EOF {
+				return "", err
+			}
+			break
+		}
+
+		h := sha256.New()
+		for _, v := range dest {
+			switch w := v.(type) {
+			case nil:
+				h.Write([]byte{'n'})
+			case int64:
+				h.Write([]byte{'i'})
+				binary.Write(h, binary.BigEndian, w)
+			case float64:
+				h.Write([]byte{'f'})
+				binary.Write(h, binary.BigEndian, math.Float64bits(w))
+			case []byte:
+				h.Write([]byte{'b'})
+				binary.Write(h, binary.BigEndian, uint64(len(w)))
+				h.Write(w)
+			case string:
+				h.Write([]byte{'s'})
+				binary.Write(h, binary.BigEndian, uint64(len(w)))
+				h.Write([]byte(w))
+			default:
+				// Values the driver converts, such as times.
+				s := fmt.Sprintf("%T:%v", w, w)
+				h.Write([]byte{'o'})
+				binary.Write(h, binary.BigEndian, uint64(len(s)))
+				h.Write([]byte(s))
+			}
+		}
+
+		// Add the hash to the sum, as a big-endian integer.
+		rh := h.Sum(nil)
+		var carry uint16
+		for i := len(sum) - 1; i >= 0; i-- {
+			t := uint16(sum[i]) + uint16(rh[i]) + carry
+			sum[i] = byte(t)
+			carry = t >> 8
+		}
+	}
+	return hex.EncodeToString(sum[:]), nil
+}
+
 func copyDatabase(dst *sqlite3.SQLiteConn, src *sqlite3.SQLiteConn) error {
 	bk, err := dst.Backup("main", src, "main")
 	if err != nil {
@@ -656,6 +1110,78 @@ func parametersToValues(parameters []*command.Parameter) ([]driver.Value, error)
 	return values, nil
 }
 
+// valueEquals returns whether v, a normalized row value, equals the value of
+// p. Integers and floats compare by numeric value. A Parameter holding no
+// value equals NULL.
+func valueEquals(v interface{}, p *command.Parameter) bool {
+	switch w := p.GetValue().(type) {
+	case nil:
+		return v == nil
+	case *command.Parameter_I:
+		switch x := v.(type) {
+		case int64:
+			return x == w.I
+		case float64:
+			return x == float64(w.I)
+		}
+	case *command.Parameter_D:
+		switch x := v.(type) {
+		case int64:
+			return float64(x) == w.D
+		case float64:
+			return x == w.D
+		}
+	case *command.Parameter_B:
+		switch x := v.(type) {
+		case bool:
+			return x == w.B
+		case int64:
+			return (x != 0) == w.B
+		}
+	case *command.Parameter_S:
+		switch x := v.(type) {
+		case string:
+			return x == w.S
+		case []byte:
+			return string(x) == w.S
+		}
+	case *command.Parameter_Y:
+		if x, ok := v.([]byte); ok {
+			return bytes.Equal(x, w.Y)
+		}
+	}
+	return false
+}
+
+// parameterValue returns the value held by p, or nil if it holds none.
+func parameterValue(p *command.Parameter) interface{} {
+	switch w := p.GetValue().(type) {
+	case *command.Parameter_I:
+		return w.I
+	case *command.Parameter_D:
+		return w.D
+	case *command.Parameter_B:
+		return w.B
+	case *command.Parameter_Y:
+		return w.Y
+	case *command.Parameter_S:
+		return w.S
+	}
+	return nil
+}
+
+// displayValue returns v formatted for an error message.
+func displayValue(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return fmt.Sprintf("%q", x)
+	default:
+		return fmt.Sprintf("%v", x)
+	}
+}
+
 // normalizeRowValues performs some normalization of values in the returned rows.
 // Text values come over (from sqlite-go) as []byte instead of strings
 // for some reason, so we have explicitly convert (but only when type
@@ -0,0 +1,122 @@
+package db
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/minghsu0107/tqlite/command"
+)
+
+func Test_InternalTablesProtected(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "db.sqlite"))
+	if err != nil {
+		t.Fatalf("failed to open database: %s", err)
+	}
+	defer db.Close()
+
+	for _, stmt := range []string{
+		"CREATE TABLE _tqlite_foo (id INTEGER)",
+		"CREATE TEMP TABLE _TQLITE_foo (id INTEGER)",
+		"CREATE VIEW _tqlite_bar AS SELECT 1",
+	} {
+		r, err := db.ExecuteStringStmt(stmt)
+		if err != nil {
+			t.Fatalf("failed to execute %s: %s", stmt, err)
+		}
+		if !strings.Contains(r[0].Error, "not authorized") {
+			t.Fatalf("%s not denied, result %+v", stmt, r[0])
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %s", err)
+	}
+	for _, stmt := range []string{
+		"CREATE TABLE _tqlite_foo (id INTEGER)",
+		"CREATE INDEX _tqlite_foo_id ON _tqlite_foo (id)",
+		"INSERT INTO _tqlite_foo VALUES (1)",
+	} {
+		r, err := tx.ExecuteInternal(request(stmt))
+		if err != nil || r[0].Error != "" {
+			t.Fatalf("internal statement %s failed: %v %+v", stmt, err, r)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("failed to commit: %s", err)
+	}
+
+	for _, stmt := range []string{
+		"INSERT INTO _tqlite_foo VALUES (2)",
+		"UPDATE _tqlite_foo SET id = 2",
+		"DELETE FROM _tqlite_foo",
+		"DROP TABLE _tqlite_foo",
+		"DROP INDEX _tqlite_foo_id",
+		"ALTER TABLE _tqlite_foo ADD COLUMN name TEXT",
+		"CREATE TRIGGER t AFTER INSERT ON _tqlite_foo BEGIN SELECT 1; END",
+	} {
+		r, err := db.ExecuteStringStmt(stmt)
+		if err != nil {
+			t.Fatalf("failed to execute %s: %s", stmt, err)
+		}
+		if !strings.Contains(r[0].Error, "not authorized") {
+			t.Fatalf("%s not denied, result %+v", stmt, r[0])
+		}
+	}
+
+	// Internal tables can still be read.
+	r, err := db.QueryStringStmt("SELECT id FROM _tqlite_foo")
+	if err != nil {
+		t.Fatalf("failed to query internal table: %s", err)
+	}
+	if r[0].Error != "" || len(r[0].Values) != 1 {
+		t.Fatalf("wrong rows from internal table: %+v", r[0])
+	}
+}
+
+func Test_DumpSkipsInternalTables(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "db.sqlite"))
+	if err != nil {
+		t.Fatalf("failed to open database: %s", err)
+	}
+	defer db.Close()
+
+	if _, err := db.ExecuteStringStmt(`CREATE TABLE foo (id INTEGER NOT NULL PRIMARY KEY, name TEXT)`); err != nil {
+		t.Fatalf("failed to create table: %s", err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %s", err)
+	}
+	for _, stmt := range []string{
+		"CREATE TABLE _tqlite_foo (id INTEGER)",
+		"CREATE INDEX _tqlite_foo_id ON _tqlite_foo (id)",
+		"INSERT INTO _tqlite_foo VALUES (1)",
+	} {
+		if _, err := tx.ExecuteInternal(request(stmt)); err != nil {
+			t.Fatalf("internal statement %s failed: %s", stmt, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("failed to commit: %s", err)
+	}
+
+	var b bytes.Buffer
+	if err := db.Dump(&b); err != nil {
+		t.Fatalf("failed to dump: %s", err)
+	}
+	if strings.Contains(b.String(), "_tqlite_") {
+		t.Fatalf("dump includes internal table:\n%s", b.String())
+	}
+	if !strings.Contains(b.String(), "CREATE TABLE foo") {
+		t.Fatalf("dump does not include table foo:\n%s", b.String())
+	}
+}
+
+func request(stmt string) *command.Request {
+	return &command.Request{
+		Statements: []*command.Statement{{Sql: stmt}},
+	}
+}
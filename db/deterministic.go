@@ -0,0 +1,103 @@
+package db
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minghsu0107/tqlite/command"
+)
+
+// maxRandomBlob is the largest blob RANDOMBLOB() returns, the largest blob
+// SQLite allows by default.
+const maxRandomBlob = 1000000000
+
+// registerFuncs replaces SQLite's RANDOM() and RANDOMBLOB() with functions
+// which draw from the random numbers bound to the request being executed, if
+// any. As they replace the functions on the connection, they are used
+// wherever the functions are called, including in column defaults and
+// triggers. The current time is bound through the connection's clock.
+func (db *DB) registerFuncs() error {
+	funcs := []struct {
+		name string
+		impl interface{}
+	}{
+		{"random", db.random},
+		{"randomblob", db.randomBlob},
+	}
+	for _, f := range funcs {
+		if err := db.sqlite3conn.RegisterFunc(f.name, f.impl, false); err != nil {
+			return fmt.Errorf("register function %s: %s", f.name, err)
+		}
+	}
+	return nil
+}
+
+// bind makes RANDOM(), RANDOMBLOB() and the current time of SQLite return
+// the values bound to req, if any, until the returned function is called. A
+// request with bound values has the database to itself, so that no other
+// request draws from its random numbers, or sees its time.
+func (db *DB) bind(req *command.Request) func() {
+	if req.Now == 0 {
+		db.boundMu.RLock()
+		return db.boundMu.RUnlock
+	}
+	db.boundMu.Lock()
+	db.rand = rand.New(rand.NewSource(req.RandomSeed))
+	db.clock.Set(time.Unix(0, req.Now))
+	return func() {
+		db.rand = nil
+		db.clock.Set(time.Time{})
+		db.boundMu.Unlock()
+	}
+}
+
+// random returns the next random integer for RANDOM().
+func (db *DB) random() int64 {
+	if db.rand != nil {
+		return int64(db.rand.Uint64())
+	}
+	b := make([]byte, 8)
+	if _, err := crand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to read random bytes: %s", err))
+	}
+	return int64(binary.LittleEndian.Uint64(b))
+}
+
+// randomBlob returns a blob of n random bytes for RANDOMBLOB(n). As in
+// SQLite, n is converted to an integer, and a blob of one byte is returned if
+// it is less than one.
+func (db *DB) randomBlob(n interface{}) ([]byte, error) {
+	var size int64
+	switch v := n.(type) {
+	case int64:
+		size = v
+	case float64:
+		size = int64(v)
+	case string:
+		size, _ = strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	case []byte:
+		size, _ = strconv.ParseInt(strings.TrimSpace(string(v)), 10, 64)
+	}
+	if size < 1 {
+		size = 1
+	}
+	if size > maxRandomBlob {
+		return nil, errors.New("string or blob too big")
+	}
+
+	b := make([]byte, size)
+	if db.rand != nil {
+		db.rand.Read(b)
+		return b, nil
+	}
+	if _, err := crand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
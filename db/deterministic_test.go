@@ -0,0 +1,107 @@
+package db
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/minghsu0107/tqlite/command"
+)
+
+func Test_BoundFunctions(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 30, 15, 250000000, time.UTC)
+	stmts := []string{
+		"CREATE TABLE foo (id INTEGER PRIMARY KEY, r INTEGER, b BLOB, at TEXT DEFAULT CURRENT_TIMESTAMP)",
+		"INSERT INTO foo(r, b) SELECT random(), randomblob(4) FROM (SELECT 1 UNION ALL SELECT 2 UNION ALL SELECT 3)",
+		"UPDATE foo SET r = random() WHERE id > 1",
+		"CREATE TABLE bar AS SELECT random() AS r, datetime('now') AS at, date() AS day",
+	}
+
+	// Every node executing the same requests gets the same data.
+	var checksums []map[string]string
+	for i := 0; i < 2; i++ {
+		db := mustOpenDB(t)
+		for j, stmt := range stmts {
+			req := request(stmt)
+			req.RandomSeed, req.Now = int64(j), now.UnixNano()
+			mustExecuteRequest(t, db, req)
+		}
+		sums, err := db.Checksum()
+		if err != nil {
+			t.Fatalf("failed to checksum database: %s", err)
+		}
+		checksums = append(checksums, sums)
+
+		// Every call gets its own value, not one for each statement.
+		mustQueryEqual(t, db, "SELECT COUNT(DISTINCT r), COUNT(DISTINCT b) FROM foo", "[[3 3]]")
+		mustQueryEqual(t, db, "SELECT DISTINCT at FROM foo", "[[2021-06-01 12:30:15]]")
+		mustQueryEqual(t, db, "SELECT at, day FROM bar", "[[2021-06-01 12:30:15 2021-06-01]]")
+	}
+	if fmt.Sprint(checksums[0]) != fmt.Sprint(checksums[1]) {
+		t.Fatalf("nodes diverged: %v, %v", checksums[0], checksums[1])
+	}
+
+	// Without bound values, the functions are random, and the time is now.
+	db := mustOpenDB(t)
+	mustExecute(t, db, stmts[0])
+	mustExecute(t, db, stmts[1])
+	mustQueryEqual(t, db, "SELECT COUNT(DISTINCT r), COUNT(*) FROM foo WHERE at > '2021-06-02'", "[[3 3]]")
+}
+
+func Test_BoundTimeInSchema(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 30, 15, 250000000, time.UTC)
+	stmts := []string{
+		"CREATE TABLE foo (id INTEGER PRIMARY KEY, at TEXT DEFAULT (datetime('now')), s INTEGER)",
+		"CREATE TABLE log (at TEXT)",
+		"CREATE TRIGGER t AFTER INSERT ON foo BEGIN INSERT INTO log VALUES(strftime('%Y-%m-%d %H:%M:%f')); END",
+		"CREATE VIEW v AS SELECT julianday('now') AS day",
+		"INSERT INTO foo(s) SELECT CAST(day AS INTEGER) FROM v",
+	}
+
+	// Times evaluated later, in defaults, triggers and views, are bound too.
+	db := mustOpenDB(t)
+	for _, stmt := range stmts {
+		req := request(stmt)
+		req.Now = now.UnixNano()
+		mustExecuteRequest(t, db, req)
+	}
+	mustQueryEqual(t, db, "SELECT at, s FROM foo", "[[2021-06-01 12:30:15 2459367]]")
+	mustQueryEqual(t, db, "SELECT at FROM log", "[[2021-06-01 12:30:15.250]]")
+
+	// Without a bound time, SQLite takes the time now.
+	mustExecute(t, db, "INSERT INTO foo(s) VALUES(0)")
+	mustQueryEqual(t, db, "SELECT COUNT(*) FROM foo WHERE at > '2021-06-02'", "[[1]]")
+}
+
+func mustOpenDB(t *testing.T) *DB {
+	db, err := Open(filepath.Join(t.TempDir(), "db.sqlite"))
+	if err != nil {
+		t.Fatalf("failed to open database: %s", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func mustExecuteRequest(t *testing.T, db *DB, req *command.Request) {
+	r, err := db.Execute(req, false)
+	if err != nil {
+		t.Fatalf("failed to execute %s: %s", req.Statements[0].Sql, err)
+	}
+	if r[0].Error != "" {
+		t.Fatalf("failed to execute %s: %s", req.Statements[0].Sql, r[0].Error)
+	}
+}
+
+func mustQueryEqual(t *testing.T, db *DB, query, exp string) {
+	r, err := db.QueryStringStmt(query)
+	if err != nil {
+		t.Fatalf("failed to query %s: %s", query, err)
+	}
+	if r[0].Error != "" {
+		t.Fatalf("failed to query %s: %s", query, r[0].Error)
+	}
+	if got := fmt.Sprint(r[0].Values); got != exp {
+		t.Fatalf("wrong rows for %s, exp %s, got %s", query, exp, got)
+	}
+}
@@ -0,0 +1,218 @@
+package db
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// StatementScanner reads SQL text, such as a database dump, one complete
+// statement at a time. Statements end at a semicolon which is not part of a
+// string literal, quoted identifier, comment, or the body of a trigger.
+type StatementScanner struct {
+	r      *bufio.Reader
+	offset int64
+}
+
+// NewStatementScanner returns a StatementScanner reading from r.
+func NewStatementScanner(r io.Reader) *StatementScanner {
+	return &StatementScanner{r: bufio.NewReader(r)}
+}
+
+// Offset returns the number of bytes consumed by the scanner so far. After a
+// call to Scan it is the offset just past the statement returned.
+func (s *StatementScanner) Offset() int64 {
+	return s.offset
+}
+
+// Scan returns the next statement, including the terminating semicolon, if
+// any. Comments preceding the statement, and statements made up only of
+// whitespace and comments, are skipped. io.EOF is returned once all
+// statements have been read.
+func (s *StatementScanner) Scan() (string, error) {
+	var stmt strings.Builder
+	var word strings.Builder
+	var words []string // Leading keywords, to recognize CREATE TRIGGER.
+	trigger, inBody, bodyEnded := false, false, false
+	caseDepth := 0
+
+	// endWord handles the keyword just read, if any.
+	endWord := func() {
+		if word.Len() == 0 {
+			return
+		}
+		w := strings.ToUpper(word.String())
+		word.Reset()
+
+		if len(words) < 4 {
+			words = append(words, w)
+			trigger = isCreateTrigger(words)
+		}
+		if !trigger {
+			return
+		}
+		switch w {
+		case "BEGIN":
+			inBody = true
+		case "CASE":
+			caseDepth++
+		case "END":
+			if caseDepth > 0 {
+				caseDepth--
+			} else if inBody {
+				bodyEnded = true
+			}
+		}
+	}
+
+	for {
+		c, err := s.readByte()
+		if err == io.EOF {
+			endWord()
+			if stmt.Len() == 0 {
+				return "", io.EOF
+			}
+			return strings.TrimSpace(stmt.String()), nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if isWordByte(c) {
+			word.WriteByte(c)
+			stmt.WriteByte(c)
+			continue
+		}
+		endWord()
+
+		switch c {
+		case '\'', '"', '`', '[':
+			end := c
+			if c == '[' {
+				end = ']'
+			}
+			stmt.WriteByte(c)
+			if err := s.readUntil(&stmt, end); err != nil {
+				return "", err
+			}
+		case '-', '/':
+			next, err := s.readByte()
+			if err != nil && err != io.EOF {
+				return "", err
+			}
+			isComment := err == nil && ((c == '-' && next == '-') || (c == '/' && next == '*'))
+			if err == nil && !isComment {
+				s.unreadByte()
+			}
+			if !isComment {
+				stmt.WriteByte(c)
+				continue
+			}
+
+			// Comments before the statement are dropped.
+			var comment strings.Builder
+			comment.WriteByte(c)
+			comment.WriteByte(next)
+			if c == '-' {
+				err = s.readUntil(&comment, '\n')
+			} else {
+				err = s.readBlockComment(&comment)
+			}
+			if err != nil {
+				return "", err
+			}
+			if stmt.Len() > 0 {
+				stmt.WriteString(comment.String())
+			}
+		case ';':
+			if stmt.Len() == 0 {
+				// Empty statement.
+				continue
+			}
+			if trigger && !bodyEnded {
+				stmt.WriteByte(c)
+				continue
+			}
+			stmt.WriteByte(c)
+			return strings.TrimSpace(stmt.String()), nil
+		default:
+			if stmt.Len() == 0 && isSpaceByte(c) {
+				continue
+			}
+			stmt.WriteByte(c)
+		}
+	}
+}
+
+// readUntil copies bytes to b up to and including end. The end of the input
+// is treated as the end of the token.
+func (s *StatementScanner) readUntil(b *strings.Builder, end byte) error {
+	for {
+		c, err := s.readByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		b.WriteByte(c)
+		if c == end {
+			return nil
+		}
+	}
+}
+
+// readBlockComment copies bytes to b up to and including the end of a block
+// comment.
+func (s *StatementScanner) readBlockComment(b *strings.Builder) error {
+	var prev byte
+	for {
+		c, err := s.readByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		b.WriteByte(c)
+		if prev == '*' && c == '/' {
+			return nil
+		}
+		prev = c
+	}
+}
+
+func (s *StatementScanner) readByte() (byte, error) {
+	c, err := s.r.ReadByte()
+	if err == nil {
+		s.offset++
+	}
+	return c, err
+}
+
+func (s *StatementScanner) unreadByte() {
+	if s.r.UnreadByte() == nil {
+		s.offset--
+	}
+}
+
+// isCreateTrigger returns whether the leading keywords of a statement are
+// those of a CREATE TRIGGER statement.
+func isCreateTrigger(words []string) bool {
+	if len(words) < 2 || words[0] != "CREATE" {
+		return false
+	}
+	if words[1] == "TRIGGER" {
+		return true
+	}
+	return len(words) >= 3 && (words[1] == "TEMP" || words[1] == "TEMPORARY") && words[2] == "TRIGGER"
+}
+
+func isWordByte(c byte) bool {
+	return (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+		c == '_' || c == '$' || c >= 0x80
+}
+
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
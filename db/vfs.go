@@ -0,0 +1,201 @@
+package db
+
+/*
+#include <stdlib.h>
+#include <string.h>
+
+// The definitions below are those of sqlite3.h, which the SQLite driver
+// compiles, and which are part of SQLite's stable interface.
+typedef long long int sqlite3_int64;
+typedef struct sqlite3_file sqlite3_file;
+typedef struct sqlite3_vfs sqlite3_vfs;
+typedef void (*sqlite3_syscall_ptr)(void);
+struct sqlite3_vfs {
+	int iVersion;
+	int szOsFile;
+	int mxPathname;
+	sqlite3_vfs *pNext;
+	const char *zName;
+	void *pAppData;
+	int (*xOpen)(sqlite3_vfs*, const char *zName, sqlite3_file*, int flags, int *pOutFlags);
+	int (*xDelete)(sqlite3_vfs*, const char *zName, int syncDir);
+	int (*xAccess)(sqlite3_vfs*, const char *zName, int flags, int *pResOut);
+	int (*xFullPathname)(sqlite3_vfs*, const char *zName, int nOut, char *zOut);
+	void *(*xDlOpen)(sqlite3_vfs*, const char *zFilename);
+	void (*xDlError)(sqlite3_vfs*, int nByte, char *zErrMsg);
+	void (*(*xDlSym)(sqlite3_vfs*,void*, const char *zSymbol))(void);
+	void (*xDlClose)(sqlite3_vfs*, void*);
+	int (*xRandomness)(sqlite3_vfs*, int nByte, char *zOut);
+	int (*xSleep)(sqlite3_vfs*, int microseconds);
+	int (*xCurrentTime)(sqlite3_vfs*, double*);
+	int (*xGetLastError)(sqlite3_vfs*, int, char *);
+	int (*xCurrentTimeInt64)(sqlite3_vfs*, sqlite3_int64*);
+	int (*xSetSystemCall)(sqlite3_vfs*, const char *zName, sqlite3_syscall_ptr);
+	sqlite3_syscall_ptr (*xGetSystemCall)(sqlite3_vfs*, const char *zName);
+	const char *(*xNextSystemCall)(sqlite3_vfs*, const char *zName);
+};
+sqlite3_vfs *sqlite3_vfs_find(const char *zVfsName);
+int sqlite3_vfs_register(sqlite3_vfs*, int makeDflt);
+int sqlite3_vfs_unregister(sqlite3_vfs*);
+
+// clockVfs is a VFS which passes every call to the default VFS, other than
+// those for the current time, when a time is bound to it.
+typedef struct {
+	sqlite3_vfs base;
+	sqlite3_vfs *orig;
+	sqlite3_int64 now; // Bound time, as a Julian day number in milliseconds, or 0.
+} clockVfs;
+
+#define ORIG(p) (((clockVfs*)(p))->orig)
+
+static int clockOpen(sqlite3_vfs *p, const char *z, sqlite3_file *f, int flags, int *out) {
+	return ORIG(p)->xOpen(ORIG(p), z, f, flags, out);
+}
+static int clockDelete(sqlite3_vfs *p, const char *z, int syncDir) {
+	return ORIG(p)->xDelete(ORIG(p), z, syncDir);
+}
+static int clockAccess(sqlite3_vfs *p, const char *z, int flags, int *out) {
+	return ORIG(p)->xAccess(ORIG(p), z, flags, out);
+}
+static int clockFullPathname(sqlite3_vfs *p, const char *z, int n, char *out) {
+	return ORIG(p)->xFullPathname(ORIG(p), z, n, out);
+}
+static void *clockDlOpen(sqlite3_vfs *p, const char *z) {
+	return ORIG(p)->xDlOpen(ORIG(p), z);
+}
+static void clockDlError(sqlite3_vfs *p, int n, char *z) {
+	ORIG(p)->xDlError(ORIG(p), n, z);
+}
+static void (*clockDlSym(sqlite3_vfs *p, void *h, const char *z))(void) {
+	return ORIG(p)->xDlSym(ORIG(p), h, z);
+}
+static void clockDlClose(sqlite3_vfs *p, void *h) {
+	ORIG(p)->xDlClose(ORIG(p), h);
+}
+static int clockRandomness(sqlite3_vfs *p, int n, char *out) {
+	return ORIG(p)->xRandomness(ORIG(p), n, out);
+}
+static int clockSleep(sqlite3_vfs *p, int us) {
+	return ORIG(p)->xSleep(ORIG(p), us);
+}
+static int clockGetLastError(sqlite3_vfs *p, int n, char *z) {
+	return ORIG(p)->xGetLastError(ORIG(p), n, z);
+}
+static int clockCurrentTimeInt64(sqlite3_vfs *p, sqlite3_int64 *out) {
+	clockVfs *c = (clockVfs*)p;
+	if (c->now != 0) {
+		*out = c->now;
+		return 0;
+	}
+	return c->orig->xCurrentTimeInt64(c->orig, out);
+}
+static int clockCurrentTime(sqlite3_vfs *p, double *out) {
+	sqlite3_int64 t;
+	int rc = clockCurrentTimeInt64(p, &t);
+	*out = t / 86400000.0;
+	return rc;
+}
+
+static clockVfs *newClockVfs(const char *name) {
+	sqlite3_vfs *orig = sqlite3_vfs_find(0);
+	clockVfs *c;
+	if (orig == 0 || orig->iVersion < 2) {
+		return 0;
+	}
+	c = (clockVfs*)calloc(1, sizeof(clockVfs));
+	if (c == 0) {
+		return 0;
+	}
+	c->orig = orig;
+	c->base.iVersion = 2;
+	c->base.szOsFile = orig->szOsFile;
+	c->base.mxPathname = orig->mxPathname;
+	c->base.zName = name;
+	c->base.xOpen = clockOpen;
+	c->base.xDelete = clockDelete;
+	c->base.xAccess = clockAccess;
+	c->base.xFullPathname = clockFullPathname;
+	c->base.xDlOpen = clockDlOpen;
+	c->base.xDlError = clockDlError;
+	c->base.xDlSym = clockDlSym;
+	c->base.xDlClose = clockDlClose;
+	c->base.xRandomness = clockRandomness;
+	c->base.xSleep = clockSleep;
+	c->base.xCurrentTime = clockCurrentTime;
+	c->base.xGetLastError = clockGetLastError;
+	c->base.xCurrentTimeInt64 = clockCurrentTimeInt64;
+	if (sqlite3_vfs_register(&c->base, 0) != 0) {
+		free(c);
+		return 0;
+	}
+	return c;
+}
+
+static void freeClockVfs(clockVfs *c) {
+	sqlite3_vfs_unregister(&c->base);
+	free(c);
+}
+
+static void setClockVfsTime(clockVfs *c, sqlite3_int64 now) {
+	c->now = now;
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// unixEpochJulianMs is the Unix epoch, as a Julian day number in
+// milliseconds, which is how SQLite keeps time.
+const unixEpochJulianMs = 210866760000000
+
+var numClockVFSs int64
+
+// clock is a VFS, used by a single connection, through which SQLite takes the
+// current time, for 'now' in the date and time functions. Every other
+// operation is passed to the default VFS.
+type clock struct {
+	name *C.char
+	vfs  *C.clockVfs
+}
+
+func newClock() (*clock, error) {
+	name := C.CString(fmt.Sprintf("tqlite-clock-%d", atomic.AddInt64(&numClockVFSs, 1)))
+	vfs := C.newClockVfs(name)
+	if vfs == nil {
+		C.free(unsafe.Pointer(name))
+		return nil, errors.New("failed to register clock VFS")
+	}
+	return &clock{name: name, vfs: vfs}, nil
+}
+
+// Name returns the name of the VFS, for opening a connection with it.
+func (c *clock) Name() string {
+	return C.GoString(c.name)
+}
+
+// Set makes SQLite take t as the current time. If t is zero, SQLite takes the
+// current time from the system again.
+func (c *clock) Set(t time.Time) {
+	var ms int64
+	if !t.IsZero() {
+		ms = t.UnixNano()/int64(time.Millisecond) + unixEpochJulianMs
+	}
+	C.setClockVfsTime(c.vfs, C.sqlite3_int64(ms))
+}
+
+// Close unregisters the VFS. It must not be called until the connection
+// using it is closed. Calling it again has no effect.
+func (c *clock) Close() {
+	if c.vfs == nil {
+		return
+	}
+	C.freeClockVfs(c.vfs)
+	C.free(unsafe.Pointer(c.name))
+	c.vfs, c.name = nil, nil
+}
@@ -0,0 +1,173 @@
+package db
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_CheckpointTruncatesWAL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.sqlite")
+	db := mustOpenWAL(t, path, "")
+	defer db.Close()
+
+	mustExecute(t, db, "CREATE TABLE foo (id INTEGER NOT NULL PRIMARY KEY, name TEXT)")
+	mustExecute(t, db, `INSERT INTO foo(name) VALUES("fiona")`)
+	if sz := mustFileSize(t, WALPath(path)); sz == 0 {
+		t.Fatal("WAL is empty after writes")
+	}
+
+	if err := db.Checkpoint(); err != nil {
+		t.Fatalf("failed to checkpoint: %s", err)
+	}
+	if sz := mustFileSize(t, WALPath(path)); sz != 0 {
+		t.Fatalf("WAL not truncated by checkpoint, size %d", sz)
+	}
+}
+
+func Test_CheckpointBusy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.sqlite")
+	db := mustOpenWAL(t, path, "_busy_timeout=0")
+	defer db.Close()
+
+	mustExecute(t, db, "CREATE TABLE foo (id INTEGER NOT NULL PRIMARY KEY, name TEXT)")
+	mustExecute(t, db, `INSERT INTO foo(name) VALUES("fiona")`)
+
+	// A reader on another connection keeps the WAL from being truncated.
+	rdb, err := OpenWithDSN(path, "_busy_timeout=0")
+	if err != nil {
+		t.Fatalf("failed to open reader: %s", err)
+	}
+	defer rdb.Close()
+	tx, err := rdb.Begin()
+	if err != nil {
+		t.Fatalf("failed to begin read transaction: %s", err)
+	}
+	if _, err := tx.Query(request("SELECT * FROM foo"), false); err != nil {
+		t.Fatalf("failed to query: %s", err)
+	}
+
+	walSz := mustFileSize(t, WALPath(path))
+	busy := stats.Get(numCheckpointsBusy).String()
+	if err := db.Checkpoint(); err != nil {
+		t.Fatalf("checkpoint failed with reader present: %s", err)
+	}
+	if stats.Get(numCheckpointsBusy).String() == busy {
+		t.Fatal("busy checkpoint not counted")
+	}
+	if sz := mustFileSize(t, WALPath(path)); sz != walSz {
+		t.Fatalf("WAL changed while a reader held it, size %d, was %d", sz, walSz)
+	}
+
+	// Once the reader is done, the WAL is truncated again.
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("failed to end read transaction: %s", err)
+	}
+	if err := db.Checkpoint(); err != nil {
+		t.Fatalf("failed to checkpoint: %s", err)
+	}
+	if sz := mustFileSize(t, WALPath(path)); sz != 0 {
+		t.Fatalf("WAL not truncated by checkpoint, size %d", sz)
+	}
+}
+
+// Test_ReplayWAL checks that copies of a database file and of the WAL
+// written on top of it combine to the database, as WAL snapshots rely on.
+func Test_ReplayWAL(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "db.sqlite")
+	db := mustOpenWAL(t, path, "")
+	defer db.Close()
+
+	mustExecute(t, db, "CREATE TABLE foo (id INTEGER NOT NULL PRIMARY KEY, name TEXT)")
+	mustExecute(t, db, `INSERT INTO foo(name) VALUES("fiona")`)
+	if err := db.Checkpoint(); err != nil {
+		t.Fatalf("failed to checkpoint: %s", err)
+	}
+	snapPath := filepath.Join(dir, "snap.sqlite")
+	if err := db.Backup(snapPath); err != nil {
+		t.Fatalf("failed to back up: %s", err)
+	}
+
+	// Changes since the backup are only in the WAL. They are copied twice,
+	// with a checkpoint in between, as for two WAL snapshots in a row.
+	mustExecute(t, db, `INSERT INTO foo(name) VALUES("declan")`)
+	walPath1 := mustCopyFile(t, WALPath(path), filepath.Join(dir, "wal1"))
+	if err := db.Checkpoint(); err != nil {
+		t.Fatalf("failed to checkpoint: %s", err)
+	}
+	mustExecute(t, db, `UPDATE foo SET name="fiona2" WHERE id=1`)
+	mustExecute(t, db, `INSERT INTO foo(name) VALUES("aoife")`)
+	walPath2 := mustCopyFile(t, WALPath(path), filepath.Join(dir, "wal2"))
+
+	for _, w := range []string{walPath1, walPath2} {
+		if err := ReplayWAL(snapPath, w); err != nil {
+			t.Fatalf("failed to replay WAL %s: %s", w, err)
+		}
+		if _, err := os.Stat(w); !os.IsNotExist(err) {
+			t.Fatalf("WAL %s not consumed by replay", w)
+		}
+	}
+
+	rdb, err := Open(snapPath)
+	if err != nil {
+		t.Fatalf("failed to open replayed database: %s", err)
+	}
+	defer rdb.Close()
+	r, err := rdb.QueryStringStmt("SELECT id, name FROM foo ORDER BY id")
+	if err != nil {
+		t.Fatalf("failed to query replayed database: %s", err)
+	}
+	if exp, got := `[[1 fiona2] [2 declan] [3 aoife]]`, fmt.Sprint(r[0].Values); exp != got {
+		t.Fatalf("wrong replayed data, exp %s, got %s", exp, got)
+	}
+	r, err = rdb.QueryStringStmt("PRAGMA journal_mode")
+	if err != nil {
+		t.Fatalf("failed to query journal mode: %s", err)
+	}
+	if exp, got := `[[delete]]`, fmt.Sprint(r[0].Values); exp != got {
+		t.Fatalf("wrong journal mode after replay, exp %s, got %s", exp, got)
+	}
+}
+
+func mustOpenWAL(t *testing.T, path, dsn string) *DB {
+	db, err := OpenWithDSN(path, dsn)
+	if err != nil {
+		t.Fatalf("failed to open database: %s", err)
+	}
+	if err := db.EnableWAL(); err != nil {
+		t.Fatalf("failed to enable WAL: %s", err)
+	}
+	return db
+}
+
+func mustExecute(t *testing.T, db *DB, stmt string) {
+	r, err := db.ExecuteStringStmt(stmt)
+	if err != nil {
+		t.Fatalf("failed to execute %s: %s", stmt, err)
+	}
+	if r[0].Error != "" {
+		t.Fatalf("failed to execute %s: %s", stmt, r[0].Error)
+	}
+}
+
+func mustFileSize(t *testing.T, path string) int64 {
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat %s: %s", path, err)
+	}
+	return fi.Size()
+}
+
+func mustCopyFile(t *testing.T, src, dst string) string {
+	b, err := ioutil.ReadFile(src)
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", src, err)
+	}
+	if err := ioutil.WriteFile(dst, b, 0644); err != nil {
+		t.Fatalf("failed to write %s: %s", dst, err)
+	}
+	return dst
+}
@@ -0,0 +1,176 @@
+// Package disco provides discovery of the HTTP API addresses of the nodes
+// which form a cluster, for nodes which bootstrap it together.
+package disco
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lookupTimeout is the time allowed for a single DNS lookup.
+const lookupTimeout = 5 * time.Second
+
+// Provider is the interface discovery mechanisms must implement.
+type Provider interface {
+	// Lookup returns the HTTP API addresses, as host:port, of the nodes
+	// currently discovered.
+	Lookup() ([]string, error)
+
+	// String returns a description of the provider.
+	String() string
+}
+
+// Static is a Provider which returns a fixed list of addresses.
+type Static struct {
+	addrs []string
+}
+
+// NewStatic returns a Provider which always returns addrs.
+func NewStatic(addrs []string) *Static {
+	return &Static{addrs: addrs}
+}
+
+// Lookup returns the addresses of the provider.
+func (s *Static) Lookup() ([]string, error) {
+	return s.addrs, nil
+}
+
+// String returns a description of the provider.
+func (s *Static) String() string {
+	return fmt.Sprintf("static addresses %s", strings.Join(s.addrs, ","))
+}
+
+// DNS is a Provider which looks up the A and AAAA records of a name. Every
+// node is expected to serve the HTTP API on the same port.
+type DNS struct {
+	name     string
+	port     int
+	resolver *net.Resolver
+}
+
+// NewDNS returns a Provider which looks up the A and AAAA records of name,
+// and returns their addresses with port. If server is set, it is the
+// host:port of the DNS server queried, instead of the system's.
+func NewDNS(name string, port int, server string) *DNS {
+	return &DNS{name: name, port: port, resolver: newResolver(server)}
+}
+
+// Lookup returns the addresses of the records found.
+func (d *DNS) Lookup() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+
+	ips, err := d.resolver.LookupIPAddr(ctx, d.name)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]string, len(ips))
+	for i := range ips {
+		addrs[i] = net.JoinHostPort(ips[i].IP.String(), strconv.Itoa(d.port))
+	}
+	sort.Strings(addrs)
+	return addrs, nil
+}
+
+// String returns a description of the provider.
+func (d *DNS) String() string {
+	return fmt.Sprintf("DNS name %s, port %d", d.name, d.port)
+}
+
+// DNSSRV is a Provider which looks up the SRV records of a name, which give
+// both the host and the HTTP API port of every node.
+type DNSSRV struct {
+	name     string
+	resolver *net.Resolver
+}
+
+// NewDNSSRV returns a Provider which looks up the SRV records of name, such
+// as _tqlite._tcp.example.com. If server is set, it is the host:port of the
+// DNS server queried, instead of the system's.
+func NewDNSSRV(name, server string) *DNSSRV {
+	return &DNSSRV{name: name, resolver: newResolver(server)}
+}
+
+// Lookup returns the addresses of the records found.
+func (d *DNSSRV) Lookup() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+
+	_, srvs, err := d.resolver.LookupSRV(ctx, "", "", d.name)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]string, len(srvs))
+	for i := range srvs {
+		addrs[i] = net.JoinHostPort(strings.TrimSuffix(srvs[i].Target, "."), strconv.Itoa(int(srvs[i].Port)))
+	}
+	sort.Strings(addrs)
+	return addrs, nil
+}
+
+// String returns a description of the provider.
+func (d *DNSSRV) String() string {
+	return fmt.Sprintf("DNS SRV name %s", d.name)
+}
+
+// File is a Provider which reads addresses from a file, one per line. The
+// file is read on every lookup, so it may be changed while nodes wait for
+// each other. Blank lines, and lines starting with #, are ignored.
+type File struct {
+	path string
+}
+
+// NewFile returns a Provider which reads addresses from the file at path.
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+// Lookup returns the addresses in the file.
+func (f *File) Lookup() ([]string, error) {
+	fd, err := os.Open(f.path)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	var addrs []string
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		addrs = append(addrs, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return addrs, nil
+}
+
+// String returns a description of the provider.
+func (f *File) String() string {
+	return fmt.Sprintf("peers file %s", f.path)
+}
+
+// newResolver returns a resolver which queries the DNS server at server, or
+// the system's resolver if server is not set.
+func newResolver(server string) *net.Resolver {
+	if server == "" {
+		return net.DefaultResolver
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
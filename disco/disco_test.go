@@ -0,0 +1,224 @@
+package disco
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	typeA    = 1
+	typeAAAA = 28
+	typeSRV  = 33
+)
+
+func Test_Static(t *testing.T) {
+	s := NewStatic([]string{"node1:4001", "node2:4001"})
+	addrs, err := s.Lookup()
+	if err != nil {
+		t.Fatalf("failed to look up: %s", err)
+	}
+	if exp, got := "node1:4001,node2:4001", strings.Join(addrs, ","); exp != got {
+		t.Fatalf("wrong addresses, exp %s, got %s", exp, got)
+	}
+}
+
+func Test_DNS(t *testing.T) {
+	srv := mustNewDNSServer(t, map[string][]dnsRecord{
+		"nodes.tqlite.test.": {
+			{typ: typeA, ip: net.ParseIP("10.0.0.2")},
+			{typ: typeA, ip: net.ParseIP("10.0.0.1")},
+			{typ: typeAAAA, ip: net.ParseIP("fd00::1")},
+		},
+	})
+
+	d := NewDNS("nodes.tqlite.test.", 4001, srv)
+	addrs, err := d.Lookup()
+	if err != nil {
+		t.Fatalf("failed to look up: %s", err)
+	}
+	if exp, got := "10.0.0.1:4001,10.0.0.2:4001,[fd00::1]:4001", strings.Join(addrs, ","); exp != got {
+		t.Fatalf("wrong addresses, exp %s, got %s", exp, got)
+	}
+
+	if _, err := NewDNS("other.tqlite.test.", 4001, srv).Lookup(); err == nil {
+		t.Fatal("looked up unknown name")
+	}
+}
+
+func Test_DNSSRV(t *testing.T) {
+	srv := mustNewDNSServer(t, map[string][]dnsRecord{
+		"_tqlite._tcp.tqlite.test.": {
+			{typ: typeSRV, target: "node2.tqlite.test.", port: 4011},
+			{typ: typeSRV, target: "node1.tqlite.test.", port: 4001},
+		},
+	})
+
+	d := NewDNSSRV("_tqlite._tcp.tqlite.test.", srv)
+	addrs, err := d.Lookup()
+	if err != nil {
+		t.Fatalf("failed to look up: %s", err)
+	}
+	if exp, got := "node1.tqlite.test:4001,node2.tqlite.test:4011", strings.Join(addrs, ","); exp != got {
+		t.Fatalf("wrong addresses, exp %s, got %s", exp, got)
+	}
+
+	if _, err := NewDNSSRV("_other._tcp.tqlite.test.", srv).Lookup(); err == nil {
+		t.Fatal("looked up unknown name")
+	}
+}
+
+func Test_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "peers")
+	f := NewFile(path)
+	if _, err := f.Lookup(); err == nil {
+		t.Fatal("looked up missing file")
+	}
+
+	mustWriteFile(t, path, "# nodes\nnode1:4001\n\n  node2:4001  \n")
+	addrs, err := f.Lookup()
+	if err != nil {
+		t.Fatalf("failed to look up: %s", err)
+	}
+	if exp, got := "node1:4001,node2:4001", strings.Join(addrs, ","); exp != got {
+		t.Fatalf("wrong addresses, exp %s, got %s", exp, got)
+	}
+
+	// The file is read again on every lookup.
+	mustWriteFile(t, path, "node1:4001\nnode2:4001\nnode3:4001\n")
+	addrs, err = f.Lookup()
+	if err != nil {
+		t.Fatalf("failed to look up: %s", err)
+	}
+	if len(addrs) != 3 {
+		t.Fatalf("changed file not read, got %v", addrs)
+	}
+}
+
+// dnsRecord is a record served by the DNS server started by
+// mustNewDNSServer. A and AAAA records have ip set, SRV records have target
+// and port set.
+type dnsRecord struct {
+	typ    uint16
+	ip     net.IP
+	target string
+	port   uint16
+}
+
+// mustNewDNSServer starts a DNS server, answering queries over UDP with the
+// given records, by name. It returns the address of the server.
+func mustNewDNSServer(t *testing.T, records map[string][]dnsRecord) string {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	t.Cleanup(func() { pc.Close() })
+
+	go func() {
+		b := make([]byte, 512)
+		for {
+			n, addr, err := pc.ReadFrom(b)
+			if err != nil {
+				return
+			}
+			if resp := dnsResponse(b[:n], records); resp != nil {
+				pc.WriteTo(resp, addr)
+			}
+		}
+	}()
+	return pc.LocalAddr().String()
+}
+
+// dnsResponse returns the response to the DNS query q, or nil if q cannot be
+// parsed.
+func dnsResponse(q []byte, records map[string][]dnsRecord) []byte {
+	if len(q) < 12 || binary.BigEndian.Uint16(q[4:]) != 1 {
+		return nil
+	}
+	name, end, ok := readName(q, 12)
+	if !ok || end+4 > len(q) {
+		return nil
+	}
+	qtype := binary.BigEndian.Uint16(q[end:])
+	question := q[12 : end+4]
+
+	rrs, found := records[strings.ToLower(name)]
+	var answers [][]byte
+	for _, rr := range rrs {
+		if rr.typ != qtype {
+			continue
+		}
+		var rdata []byte
+		switch rr.typ {
+		case typeA:
+			rdata = rr.ip.To4()
+		case typeAAAA:
+			rdata = rr.ip.To16()
+		case typeSRV:
+			rdata = make([]byte, 6)
+			binary.BigEndian.PutUint16(rdata[4:], rr.port)
+			rdata = append(rdata, writeName(rr.target)...)
+		}
+		// The name is a pointer to the name in the question.
+		a := []byte{0xc0, 12, 0, 0, 0, 1, 0, 0, 0, 60, 0, 0}
+		binary.BigEndian.PutUint16(a[2:], rr.typ)
+		binary.BigEndian.PutUint16(a[10:], uint16(len(rdata)))
+		answers = append(answers, append(a, rdata...))
+	}
+
+	resp := make([]byte, 12)
+	copy(resp, q[:2])
+	flags := uint16(0x8180) // Response, recursion desired and available.
+	if !found {
+		flags |= 3 // NXDOMAIN
+	}
+	binary.BigEndian.PutUint16(resp[2:], flags)
+	binary.BigEndian.PutUint16(resp[4:], 1)
+	binary.BigEndian.PutUint16(resp[6:], uint16(len(answers)))
+	resp = append(resp, question...)
+	for _, a := range answers {
+		resp = append(resp, a...)
+	}
+	return resp
+}
+
+// readName reads the uncompressed name starting at offset i of b, returning
+// it with a trailing dot, and the offset following it.
+func readName(b []byte, i int) (string, int, bool) {
+	var labels []string
+	for {
+		if i >= len(b) {
+			return "", 0, false
+		}
+		n := int(b[i])
+		i++
+		if n == 0 {
+			break
+		}
+		if n > 63 || i+n > len(b) {
+			return "", 0, false
+		}
+		labels = append(labels, string(b[i:i+n]))
+		i += n
+	}
+	return strings.Join(labels, ".") + ".", i, true
+}
+
+// writeName returns the uncompressed wire form of name.
+func writeName(name string) []byte {
+	var b []byte
+	for _, l := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		b = append(b, byte(len(l)))
+		b = append(b, l...)
+	}
+	return append(b, 0)
+}
+
+func mustWriteFile(t *testing.T, path, data string) {
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("failed to write %s: %s", path, err)
+	}
+}
@@ -3,6 +3,13 @@
 package http
 
 import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"expvar"
@@ -15,13 +22,34 @@ import (
 	"net/http/pprof"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
 
 	"github.com/minghsu0107/tqlite/command"
 	sql "github.com/minghsu0107/tqlite/db"
 	"github.com/minghsu0107/tqlite/store"
+	"github.com/minghsu0107/tqlite/verify"
+)
+
+var (
+	// ErrLeaderNotFound is returned when a request must be forwarded to the
+	// leader, but no leader is known.
+	ErrLeaderNotFound = errors.New("leader not found")
+
+	// ErrJoinTokenMissing is returned when a join request carries no join
+	// token, but the node requires one.
+	ErrJoinTokenMissing = errors.New("join token missing, set the cluster join secret on the joining node")
+
+	// ErrJoinTokenExpired is returned when the join token of a join request
+	// was created too long ago, or too far in the future.
+	ErrJoinTokenExpired = errors.New("join token expired, check the clocks of the joining node and the cluster")
+
+	// ErrJoinTokenInvalid is returned when the join token of a join request
+	// was not created with the cluster join secret.
+	ErrJoinTokenInvalid = errors.New("join token invalid, check the join secret of the joining node")
 )
 
 // Database is the interface any queryable system must implement
@@ -29,12 +57,13 @@ type Database interface {
 	// Execute executes a slice of queries, each of which is not expected
 	// to return rows. If timings is true, then timing information will
 	// be return. If tx is true, then either all queries will be executed
-	// successfully or it will as though none executed.
-	Execute(er *command.ExecuteRequest) ([]*sql.Result, error)
+	// successfully or it will as though none executed. The Raft index of
+	// the committed request is also returned.
+	Execute(er *command.ExecuteRequest) ([]*sql.Result, uint64, error)
 
 	// ExecuteOrAbort performs the same function as Execute(), but ensures
 	// any transactions are aborted in case of any error.
-	ExecuteOrAbort(er *command.ExecuteRequest) ([]*sql.Result, error)
+	ExecuteOrAbort(er *command.ExecuteRequest) ([]*sql.Result, uint64, error)
 
 	// Query executes a slice of queries, each of which returns rows. If
 	// timings is true, then timing information will be returned. If tx
@@ -50,9 +79,55 @@ type Store interface {
 	// Join joins the node with the given ID, reachable at addr, to this node.
 	Join(id, addr string, voter bool) error
 
+	// Notify records that the node with the given ID, reachable at addr, is
+	// ready to bootstrap a cluster with this node.
+	Notify(id, addr string) error
+
 	// Remove removes the node, specified by id, from the cluster.
 	Remove(id string) error
 
+	// TransferLeadership transfers leadership to the node with the given ID,
+	// or to a node chosen by Raft if id is empty.
+	TransferLeadership(id string) error
+
+	// Promote makes the non-voting node with the given ID a voter. Unless
+	// force is set, the node must have caught up, having applied the log up
+	// to appliedIndex.
+	Promote(id string, appliedIndex uint64, force bool) error
+
+	// Demote makes the voting node with the given ID a non-voter.
+	Demote(id string) error
+
+	// Load replaces the entire database with the SQLite database file in
+	// the request. The Raft index of the committed request is returned.
+	Load(lr *command.LoadRequest) (uint64, error)
+
+	// LoadChunk applies a chunk of SQL text which is part of a larger load.
+	// The progress of the load and the Raft index of the chunk are returned.
+	LoadChunk(lcr *command.LoadChunkRequest) (store.LoadProgress, uint64, error)
+
+	// LoadProgress returns the progress of the load with the given ID, as
+	// applied by this node.
+	LoadProgress(id string) (store.LoadProgress, bool)
+
+	// BeginTx starts an interactive transaction, and returns its ID.
+	BeginTx() (string, error)
+
+	// ExecuteTx executes statements in the interactive transaction with the
+	// given ID.
+	ExecuteTx(id string, er *command.ExecuteRequest) ([]*sql.Result, error)
+
+	// QueryTx executes queries in the interactive transaction with the given
+	// ID.
+	QueryTx(id string, qr *command.QueryRequest) ([]*sql.Rows, error)
+
+	// CommitTx commits the interactive transaction with the given ID. The
+	// Raft index of the committed transaction is returned.
+	CommitTx(id string) (uint64, error)
+
+	// RollbackTx discards the interactive transaction with the given ID.
+	RollbackTx(id string) error
+
 	// LeaderAddr returns the Raft address of the leader of the cluster.
 	LeaderAddr() (string, error)
 
@@ -62,15 +137,68 @@ type Store interface {
 	// Nodes returns the slice of store.Servers in the cluster
 	Nodes() ([]*store.Server, error)
 
+	// Readiness returns the state of the node which determines whether it
+	// can serve requests.
+	Readiness() (*store.Readiness, error)
+
+	// ServerHealth returns the health of every node in the cluster, by ID,
+	// as tracked by autopilot on the leader.
+	ServerHealth() map[string]store.ServerHealth
+
 	// Backup wites backup of the node state to dst
 	Backup(leader bool, f store.BackupFormat, dst io.Writer) error
 }
 
+// CredentialStore is the interface credential stores must support.
+type CredentialStore interface {
+	// Check returns whether username and password are a valid combination.
+	Check(username, password string) bool
+
+	// HasAnyPerm returns whether username has at least one of the given perms.
+	HasAnyPerm(username string, perm ...string) bool
+}
+
 // Cluster is the interface node API services must provide
 type Cluster interface {
 	// GetNodeAPIAddr returns the HTTP API URL for the node at the given Raft address.
 	GetNodeAPIAddr(nodeAddr string) (string, error)
 
+	// Execute performs an Execute Request on the node at the given Raft address.
+	Execute(er *command.ExecuteRequest, nodeAddr string, creds *command.Credentials, timeout time.Duration) ([]*sql.Result, uint64, error)
+
+	// LoadChunk requests that the node at the given Raft address applies a
+	// chunk of SQL text which is part of a larger load.
+	LoadChunk(lcr *command.LoadChunkRequest, nodeAddr string, creds *command.Credentials, timeout time.Duration) (store.LoadProgress, uint64, error)
+
+	// LoadDatabase requests that the node at the given Raft address replaces
+	// the entire database with the SQLite database file in the request.
+	LoadDatabase(lr *command.LoadRequest, nodeAddr string, creds *command.Credentials, timeout time.Duration) (uint64, error)
+
+	// Join requests that the node at the given Raft address joins the node
+	// with the given ID and address to the cluster, presenting the join token
+	// of the node, created at t.
+	Join(id, addr string, voter bool, token string, t time.Time, nodeAddr string, creds *command.Credentials, timeout time.Duration) error
+
+	// Remove requests that the node at the given Raft address removes the
+	// node with the given ID from the cluster.
+	Remove(id string, nodeAddr string, creds *command.Credentials, timeout time.Duration) error
+
+	// TransferLeadership requests that the node at the given Raft address
+	// transfers leadership to the node with the given ID.
+	TransferLeadership(id string, nodeAddr string, creds *command.Credentials, timeout time.Duration) error
+
+	// Promote requests that the node at the given Raft address promotes the
+	// node with the given ID to voter.
+	Promote(id string, appliedIndex uint64, force bool, nodeAddr string, creds *command.Credentials, timeout time.Duration) error
+
+	// Demote requests that the node at the given Raft address demotes the
+	// node with the given ID to non-voter.
+	Demote(id string, nodeAddr string, creds *command.Credentials, timeout time.Duration) error
+
+	// GetNodeStatus returns a summary of the state of the node at the given
+	// Raft address.
+	GetNodeStatus(nodeAddr string, timeout time.Duration) (*store.NodeStatus, error)
+
 	// Stats returns stats on the Cluster.
 	Stats() (map[string]interface{}, error)
 }
@@ -80,28 +208,114 @@ type Statuser interface {
 	Stats() (interface{}, error)
 }
 
+// Verifier is the interface the checker of replica consistency must
+// implement.
+type Verifier interface {
+	// Verify compares the checksums every node takes of its database, at
+	// the same log index, with the leader's, waiting up to timeout for the
+	// nodes to report them. This node must be the leader.
+	Verify(timeout time.Duration) (*verify.Report, error)
+}
+
 // Response represents a response from the HTTP service.
 type Response struct {
-	Results interface{} `json:"results,omitempty"`
-	Error   string      `json:"error,omitempty"`
-	Time    float64     `json:"time,omitempty"`
+	Results   interface{}         `json:"results,omitempty"`
+	Load      *store.LoadProgress `json:"load,omitempty"`
+	Tx        string              `json:"tx,omitempty"`
+	Error     string              `json:"error,omitempty"`
+	Time      float64             `json:"time,omitempty"`
+	RaftIndex uint64              `json:"raft_index,omitempty"`
 
 	start time.Time
 	end   time.Time
 }
 
+const (
+	// PermAll means all actions permitted.
+	PermAll = "all"
+	// PermJoin means user is permitted to join cluster.
+	PermJoin = "join"
+	// PermRemove means user is permitted to remove a node.
+	PermRemove = "remove"
+	// PermLeadership means user is permitted to transfer leadership.
+	PermLeadership = "leadership"
+	// PermExecute means user can access execute endpoint.
+	PermExecute = "execute"
+	// PermQuery means user can access query endpoint
+	PermQuery = "query"
+	// PermStatus means user can retrieve node status, metrics and debug
+	// information.
+	PermStatus = "status"
+	// PermReady means user can retrieve ready status.
+	PermReady = "ready"
+	// PermBackup means user can backup node.
+	PermBackup = "backup"
+	// PermLoad means user can load a SQLite dump into a node.
+	PermLoad = "load"
+)
+
 // stats captures stats for the HTTP service.
 var stats *expvar.Map
 
 const (
-	numExecutions = "executions"
-	numQueries    = "queries"
-	numBackups    = "backups"
-	numLoad       = "loads"
-	numJoins      = "joins"
+	numExecutions       = "executions"
+	numQueries          = "queries"
+	numBackups          = "backups"
+	numLoad             = "loads"
+	numJoins            = "joins"
+	numNotifications    = "notifications"
+	numRemoteExecutions = "remote_executions"
+	numRemoteLoads      = "remote_loads"
+	numRemoteJoins      = "remote_joins"
+	numRemoteRemovals   = "remote_removals"
+	numRemoteTransfers  = "remote_leadership_transfers"
+	numRemotePromotions = "remote_promotions"
+	numRemoteDemotions  = "remote_demotions"
+	numAuthFailures     = "auth_failures"
+	numJoinRejections   = "join_rejections"
+	numReadyzFailures   = "readyz_failures"
+	numTxRequests       = "transaction_requests"
+	numVerifyRequests   = "verify_requests"
+
+	// defaultTimeout is the default time allowed for a request forwarded
+	// to the leader.
+	defaultTimeout = 30 * time.Second
+
+	// clusterStatusTimeout is the default time allowed for each node to
+	// report its status.
+	clusterStatusTimeout = 5 * time.Second
+
+	// verifyTimeout is the default time allowed for each node to report
+	// its checksum.
+	verifyTimeout = 30 * time.Second
+
+	// readyzLeaderTimeout is the default time allowed for the leader to
+	// report its commit index, when a follower checks its lag.
+	readyzLeaderTimeout = time.Second
+
+	// joinTokenMaxAge is the maximum difference between the time a join
+	// token was created, and the time it is verified.
+	joinTokenMaxAge = 5 * time.Minute
+
+	// defaultLoadChunkSize is the default size, in bytes, of the SQL text
+	// applied by each Raft log entry of a load.
+	defaultLoadChunkSize = 1024 * 1024
+
+	// sqliteHeaderSize is the size of the header of SQLite database files.
+	sqliteHeaderSize = 16
 
 	// VersionHTTPHeader is the HTTP header key for the version.
 	VersionHTTPHeader = "X-TQLITE-VERSION"
+
+	// RaftIndexHTTPHeader is the HTTP header key for the Raft index of a write.
+	RaftIndexHTTPHeader = "X-TQLITE-RAFT-INDEX"
+
+	// IdempotencyKeyHTTPHeader is the HTTP header key for the idempotency key
+	// of an execute request.
+	IdempotencyKeyHTTPHeader = "Idempotency-Key"
+
+	// maxIdempotencyKeyLen is the maximum length of an idempotency key.
+	maxIdempotencyKeyLen = 255
 )
 
 func init() {
@@ -111,6 +325,19 @@ func init() {
 	stats.Add(numBackups, 0)
 	stats.Add(numLoad, 0)
 	stats.Add(numJoins, 0)
+	stats.Add(numNotifications, 0)
+	stats.Add(numRemoteExecutions, 0)
+	stats.Add(numRemoteLoads, 0)
+	stats.Add(numRemoteJoins, 0)
+	stats.Add(numRemoteRemovals, 0)
+	stats.Add(numRemoteTransfers, 0)
+	stats.Add(numRemotePromotions, 0)
+	stats.Add(numRemoteDemotions, 0)
+	stats.Add(numAuthFailures, 0)
+	stats.Add(numJoinRejections, 0)
+	stats.Add(numReadyzFailures, 0)
+	stats.Add(numTxRequests, 0)
+	stats.Add(numVerifyRequests, 0)
 }
 
 // SetTime sets the Time attribute of the response. This way it will be present
@@ -135,29 +362,41 @@ type Service struct {
 
 	cluster Cluster // The Cluster service.
 
+	credentialStore CredentialStore // Credentials of users, nil if auth is disabled.
+
 	start      time.Time // Start up time.
 	lastBackup time.Time // Time of last successful backup.
 
 	statusMu sync.RWMutex
 	statuses map[string]Statuser
 
+	CertFile   string // Path to SSL certificate.
+	KeyFile    string // Path to SSL private key.
+	CACertFile string // Path to CA certificate, for verifying client certificates.
+
+	JoinSecret string // Secret shared by the cluster, required to join nodes if set.
+
 	Expvar bool
 	Pprof  bool
 
 	BuildInfo map[string]interface{}
 
+	Verifier Verifier // Checks that every node holds the same data, nil if unavailable.
+
 	logger *log.Logger
 }
 
-// New returns an uninitialized HTTP service.
-func New(addr string, store Store, cluster Cluster) *Service {
+// New returns an uninitialized HTTP service. If credentials is nil, then
+// the service performs no authentication and authorization checks.
+func New(addr string, store Store, cluster Cluster, credentials CredentialStore) *Service {
 	return &Service{
-		addr:     addr,
-		store:    store,
-		cluster:  cluster,
-		start:    time.Now(),
-		statuses: make(map[string]Statuser),
-		logger:   log.New(os.Stderr, "[http] ", log.LstdFlags),
+		addr:            addr,
+		store:           store,
+		cluster:         cluster,
+		credentialStore: credentials,
+		start:           time.Now(),
+		statuses:        make(map[string]Statuser),
+		logger:          log.New(os.Stderr, "[http] ", log.LstdFlags),
 	}
 }
 
@@ -169,9 +408,25 @@ func (s *Service) Start() error {
 
 	var ln net.Listener
 	var err error
-	ln, err = net.Listen("tcp", s.addr)
-	if err != nil {
-		return err
+	if !s.HTTPS() {
+		ln, err = net.Listen("tcp", s.addr)
+		if err != nil {
+			return err
+		}
+	} else {
+		config, err := createTLSConfig(s.CertFile, s.KeyFile, s.CACertFile)
+		if err != nil {
+			return err
+		}
+
+		ln, err = tls.Listen("tcp", s.addr, config)
+		if err != nil {
+			return err
+		}
+		s.logger.Printf("secure HTTPS server enabled with cert %s, key %s", s.CertFile, s.KeyFile)
+		if s.CACertFile != "" {
+			s.logger.Printf("client certificates verified with CA cert %s", s.CACertFile)
+		}
 	}
 
 	s.ln = ln
@@ -197,6 +452,11 @@ func (s *Service) Close() {
 func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.addBuildVersion(w)
 
+	start := time.Now()
+	defer func() {
+		requestLatencies.observe(r.URL.Path, time.Since(start))
+	}()
+
 	switch {
 	case strings.HasPrefix(r.URL.Path, "/db/execute"):
 		stats.Add(numExecutions, 1)
@@ -210,15 +470,37 @@ func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	case strings.HasPrefix(r.URL.Path, "/db/load"):
 		stats.Add(numLoad, 1)
 		s.handleLoad(w, r)
+	case strings.HasPrefix(r.URL.Path, "/db/tx"):
+		stats.Add(numTxRequests, 1)
+		s.handleTx(w, r)
 	case strings.HasPrefix(r.URL.Path, "/join"):
 		stats.Add(numJoins, 1)
 		s.handleJoin(w, r)
+	case strings.HasPrefix(r.URL.Path, "/notify"):
+		stats.Add(numNotifications, 1)
+		s.handleNotify(w, r)
 	case strings.HasPrefix(r.URL.Path, "/remove"):
 		s.handleRemove(w, r)
+	case strings.HasPrefix(r.URL.Path, "/promote"):
+		s.handleSuffrage(w, r, true)
+	case strings.HasPrefix(r.URL.Path, "/demote"):
+		s.handleSuffrage(w, r, false)
+	case strings.HasPrefix(r.URL.Path, "/leader/transfer"):
+		s.handleTransferLeadership(w, r)
 	case strings.HasPrefix(r.URL.Path, "/status"):
 		s.handleStatus(w, r)
 	case strings.HasPrefix(r.URL.Path, "/nodes"):
 		s.handleNodes(w, r)
+	case strings.HasPrefix(r.URL.Path, "/cluster"):
+		s.handleCluster(w, r)
+	case r.URL.Path == "/verify":
+		s.handleVerify(w, r)
+	case r.URL.Path == "/readyz":
+		s.handleReadyz(w, r)
+	case r.URL.Path == "/livez":
+		s.handleLivez(w, r)
+	case r.URL.Path == "/metrics":
+		s.handleMetrics(w, r)
 	case r.URL.Path == "/debug/vars" && s.Expvar:
 		s.handleExpvar(w, r)
 	case strings.HasPrefix(r.URL.Path, "/debug/pprof") && s.Pprof:
@@ -243,11 +525,28 @@ func (s *Service) RegisterStatus(key string, stat Statuser) error {
 
 // handleJoin handles cluster-join requests from other nodes.
 func (s *Service) handleJoin(w http.ResponseWriter, r *http.Request) {
+	if !s.CheckRequestPerm(r, PermJoin) {
+		s.unauthorized(w)
+		return
+	}
+
 	if r.Method != "POST" {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
 
+	redirect, err := isRedirect(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	t, err := timeout(r, defaultTimeout)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	b, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -259,25 +558,44 @@ func (s *Service) handleJoin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	remoteID, ok := md["id"]
+	remoteID, ok := md["id"].(string)
 	if !ok {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	remoteAddr, ok := md["addr"]
+	remoteAddr, ok := md["addr"].(string)
 	if !ok {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	voter, ok := md["voter"]
-	if !ok {
-		voter = true
+	voter := true
+	if v, ok := md["voter"]; ok {
+		if voter, ok = v.(bool); !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
 	}
 
-	if err := s.store.Join(remoteID.(string), remoteAddr.(string), voter.(bool)); err != nil {
-		if err == store.ErrNotLeader {
+	// The token is also checked by the leader, should the join be forwarded.
+	token, _ := md["token"].(string)
+	timestamp, _ := md["timestamp"].(float64)
+	tokenTime := time.Unix(int64(timestamp), 0)
+	if s.JoinSecret != "" {
+		if err := VerifyJoinToken(s.JoinSecret, token, remoteID, remoteAddr,
+			voter, tokenTime); err != nil {
+			stats.Add(numJoinRejections, 1)
+			s.logger.Printf("rejected join request of node %s at %s from %s: %s",
+				remoteID, remoteAddr, r.RemoteAddr, err.Error())
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+
+	err = s.store.Join(remoteID, remoteAddr, voter)
+	if err == store.ErrNotLeader {
+		if redirect {
 			leaderAPIAddr := s.LeaderAPIAddr()
 			if leaderAPIAddr == "" {
 				http.Error(w, err.Error(), http.StatusServiceUnavailable)
@@ -289,6 +607,71 @@ func (s *Service) handleJoin(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		leaderAddr, lerr := s.store.LeaderAddr()
+		if lerr != nil || leaderAddr == "" {
+			http.Error(w, ErrLeaderNotFound.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		stats.Add(numRemoteJoins, 1)
+		err = s.cluster.Join(remoteID, remoteAddr, voter, token, tokenTime,
+			leaderAddr, credentials(r), t)
+	}
+	if err != nil {
+		if isError(err, ErrJoinTokenMissing) || isError(err, ErrJoinTokenExpired) || isError(err, ErrJoinTokenInvalid) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleNotify handles a notification from a node that it is ready to
+// bootstrap a cluster with this node.
+func (s *Service) handleNotify(w http.ResponseWriter, r *http.Request) {
+	if !s.CheckRequestPerm(r, PermJoin) {
+		s.unauthorized(w)
+		return
+	}
+
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	md := struct {
+		ID        string `json:"id"`
+		Addr      string `json:"addr"`
+		Timestamp int64  `json:"timestamp"`
+		Token     string `json:"token"`
+	}{}
+	if err := json.Unmarshal(b, &md); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if md.ID == "" || md.Addr == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Nodes bootstrap as voters, so the token signs the node as one.
+	if s.JoinSecret != "" {
+		if err := VerifyJoinToken(s.JoinSecret, md.Token, md.ID, md.Addr, true,
+			time.Unix(md.Timestamp, 0)); err != nil {
+			stats.Add(numJoinRejections, 1)
+			s.logger.Printf("rejected bootstrap notification of node %s at %s from %s: %s",
+				md.ID, md.Addr, r.RemoteAddr, err.Error())
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+
+	if err := s.store.Notify(md.ID, md.Addr); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -296,11 +679,28 @@ func (s *Service) handleJoin(w http.ResponseWriter, r *http.Request) {
 
 // handleRemove handles cluster-remove requests.
 func (s *Service) handleRemove(w http.ResponseWriter, r *http.Request) {
+	if !s.CheckRequestPerm(r, PermRemove) {
+		s.unauthorized(w)
+		return
+	}
+
 	if r.Method != "DELETE" {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
 
+	redirect, err := isRedirect(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	t, err := timeout(r, defaultTimeout)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	b, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -323,8 +723,9 @@ func (s *Service) handleRemove(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.store.Remove(remoteID); err != nil {
-		if err == store.ErrNotLeader {
+	err = s.store.Remove(remoteID)
+	if err == store.ErrNotLeader {
+		if redirect {
 			leaderAPIAddr := s.LeaderAPIAddr()
 			if leaderAPIAddr == "" {
 				http.Error(w, err.Error(), http.StatusServiceUnavailable)
@@ -336,33 +737,78 @@ func (s *Service) handleRemove(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		leaderAddr, lerr := s.store.LeaderAddr()
+		if lerr != nil || leaderAddr == "" {
+			http.Error(w, ErrLeaderNotFound.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		stats.Add(numRemoteRemovals, 1)
+		err = s.cluster.Remove(remoteID, leaderAddr, credentials(r), t)
+	}
+	if err != nil {
+		if isError(err, store.ErrNodeNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 }
 
-// handleBackup returns the consistent database snapshot.
-func (s *Service) handleBackup(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
+// handleSuffrage handles requests to promote a non-voting node to voter, if
+// voter is set, or to demote a voting node to non-voter. Promotions made this
+// way do not wait for the node to catch up.
+func (s *Service) handleSuffrage(w http.ResponseWriter, r *http.Request, voter bool) {
+	perm := PermJoin
+	if !voter {
+		perm = PermRemove
+	}
+	if !s.CheckRequestPerm(r, perm) {
+		s.unauthorized(w)
+		return
+	}
+
+	if r.Method != "POST" {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
 
-	noLeader, err := noLeader(r)
+	redirect, err := isRedirect(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	bf, err := backupFormat(w, r)
+	t, err := timeout(r, defaultTimeout)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	err = s.store.Backup(!noLeader, bf, w)
+	b, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		if err == store.ErrNotLeader {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	m := map[string]string{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	remoteID, ok := m["id"]
+	if !ok || remoteID == "" {
+		http.Error(w, "node ID not specified", http.StatusBadRequest)
+		return
+	}
+
+	if voter {
+		err = s.store.Promote(remoteID, 0, true)
+	} else {
+		err = s.store.Demote(remoteID)
+	}
+	if err == store.ErrNotLeader {
+		if redirect {
 			leaderAPIAddr := s.LeaderAPIAddr()
 			if leaderAPIAddr == "" {
 				http.Error(w, err.Error(), http.StatusServiceUnavailable)
@@ -373,43 +819,77 @@ func (s *Service) handleBackup(w http.ResponseWriter, r *http.Request) {
 			http.Redirect(w, r, redirect, http.StatusMovedPermanently)
 			return
 		}
+
+		leaderAddr, lerr := s.store.LeaderAddr()
+		if lerr != nil || leaderAddr == "" {
+			http.Error(w, ErrLeaderNotFound.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		if voter {
+			stats.Add(numRemotePromotions, 1)
+			err = s.cluster.Promote(remoteID, 0, true, leaderAddr, credentials(r), t)
+		} else {
+			stats.Add(numRemoteDemotions, 1)
+			err = s.cluster.Demote(remoteID, leaderAddr, credentials(r), t)
+		}
+	}
+	if err != nil {
+		if isError(err, store.ErrNodeNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if isError(err, store.ErrDemoteLeader) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-
-	s.lastBackup = time.Now()
 }
 
-// handleLoad loads the state contained in a .dump output. This API is different
-// from others in that it expects a raw file, not wrapped in any kind of JSON.
-func (s *Service) handleLoad(w http.ResponseWriter, r *http.Request) {
+// handleTransferLeadership handles requests to move leadership to another node.
+// The body may optionally name the target node with an "id" key, otherwise
+// Raft picks the target.
+func (s *Service) handleTransferLeadership(w http.ResponseWriter, r *http.Request) {
+	if !s.CheckRequestPerm(r, PermLeadership) {
+		s.unauthorized(w)
+		return
+	}
+
 	if r.Method != "POST" {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
 
-	resp := NewResponse()
-
-	timings, err := timings(r)
+	redirect, err := isRedirect(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	b, err := ioutil.ReadAll(r.Body)
+	t, err := timeout(r, defaultTimeout)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	r.Body.Close()
-
-	// No JSON structure expected for this API.
-	queries := []string{string(b)}
-	er := executeRequestFromStrings(queries, timings, false)
 
-	results, err := s.store.ExecuteOrAbort(er)
+	b, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		if err == store.ErrNotLeader {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	m := map[string]string{}
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, &m); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	targetID := m["id"]
+
+	err = s.store.TransferLeadership(targetID)
+	if err == store.ErrNotLeader {
+		if redirect {
 			leaderAPIAddr := s.LeaderAPIAddr()
 			if leaderAPIAddr == "" {
 				http.Error(w, err.Error(), http.StatusServiceUnavailable)
@@ -420,55 +900,357 @@ func (s *Service) handleLoad(w http.ResponseWriter, r *http.Request) {
 			http.Redirect(w, r, redirect, http.StatusMovedPermanently)
 			return
 		}
-		resp.Error = err.Error()
-	} else {
-		resp.Results = results
+
+		leaderAddr, lerr := s.store.LeaderAddr()
+		if lerr != nil || leaderAddr == "" {
+			http.Error(w, ErrLeaderNotFound.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		stats.Add(numRemoteTransfers, 1)
+		err = s.cluster.TransferLeadership(targetID, leaderAddr, credentials(r), t)
+	}
+	if err != nil {
+		if isError(err, store.ErrNodeNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
-	resp.end = time.Now()
-	s.writeResponse(w, r, resp)
 }
 
-// handleStatus returns status on the system.
-func (s *Service) handleStatus(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+// handleBackup returns the consistent database snapshot.
+func (s *Service) handleBackup(w http.ResponseWriter, r *http.Request) {
+	if !s.CheckRequestPerm(r, PermBackup) {
+		s.unauthorized(w)
+		return
+	}
 
 	if r.Method != "GET" {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
 
-	storeStatus, err := s.store.Stats()
+	noLeader, err := noLeader(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	rt := map[string]interface{}{
-		"GOARCH":        runtime.GOARCH,
-		"GOOS":          runtime.GOOS,
-		"GOMAXPROCS":    runtime.GOMAXPROCS(0),
-		"num_cpu":       runtime.NumCPU(),
-		"num_goroutine": runtime.NumGoroutine(),
-		"version":       runtime.Version(),
-	}
-
-	httpStatus := map[string]interface{}{
-		"addr": s.Addr().String(),
-	}
-
-	nodeStatus := map[string]interface{}{
-		"start_time": s.start,
-		"uptime":     time.Since(s.start).String(),
+	bf, err := backupFormat(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	clusterStatus, err := s.cluster.Stats()
+	err = s.store.Backup(!noLeader, bf, w)
 	if err != nil {
+		if err == store.ErrNotLeader {
+			leaderAPIAddr := s.LeaderAPIAddr()
+			if leaderAPIAddr == "" {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+
+			redirect := s.FormRedirect(r, leaderAPIAddr)
+			http.Redirect(w, r, redirect, http.StatusMovedPermanently)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Build the status response.
-	status := map[string]interface{}{
+	s.lastBackup = time.Now()
+}
+
+// handleLoad loads the state contained in a .dump output, or in a SQLite
+// database file. This API is different from others in that it expects a raw
+// file, not wrapped in any kind of JSON. A GET request returns the progress of
+// the load with the given ID.
+func (s *Service) handleLoad(w http.ResponseWriter, r *http.Request) {
+	if !s.CheckRequestPerm(r, PermLoad) {
+		s.unauthorized(w)
+		return
+	}
+
+	if r.Method == "GET" {
+		s.handleLoadProgress(w, r)
+		return
+	}
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	redirect, err := isRedirect(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	t, err := timeout(r, defaultTimeout)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	defer r.Body.Close()
+	br := bufio.NewReader(r.Body)
+	hdr, _ := br.Peek(sqliteHeaderSize)
+	if sql.IsValidSQLiteData(hdr) {
+		b, err := ioutil.ReadAll(br)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.handleLoadDatabase(w, r, b, redirect, t)
+		return
+	}
+	s.handleLoadSQL(w, r, br, redirect, t)
+}
+
+// handleLoadSQL loads the SQL text read from body. The text is split into
+// chunks of complete statements, and each chunk is applied, in a transaction,
+// by its own Raft log entry. The load is identified by the load_id query
+// parameter, which is generated if not set. If the body is the remainder of
+// the SQL text of a failed load, the offset query parameter must be set to
+// the offset that load reached.
+func (s *Service) handleLoadSQL(w http.ResponseWriter, r *http.Request, body io.Reader, redirect bool, t time.Duration) {
+	resp := NewResponse()
+
+	loadID, err := loadIDParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	offset, err := loadOffset(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	chunkSize, err := loadChunkSize(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	progress := store.LoadProgress{ID: loadID, Offset: offset}
+	if p, ok := s.store.LoadProgress(loadID); ok {
+		progress = p
+	}
+	resp.Load = &progress
+
+	var stmts []*command.Statement
+	var size int
+	var applied bool
+	chunkStart := offset
+
+	// apply applies the statements read so far, ending at the given offset,
+	// forwarding them to the leader if necessary. It returns false if a
+	// redirect was sent instead.
+	apply := func(end uint64, tx, last bool) (bool, error) {
+		lcr := &command.LoadChunkRequest{
+			Request: &command.Request{
+				Transaction: tx,
+				Statements:  stmts,
+			},
+			LoadId:     loadID,
+			Offset:     chunkStart,
+			NextOffset: end,
+			Last:       last,
+		}
+		p, idx, err := s.store.LoadChunk(lcr)
+		if err == store.ErrNotLeader {
+			if redirect && !applied {
+				leaderAPIAddr := s.LeaderAPIAddr()
+				if leaderAPIAddr == "" {
+					http.Error(w, err.Error(), http.StatusServiceUnavailable)
+					return false, nil
+				}
+
+				redirect := s.FormRedirect(r, leaderAPIAddr)
+				http.Redirect(w, r, redirect, http.StatusMovedPermanently)
+				return false, nil
+			}
+
+			leaderAddr, lerr := s.store.LeaderAddr()
+			if lerr != nil || leaderAddr == "" {
+				return true, ErrLeaderNotFound
+			}
+			stats.Add(numRemoteLoads, 1)
+			p, idx, err = s.cluster.LoadChunk(lcr, leaderAddr, credentials(r), t)
+		}
+		if p.ID != "" {
+			progress = p
+		}
+		if err != nil {
+			return true, err
+		}
+		applied = true
+		s.setRaftIndex(w, resp, idx)
+		stmts, size, chunkStart = nil, 0, end
+		return true, nil
+	}
+
+	scanner := sql.NewStatementScanner(body)
+	var ok bool
+	var loadErr error
+loop:
+	for {
+		start := offset + uint64(scanner.Offset())
+		stmt, err := scanner.Scan()
+		if err == io.EOF {
+			ok, loadErr = apply(offset+uint64(scanner.Offset()), true, true)
+			break
+		}
+		if err != nil {
+			ok, loadErr = true, err
+			break
+		}
+		end := offset + uint64(scanner.Offset())
+
+		switch strings.ToUpper(firstWord(stmt)) {
+		case "BEGIN", "COMMIT", "END":
+			// Each chunk is applied in its own transaction.
+			continue
+		case "PRAGMA":
+			// Pragmas such as foreign_keys have no effect within a
+			// transaction, so they are applied on their own.
+			if len(stmts) > 0 {
+				ok, loadErr = apply(start, true, false)
+				if !ok || loadErr != nil {
+					break loop
+				}
+			}
+			stmts = []*command.Statement{{Sql: stmt}}
+			ok, loadErr = apply(end, false, false)
+		default:
+			stmts = append(stmts, &command.Statement{Sql: stmt})
+			size += len(stmt)
+			if size < chunkSize {
+				continue
+			}
+			ok, loadErr = apply(end, true, false)
+		}
+		if !ok || loadErr != nil {
+			break
+		}
+	}
+	if !ok {
+		return
+	}
+
+	if loadErr != nil {
+		resp.Error = loadErr.Error()
+		s.logger.Printf("load %s failed at offset %d: %s", loadID, progress.Offset, resp.Error)
+	}
+	resp.end = time.Now()
+	s.writeResponse(w, r, resp)
+}
+
+// handleLoadProgress returns the progress of a load, as known by this node.
+func (s *Service) handleLoadProgress(w http.ResponseWriter, r *http.Request) {
+	resp := NewResponse()
+
+	id := r.URL.Query().Get("load_id")
+	if id == "" {
+		http.Error(w, "load_id not set", http.StatusBadRequest)
+		return
+	}
+	p, ok := s.store.LoadProgress(id)
+	if !ok {
+		http.Error(w, store.ErrLoadNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	resp.Load = &p
+	resp.end = time.Now()
+	s.writeResponse(w, r, resp)
+}
+
+// handleLoadDatabase replaces the entire database with the SQLite database
+// file in b.
+func (s *Service) handleLoadDatabase(w http.ResponseWriter, r *http.Request, b []byte, redirect bool, t time.Duration) {
+	resp := NewResponse()
+
+	lr := &command.LoadRequest{Data: b}
+	idx, err := s.store.Load(lr)
+	if err == store.ErrNotLeader {
+		if redirect {
+			leaderAPIAddr := s.LeaderAPIAddr()
+			if leaderAPIAddr == "" {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+
+			redirect := s.FormRedirect(r, leaderAPIAddr)
+			http.Redirect(w, r, redirect, http.StatusMovedPermanently)
+			return
+		}
+
+		leaderAddr, lerr := s.store.LeaderAddr()
+		if lerr != nil || leaderAddr == "" {
+			http.Error(w, ErrLeaderNotFound.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		stats.Add(numRemoteLoads, 1)
+		idx, err = s.cluster.LoadDatabase(lr, leaderAddr, credentials(r), t)
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	} else {
+		s.setRaftIndex(w, resp, idx)
+	}
+	resp.end = time.Now()
+	s.writeResponse(w, r, resp)
+}
+
+// handleStatus returns status on the system.
+func (s *Service) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if !s.CheckRequestPerm(r, PermStatus) {
+		s.unauthorized(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	storeStatus, err := s.store.Stats()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rt := map[string]interface{}{
+		"GOARCH":        runtime.GOARCH,
+		"GOOS":          runtime.GOOS,
+		"GOMAXPROCS":    runtime.GOMAXPROCS(0),
+		"num_cpu":       runtime.NumCPU(),
+		"num_goroutine": runtime.NumGoroutine(),
+		"version":       runtime.Version(),
+	}
+
+	httpStatus := map[string]interface{}{
+		"addr": s.Addr().String(),
+		"auth": prettyEnabled(s.credentialStore != nil),
+	}
+
+	nodeStatus := map[string]interface{}{
+		"start_time": s.start,
+		"uptime":     time.Since(s.start).String(),
+	}
+
+	clusterStatus, err := s.cluster.Stats()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Build the status response.
+	status := map[string]interface{}{
 		"runtime": rt,
 		"cluster": clusterStatus,
 		"store":   storeStatus,
@@ -503,103 +1285,447 @@ func (s *Service) handleStatus(w http.ResponseWriter, r *http.Request) {
 	} else {
 		b, err = json.Marshal(status)
 	}
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	} else {
-		_, err = w.Write([]byte(b))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	} else {
+		_, err = w.Write([]byte(b))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// handleNodes returns status on the other voting nodes in the system.
+// This attempts to contact all the nodes in the cluster, so may take
+// some time to return.
+func (s *Service) handleNodes(w http.ResponseWriter, r *http.Request) {
+	if !s.CheckRequestPerm(r, PermStatus) {
+		s.unauthorized(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	t, err := timeout(r, time.Duration(1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	includeNonVoters, err := nonVoters(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Get nodes in the cluster, and possibly filter out non-voters.
+	nodes, err := s.store.Nodes()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	filteredNodes := make([]*store.Server, 0)
+	for _, n := range nodes {
+		if n.Suffrage != "Voter" && !includeNonVoters {
+			continue
+		}
+		filteredNodes = append(filteredNodes, n)
+	}
+
+	lAddr, err := s.store.LeaderAddr()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	apiAddrs, err := s.checkNodesAPIAddr(filteredNodes, t)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := make(map[string]struct {
+		APIAddr   string              `json:"api_addr,omitempty"`
+		Addr      string              `json:"addr,omitempty"`
+		Reachable bool                `json:"reachable"`
+		Leader    bool                `json:"leader"`
+		Autopilot *store.ServerHealth `json:"autopilot,omitempty"`
+	})
+
+	health := s.store.ServerHealth()
+	for _, n := range filteredNodes {
+		nn := resp[n.ID]
+		nn.Addr = n.Addr
+		nn.Leader = nn.Addr == lAddr
+		nn.APIAddr = apiAddrs[n.ID]
+		nn.Reachable = apiAddrs[n.ID] != ""
+		if h, ok := health[n.ID]; ok {
+			nn.Autopilot = &h
+		}
+		resp[n.ID] = nn
+	}
+
+	pretty, _ := isPretty(r)
+	var b []byte
+	if pretty {
+		b, err = json.MarshalIndent(resp, "", "    ")
+	} else {
+		b, err = json.Marshal(resp)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	} else {
+		_, err = w.Write([]byte(b))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// clusterNodeStatus is the state of a node, as reported by the node itself.
+type clusterNodeStatus struct {
+	ID           string     `json:"id"`
+	Addr         string     `json:"addr"`
+	APIAddr      string     `json:"api_addr,omitempty"`
+	Suffrage     string     `json:"suffrage"`
+	Reachable    bool       `json:"reachable"`
+	Leader       bool       `json:"leader"`
+	State        string     `json:"state,omitempty"`
+	Term         uint64     `json:"term,omitempty"`
+	CommitIndex  uint64     `json:"commit_index,omitempty"`
+	AppliedIndex uint64     `json:"applied_index,omitempty"`
+	LastIndex    uint64     `json:"last_index,omitempty"`
+	Lag          *uint64    `json:"lag,omitempty"`          // Entries committed by the leader, but not yet applied.
+	LastContact  *time.Time `json:"last_contact,omitempty"` // Time the node last heard from the leader.
+	DBSize       int64      `json:"db_size,omitempty"`
+	Snapshots    int        `json:"snapshots"`
+	Version      string     `json:"version,omitempty"`
+	Error        string     `json:"error,omitempty"`
+}
+
+// handleCluster returns the state of every node in the cluster, as reported
+// by each node.
+func (s *Service) handleCluster(w http.ResponseWriter, r *http.Request) {
+	if !s.CheckRequestPerm(r, PermStatus) {
+		s.unauthorized(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	t, err := timeout(r, clusterStatusTimeout)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	nodes, err := s.store.Nodes()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	lAddr, err := s.store.LeaderAddr()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	statuses := make([]*clusterNodeStatus, len(nodes))
+	var wg sync.WaitGroup
+	for i, n := range nodes {
+		statuses[i] = &clusterNodeStatus{
+			ID:       n.ID,
+			Addr:     n.Addr,
+			Suffrage: n.Suffrage,
+			Leader:   n.Addr == lAddr,
+		}
+		wg.Add(1)
+		go func(ns *clusterNodeStatus) {
+			defer wg.Done()
+			st, err := s.cluster.GetNodeStatus(ns.Addr, t)
+			if err != nil {
+				ns.Error = err.Error()
+				return
+			}
+			ns.Reachable = true
+			ns.APIAddr = st.APIAddr
+			ns.State = st.State
+			ns.Term = st.Term
+			ns.CommitIndex = st.CommitIndex
+			ns.AppliedIndex = st.AppliedIndex
+			ns.LastIndex = st.LastIndex
+			ns.DBSize = st.DBSize
+			ns.Snapshots = st.Snapshots
+			ns.Version = st.Version
+			if !st.LastContact.IsZero() {
+				lc := st.LastContact.UTC()
+				ns.LastContact = &lc
+			}
+		}(statuses[i])
+	}
+	wg.Wait()
+
+	// Lag is measured against the entries the leader knows are committed.
+	for _, l := range statuses {
+		if !l.Leader || !l.Reachable {
+			continue
+		}
+		for _, ns := range statuses {
+			if !ns.Reachable {
+				continue
+			}
+			var lag uint64
+			if l.CommitIndex > ns.AppliedIndex {
+				lag = l.CommitIndex - ns.AppliedIndex
+			}
+			ns.Lag = &lag
+		}
+	}
+
+	resp := struct {
+		Leader string               `json:"leader,omitempty"`
+		Nodes  []*clusterNodeStatus `json:"nodes"`
+	}{
+		Leader: lAddr,
+		Nodes:  statuses,
+	}
+
+	pretty, _ := isPretty(r)
+	var b []byte
+	if pretty {
+		b, err = json.MarshalIndent(resp, "", "    ")
+	} else {
+		b, err = json.Marshal(resp)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, err = w.Write(b)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleReadyz returns whether the node can serve requests. By default the
+// node must know the leader, and have applied the log entries present when it
+// opened. The query parameters add checks: leader requires the node to be the
+// leader, max_lag bounds the committed log entries not yet applied, and
+// freshness bounds the time since a follower last heard from the leader.
+// noleader skips the leader check. A follower measures its lag against the
+// commit index of the leader, which it asks for, so a follower cut off from
+// the leader fails the lag check.
+func (s *Service) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if !s.CheckRequestPerm(r, PermReady) {
+		s.unauthorized(w)
+		return
+	}
+
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	noLeader, err := noLeader(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	requireLeader, err := queryParam(r, "leader")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	maxLag, checkLag, err := maxLag(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	frsh, err := freshness(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	t, err := timeout(r, readyzLeaderTimeout)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rd, err := s.store.Readiness()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	// Every check is reported, Kubernetes-style, so a failing probe shows why.
+	ready := true
+	var b strings.Builder
+	check := func(ok bool, name, msg string) {
+		if ok {
+			fmt.Fprintf(&b, "[+]%s ok\n", name)
+			return
+		}
+		ready = false
+		fmt.Fprintf(&b, "[-]%s %s\n", name, msg)
+	}
+
+	if !noLeader {
+		check(rd.LeaderAddr != "", "leader", "not found")
+	}
+	if requireLeader {
+		check(rd.IsLeader, "is-leader", "node is not the leader")
+	}
+	check(rd.LogsApplied, "store", "log entries present at open not yet applied")
+	if checkLag {
+		lag, err := s.leaderLag(rd, t)
+		if err != nil {
+			check(false, "lag", err.Error())
+		} else {
+			check(lag <= maxLag, "lag", fmt.Sprintf("%d log entries not yet applied, more than %d", lag, maxLag))
+		}
+	}
+	if frsh > 0 && !rd.IsLeader {
+		ok := !rd.LastContact.IsZero() && time.Since(rd.LastContact) <= frsh
+		msg := "never contacted by leader"
+		if !rd.LastContact.IsZero() {
+			msg = fmt.Sprintf("last contacted by leader %s ago, more than %s",
+				time.Since(rd.LastContact).Round(time.Millisecond), frsh)
+		}
+		check(ok, "freshness", msg)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if !ready {
+		stats.Add(numReadyzFailures, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	w.Write([]byte(b.String()))
+}
+
+// leaderLag returns the number of log entries the leader has committed which
+// this node has not yet applied. A follower asks the leader for its commit
+// index, allowing it up to timeout to answer.
+func (s *Service) leaderLag(rd *store.Readiness, timeout time.Duration) (uint64, error) {
+	commitIdx := rd.CommitIndex
+	if !rd.IsLeader {
+		if rd.LeaderAddr == "" {
+			return 0, fmt.Errorf("no leader to measure lag against")
+		}
+		st, err := s.cluster.GetNodeStatus(rd.LeaderAddr, timeout)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+			return 0, fmt.Errorf("failed to get commit index of leader: %s", err)
 		}
+		commitIdx = st.CommitIndex
+	}
+	if commitIdx <= rd.AppliedIndex {
+		return 0, nil
 	}
+	return commitIdx - rd.AppliedIndex, nil
 }
 
-// handleNodes returns status on the other voting nodes in the system.
-// This attempts to contact all the nodes in the cluster, so may take
-// some time to return.
-func (s *Service) handleNodes(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-
+// handleLivez returns whether the node is running, and able to answer HTTP
+// requests. It makes no other checks, and requires no permission, as it
+// reveals nothing about the node.
+func (s *Service) handleLivez(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
 
-	t, err := timeout(r, time.Duration(1))
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("[+]node ok\n"))
+}
 
-	includeNonVoters, err := nonVoters(r)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+// handleVerify checks that every node holds the same data as the leader, and
+// returns the report.
+func (s *Service) handleVerify(w http.ResponseWriter, r *http.Request) {
+	// Verification writes to the Raft log, and makes every node copy and
+	// read its whole database, so it requires permission to write, and is
+	// limited to one every minimum interval.
+	if !s.CheckRequestPerm(r, PermExecute) {
+		s.unauthorized(w)
 		return
 	}
 
-	// Get nodes in the cluster, and possibly filter out non-voters.
-	nodes, err := s.store.Nodes()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-
-	filteredNodes := make([]*store.Server, 0)
-	for _, n := range nodes {
-		if n.Suffrage != "Voter" && !includeNonVoters {
-			continue
-		}
-		filteredNodes = append(filteredNodes, n)
+	if s.Verifier == nil {
+		http.Error(w, "verification not available", http.StatusServiceUnavailable)
+		return
 	}
+	stats.Add(numVerifyRequests, 1)
 
-	lAddr, err := s.store.LeaderAddr()
+	t, err := timeout(r, verifyTimeout)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	apiAddrs, err := s.checkNodesAPIAddr(filteredNodes, t)
+	report, err := s.Verifier.Verify(t)
 	if err != nil {
+		if err == store.ErrNotLeader {
+			leaderAPIAddr := s.LeaderAPIAddr()
+			if leaderAPIAddr == "" {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+
+			redirect := s.FormRedirect(r, leaderAPIAddr)
+			http.Redirect(w, r, redirect, http.StatusMovedPermanently)
+			return
+		}
+		if err == verify.ErrTooFrequent {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	resp := make(map[string]struct {
-		APIAddr   string `json:"api_addr,omitempty"`
-		Addr      string `json:"addr,omitempty"`
-		Reachable bool   `json:"reachable"`
-		Leader    bool   `json:"leader"`
-	})
-
-	for _, n := range filteredNodes {
-		nn := resp[n.ID]
-		nn.Addr = n.Addr
-		nn.Leader = nn.Addr == lAddr
-		nn.APIAddr = apiAddrs[n.ID]
-		nn.Reachable = apiAddrs[n.ID] != ""
-		resp[n.ID] = nn
-	}
-
 	pretty, _ := isPretty(r)
 	var b []byte
 	if pretty {
-		b, err = json.MarshalIndent(resp, "", "    ")
+		b, err = json.MarshalIndent(report, "", "    ")
 	} else {
-		b, err = json.Marshal(resp)
+		b, err = json.Marshal(report)
 	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
-	} else {
-		_, err = w.Write([]byte(b))
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+		return
+	}
+	_, err = w.Write(b)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 }
 
 // handleExecute handles queries that modify the database.
 func (s *Service) handleExecute(w http.ResponseWriter, r *http.Request) {
+	if !s.CheckRequestPerm(r, PermExecute) {
+		s.unauthorized(w)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 
 	if r.Method != "POST" {
@@ -621,6 +1747,18 @@ func (s *Service) handleExecute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	redirect, err := isRedirect(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	t, err := timeout(r, defaultTimeout)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	b, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -628,7 +1766,13 @@ func (s *Service) handleExecute(w http.ResponseWriter, r *http.Request) {
 	}
 	r.Body.Close()
 
-	stmts, err := ParseRequest(b)
+	stmts, preconditions, err := ParseExecuteRequest(b)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key, err := idempotencyKey(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -636,15 +1780,42 @@ func (s *Service) handleExecute(w http.ResponseWriter, r *http.Request) {
 
 	er := &command.ExecuteRequest{
 		Request: &command.Request{
-			Transaction: isTx,
-			Statements:  stmts,
+			Transaction:   isTx,
+			Statements:    stmts,
+			Preconditions: preconditions,
 		},
-		Timings: timings,
+		Timings:        timings,
+		IdempotencyKey: key,
 	}
 
-	results, err := s.store.Execute(er)
-	if err != nil {
-		if err == store.ErrNotLeader {
+	if id := txID(r); id != "" {
+		// Requests in an interactive transaction take effect only once it
+		// commits, in a single log entry.
+		if isTx || key != "" {
+			http.Error(w, "transaction and Idempotency-Key are not supported in interactive transactions",
+				http.StatusBadRequest)
+			return
+		}
+		results, err := s.store.ExecuteTx(id, er)
+		if s.writeTxError(w, r, err) {
+			return
+		}
+		if err != nil {
+			resp.Error = err.Error()
+			if errors.Is(err, sql.ErrPreconditionFailed) {
+				w.WriteHeader(http.StatusPreconditionFailed)
+			}
+		} else {
+			resp.Results = results
+		}
+		resp.end = time.Now()
+		s.writeResponse(w, r, resp)
+		return
+	}
+
+	results, idx, err := s.store.Execute(er)
+	if err == store.ErrNotLeader {
+		if redirect {
 			leaderAPIAddr := s.LeaderAPIAddr()
 			if leaderAPIAddr == "" {
 				http.Error(w, err.Error(), http.StatusServiceUnavailable)
@@ -655,9 +1826,25 @@ func (s *Service) handleExecute(w http.ResponseWriter, r *http.Request) {
 			http.Redirect(w, r, redirect, http.StatusMovedPermanently)
 			return
 		}
+
+		leaderAddr, lerr := s.store.LeaderAddr()
+		if lerr != nil || leaderAddr == "" {
+			http.Error(w, ErrLeaderNotFound.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		stats.Add(numRemoteExecutions, 1)
+		results, idx, err = s.cluster.Execute(er, leaderAddr, credentials(r), t)
+	}
+	if err != nil {
 		resp.Error = err.Error()
+		if errors.Is(err, sql.ErrPreconditionFailed) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+		} else if errors.Is(err, store.ErrIdempotencyKeyReused) {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		}
 	} else {
 		resp.Results = results
+		s.setRaftIndex(w, resp, idx)
 	}
 	resp.end = time.Now()
 	s.writeResponse(w, r, resp)
@@ -665,6 +1852,11 @@ func (s *Service) handleExecute(w http.ResponseWriter, r *http.Request) {
 
 // handleQuery handles queries that do not modify the database.
 func (s *Service) handleQuery(w http.ResponseWriter, r *http.Request) {
+	if !s.CheckRequestPerm(r, PermQuery) {
+		s.unauthorized(w)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 
 	if r.Method != "GET" && r.Method != "POST" {
@@ -698,6 +1890,12 @@ func (s *Service) handleQuery(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	minIdx, err := minIndex(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Get the query statement(s), and do tx if necessary.
 	queries, err := requestQueries(r)
 	if err != nil {
@@ -713,6 +1911,26 @@ func (s *Service) handleQuery(w http.ResponseWriter, r *http.Request) {
 		Timings:   timings,
 		Level:     lvl,
 		Freshness: frsh.Nanoseconds(),
+		MinIndex:  minIdx,
+	}
+
+	if id := txID(r); id != "" {
+		if isTx {
+			http.Error(w, "transaction is not supported in interactive transactions", http.StatusBadRequest)
+			return
+		}
+		results, err := s.store.QueryTx(id, qr)
+		if s.writeTxError(w, r, err) {
+			return
+		}
+		if err != nil {
+			resp.Error = err.Error()
+		} else {
+			resp.Results = results
+		}
+		resp.end = time.Now()
+		s.writeResponse(w, r, resp)
+		return
 	}
 
 	results, err := s.store.Query(qr)
@@ -736,8 +1954,89 @@ func (s *Service) handleQuery(w http.ResponseWriter, r *http.Request) {
 	s.writeResponse(w, r, resp)
 }
 
+// handleTx handles the beginning and end of interactive transactions. A POST
+// to /db/tx begins a transaction, and returns its ID. POSTs to /db/tx/commit
+// and /db/tx/rollback end the transaction given by the URL param 'tx'.
+// Transactions exist only on the leader, so other nodes redirect to it.
+func (s *Service) handleTx(w http.ResponseWriter, r *http.Request) {
+	if !s.CheckRequestPerm(r, PermExecute) {
+		s.unauthorized(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	resp := NewResponse()
+	var err error
+	switch r.URL.Path {
+	case "/db/tx":
+		resp.Tx, err = s.store.BeginTx()
+	case "/db/tx/commit":
+		var idx uint64
+		idx, err = s.store.CommitTx(txID(r))
+		if err == nil {
+			s.setRaftIndex(w, resp, idx)
+		}
+	case "/db/tx/rollback":
+		err = s.store.RollbackTx(txID(r))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if s.writeTxError(w, r, err) {
+		return
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	resp.end = time.Now()
+	s.writeResponse(w, r, resp)
+}
+
+// isError returns whether err is target. Errors returned by the leader to
+// forwarded requests only carry their text, so that is compared too.
+func isError(err, target error) bool {
+	return err == target || err.Error() == target.Error()
+}
+
+// writeTxError responds to a request made in an interactive transaction, if
+// it failed with an error which concerns the transaction rather than the
+// request itself. It returns whether a response was written.
+func (s *Service) writeTxError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch err {
+	case store.ErrNotLeader:
+		leaderAPIAddr := s.LeaderAPIAddr()
+		if leaderAPIAddr == "" {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return true
+		}
+
+		redirect := s.FormRedirect(r, leaderAPIAddr)
+		http.Redirect(w, r, redirect, http.StatusMovedPermanently)
+	case store.ErrTxNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case store.ErrTxConflict:
+		http.Error(w, err.Error(), http.StatusConflict)
+	case store.ErrTxTooLarge:
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	default:
+		return false
+	}
+	return true
+}
+
 // handleExpvar serves registered expvar information over HTTP.
 func (s *Service) handleExpvar(w http.ResponseWriter, r *http.Request) {
+	if !s.CheckRequestPerm(r, PermStatus) {
+		s.unauthorized(w)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 
 	fmt.Fprintf(w, "{\n")
@@ -754,6 +2053,11 @@ func (s *Service) handleExpvar(w http.ResponseWriter, r *http.Request) {
 
 // handlePprof serves pprof information over HTTP.
 func (s *Service) handlePprof(w http.ResponseWriter, r *http.Request) {
+	if !s.CheckRequestPerm(r, PermStatus) {
+		s.unauthorized(w)
+		return
+	}
+
 	switch r.URL.Path {
 	case "/debug/pprof/cmdline":
 		pprof.Cmdline(w, r)
@@ -771,6 +2075,44 @@ func (s *Service) Addr() net.Addr {
 	return s.ln.Addr()
 }
 
+// HTTPS returns whether this service is using HTTPS.
+func (s *Service) HTTPS() bool {
+	return s.CertFile != "" && s.KeyFile != ""
+}
+
+// CheckRequestPerm returns whether the request is authenticated, and the
+// user is authorized with the given perm.
+func (s *Service) CheckRequestPerm(r *http.Request, perm string) bool {
+	// No auth store set, so no checking required.
+	if s.credentialStore == nil {
+		return true
+	}
+
+	username, password, ok := r.BasicAuth()
+	if !ok || !s.credentialStore.Check(username, password) {
+		return false
+	}
+	return s.credentialStore.HasAnyPerm(username, perm, PermAll)
+}
+
+// credentials returns the credentials of the user who made the request, to
+// be carried with it if it is forwarded to the leader, or nil if it has none.
+func credentials(r *http.Request) *command.Credentials {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return nil
+	}
+	return &command.Credentials{Username: username, Password: password}
+}
+
+// unauthorized responds to a request which failed authentication or
+// authorization.
+func (s *Service) unauthorized(w http.ResponseWriter) {
+	stats.Add(numAuthFailures, 1)
+	w.Header().Set("WWW-Authenticate", `Basic realm="tqlite"`)
+	w.WriteHeader(http.StatusUnauthorized)
+}
+
 // FormRedirect returns the value for the "Location" header for a 301 response.
 func (s *Service) FormRedirect(r *http.Request, url string) string {
 	rq := r.URL.RawQuery
@@ -835,6 +2177,13 @@ func (s *Service) addBuildVersion(w http.ResponseWriter) {
 	w.Header().Add(VersionHTTPHeader, version)
 }
 
+// setRaftIndex records the Raft index of a write in both the response
+// body and headers, so clients can later read their own writes.
+func (s *Service) setRaftIndex(w http.ResponseWriter, j *Response, idx uint64) {
+	j.RaftIndex = idx
+	w.Header().Set(RaftIndexHTTPHeader, strconv.FormatUint(idx, 10))
+}
+
 // writeResponse writes the given response to the given writer.
 func (s *Service) writeResponse(w http.ResponseWriter, r *http.Request, j *Response) {
 	var b []byte
@@ -919,6 +2268,27 @@ func isTx(req *http.Request) (bool, error) {
 	return queryParam(req, "transaction")
 }
 
+// idempotencyKey returns the idempotency key of the request, if present.
+func idempotencyKey(req *http.Request) (string, error) {
+	key := strings.TrimSpace(req.Header.Get(IdempotencyKeyHTTPHeader))
+	if len(key) > maxIdempotencyKeyLen {
+		return "", fmt.Errorf("idempotency key longer than %d bytes", maxIdempotencyKeyLen)
+	}
+	return key, nil
+}
+
+// txID returns the value for URL param 'tx', the ID of the interactive
+// transaction in which a request is made, if present.
+func txID(req *http.Request) string {
+	return strings.TrimSpace(req.URL.Query().Get("tx"))
+}
+
+// isRedirect returns whether the HTTP request is requesting an explicit
+// redirect to the leader, instead of the request being forwarded.
+func isRedirect(req *http.Request) (bool, error) {
+	return queryParam(req, "redirect")
+}
+
 // noLeader returns whether processing should skip the leader check.
 func noLeader(req *http.Request) (bool, error) {
 	return queryParam(req, "noleader")
@@ -949,6 +2319,58 @@ func timeout(req *http.Request, d time.Duration) (time.Duration, error) {
 	return t, nil
 }
 
+// loadIDParam returns the value for URL param 'load_id', or a new random load
+// ID if it is not set.
+func loadIDParam(req *http.Request) (string, error) {
+	if id := strings.TrimSpace(req.URL.Query().Get("load_id")); id != "" {
+		return id, nil
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// loadOffset returns the offset in the SQL text of a load at which the body
+// of the request starts.
+func loadOffset(req *http.Request) (uint64, error) {
+	o := strings.TrimSpace(req.URL.Query().Get("offset"))
+	if o == "" {
+		return 0, nil
+	}
+	offset, err := strconv.ParseUint(o, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid offset: %s", err)
+	}
+	return offset, nil
+}
+
+// loadChunkSize returns the requested size, in bytes, of the chunks of a
+// load.
+func loadChunkSize(req *http.Request) (int, error) {
+	c := strings.TrimSpace(req.URL.Query().Get("chunk_size"))
+	if c == "" {
+		return defaultLoadChunkSize, nil
+	}
+	sz, err := strconv.Atoi(c)
+	if err != nil || sz <= 0 {
+		return 0, fmt.Errorf("invalid chunk_size: %s", c)
+	}
+	return sz, nil
+}
+
+// firstWord returns the first word of a SQL statement.
+func firstWord(stmt string) string {
+	i := strings.IndexFunc(stmt, func(r rune) bool {
+		return !(r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r))
+	})
+	if i == -1 {
+		return stmt
+	}
+	return stmt[:i]
+}
+
 // level returns the requested consistency level for a query
 func level(req *http.Request) (command.QueryRequest_Level, error) {
 	q := req.URL.Query()
@@ -961,6 +2383,8 @@ func level(req *http.Request) (command.QueryRequest_Level, error) {
 		return command.QueryRequest_QUERY_REQUEST_LEVEL_WEAK, nil
 	case "strong":
 		return command.QueryRequest_QUERY_REQUEST_LEVEL_STRONG, nil
+	case "linearizable":
+		return command.QueryRequest_QUERY_REQUEST_LEVEL_LINEARIZABLE, nil
 	default:
 		return command.QueryRequest_QUERY_REQUEST_LEVEL_WEAK, nil
 	}
@@ -981,6 +2405,38 @@ func freshness(req *http.Request) (time.Duration, error) {
 	return d, nil
 }
 
+// minIndex returns the minimum Raft index a node must have applied before
+// serving a query, if any.
+func minIndex(req *http.Request) (uint64, error) {
+	q := req.URL.Query()
+	m := strings.TrimSpace(q.Get("min_index"))
+	if m == "" {
+		return 0, nil
+	}
+
+	idx, err := strconv.ParseUint(m, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid min_index: %s", err)
+	}
+	return idx, nil
+}
+
+// maxLag returns the maximum number of committed log entries a node may
+// not yet have applied, and whether it was set.
+func maxLag(req *http.Request) (uint64, bool, error) {
+	q := req.URL.Query()
+	m := strings.TrimSpace(q.Get("max_lag"))
+	if m == "" {
+		return 0, false, nil
+	}
+
+	lag, err := strconv.ParseUint(m, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid max_lag: %s", err)
+	}
+	return lag, true, nil
+}
+
 // backupFormat returns the request backup format, setting the response header
 // accordingly.
 func backupFormat(w http.ResponseWriter, r *http.Request) (store.BackupFormat, error) {
@@ -1003,15 +2459,74 @@ func prettyEnabled(e bool) string {
 	return "disabled"
 }
 
+// JoinToken returns the token authorizing the node with the given ID and
+// Raft address to join a cluster with the given join secret, created at t.
+func JoinToken(secret, id, addr string, voter bool, t time.Time) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s\n%s\n%t\n%d", id, addr, voter, t.Unix())
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyJoinToken checks that token authorizes the node with the given ID
+// and Raft address to join a cluster with the given join secret, and that it
+// was created at t, recently.
+func VerifyJoinToken(secret, token, id, addr string, voter bool, t time.Time) error {
+	if token == "" {
+		return ErrJoinTokenMissing
+	}
+	if d := time.Since(t); d > joinTokenMaxAge || d < -joinTokenMaxAge {
+		return ErrJoinTokenExpired
+	}
+	expected := JoinToken(secret, id, addr, voter, t)
+	if !hmac.Equal([]byte(token), []byte(expected)) {
+		return ErrJoinTokenInvalid
+	}
+	return nil
+}
+
 // NormalizeAddr ensures that the given URL has a HTTP protocol prefix.
 // If none is supplied, it prefixes the URL with "http://".
 func NormalizeAddr(addr string) string {
-	if !strings.HasPrefix(addr, "http://") {
+	if !strings.HasPrefix(addr, "http://") && !strings.HasPrefix(addr, "https://") {
 		return fmt.Sprintf("http://%s", addr)
 	}
 	return addr
 }
 
+// EnsureHTTPS modifies the given URL, ensuring it is using the HTTPS protocol.
+func EnsureHTTPS(addr string) string {
+	if !strings.HasPrefix(addr, "http://") && !strings.HasPrefix(addr, "https://") {
+		return fmt.Sprintf("https://%s", addr)
+	}
+	return strings.Replace(addr, "http://", "https://", 1)
+}
+
+// createTLSConfig returns a TLS config from the given cert and key. If
+// caCertFile is set, clients must present a certificate signed by that CA.
+func createTLSConfig(certFile, keyFile, caCertFile string) (*tls.Config, error) {
+	var err error
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	config.Certificates = make([]tls.Certificate, 1)
+	config.Certificates[0], err = tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	if caCertFile != "" {
+		asn1Data, err := ioutil.ReadFile(caCertFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = x509.NewCertPool()
+		if ok := config.ClientCAs.AppendCertsFromPEM(asn1Data); !ok {
+			return nil, fmt.Errorf("failed to parse CA certificate(s) in %q", caCertFile)
+		}
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
 // queryRequestFromStrings converts a slice of strings into a command.QueryRequest
 func executeRequestFromStrings(s []string, timings, tx bool) *command.ExecuteRequest {
 	stmts := make([]*command.Statement, len(s))
@@ -0,0 +1,308 @@
+package http
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the buckets of the
+// request latency histograms.
+var latencyBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// endpoints are the path prefixes for which request latencies are tracked.
+// Requests for any other path are tracked as "other".
+var endpoints = []string{
+	"/db/execute",
+	"/db/query",
+	"/db/backup",
+	"/db/load",
+	"/db/tx",
+	"/join",
+	"/notify",
+	"/remove",
+	"/promote",
+	"/demote",
+	"/leader/transfer",
+	"/status",
+	"/nodes",
+	"/cluster",
+	"/verify",
+	"/readyz",
+	"/livez",
+	"/metrics",
+	"/debug/vars",
+	"/debug/pprof",
+}
+
+// raftStates are the states a node may be in, as reported by Raft.
+var raftStates = []string{"Follower", "Candidate", "Leader", "Shutdown"}
+
+// histogram is a cumulative histogram of observed values.
+type histogram struct {
+	counts []uint64 // Observations in each bucket, not cumulative.
+	sum    float64
+	count  uint64
+}
+
+// latencies tracks request latencies, by endpoint.
+type latencies struct {
+	mu         sync.Mutex
+	histograms map[string]*histogram
+}
+
+// requestLatencies holds the latencies of all requests served.
+var requestLatencies = &latencies{histograms: make(map[string]*histogram)}
+
+// observe records a request to the endpoint serving path, which took d.
+func (l *latencies) observe(path string, d time.Duration) {
+	ep := endpoint(path)
+	v := d.Seconds()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	h, ok := l.histograms[ep]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(latencyBuckets))}
+		l.histograms[ep] = h
+	}
+	for i, b := range latencyBuckets {
+		if v <= b {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// write writes the histograms in Prometheus text format.
+func (l *latencies) write(w io.Writer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	name := "tqlite_http_request_duration_seconds"
+	writeHeader(w, name, "histogram", "Latency of HTTP requests, by endpoint.")
+
+	eps := make([]string, 0, len(l.histograms))
+	for ep := range l.histograms {
+		eps = append(eps, ep)
+	}
+	sort.Strings(eps)
+	for _, ep := range eps {
+		h := l.histograms[ep]
+		var cumulative uint64
+		for i, b := range latencyBuckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(w, "%s_bucket{endpoint=%q,le=%q} %d\n", name, ep, formatFloat(b), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket{endpoint=%q,le=\"+Inf\"} %d\n", name, ep, h.count)
+		fmt.Fprintf(w, "%s_sum{endpoint=%q} %s\n", name, ep, formatFloat(h.sum))
+		fmt.Fprintf(w, "%s_count{endpoint=%q} %d\n", name, ep, h.count)
+	}
+}
+
+// endpoint returns the endpoint label for a request for path.
+func endpoint(path string) string {
+	for _, ep := range endpoints {
+		if strings.HasPrefix(path, ep) {
+			return ep
+		}
+	}
+	return "other"
+}
+
+// handleMetrics serves metrics in Prometheus text format. It exports every
+// counter in the expvar maps, the state of Raft and the database, and the
+// latency of HTTP requests.
+func (s *Service) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if !s.CheckRequestPerm(r, PermStatus) {
+		s.unauthorized(w)
+		return
+	}
+
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	storeStatus, err := s.store.Stats()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	if raftStatus, ok := storeStatus["raft"].(map[string]interface{}); ok {
+		writeRaftMetrics(w, raftStatus)
+	}
+
+	if snapStatus, ok := storeStatus["snapshot"].(map[string]interface{}); ok {
+		if t, ok := snapStatus["last_time"].(time.Time); ok {
+			writeGauge(w, "tqlite_snapshot_last_timestamp_seconds",
+				"Time the last snapshot was persisted, as seconds since the epoch.", float64(t.UnixNano())/1e9)
+		}
+		if d, ok := parseDuration(snapStatus["last_create_duration"]); ok {
+			writeGauge(w, "tqlite_snapshot_last_create_duration_seconds",
+				"Time taken to create the last snapshot.", d.Seconds())
+		}
+		if d, ok := parseDuration(snapStatus["last_persist_duration"]); ok {
+			writeGauge(w, "tqlite_snapshot_last_persist_duration_seconds",
+				"Time taken to create and persist the last snapshot.", d.Seconds())
+		}
+	}
+
+	if dbStatus, ok := storeStatus["sqlite3"].(map[string]interface{}); ok {
+		if v, ok := toFloat(dbStatus["db_size"]); ok {
+			writeGauge(w, "tqlite_db_size_bytes", "Size of the SQLite database.", v)
+		}
+		if v, ok := toFloat(dbStatus["size"]); ok {
+			writeGauge(w, "tqlite_db_file_size_bytes", "Size of the SQLite database file on disk.", v)
+		}
+	}
+	if v, ok := toFloat(storeStatus["dir_size"]); ok {
+		writeGauge(w, "tqlite_raft_dir_size_bytes", "Size of the Raft directory.", v)
+	}
+
+	writeExpvarMetrics(w)
+	requestLatencies.write(w)
+}
+
+// writeRaftMetrics writes the state of Raft, as reported by the Store.
+func writeRaftMetrics(w io.Writer, raftStatus map[string]interface{}) {
+	state, _ := raftStatus["state"].(string)
+	writeHeader(w, "tqlite_raft_state", "gauge", "Raft state of the node, 1 for the current state.")
+	for _, st := range raftStates {
+		v := 0
+		if st == state {
+			v = 1
+		}
+		fmt.Fprintf(w, "tqlite_raft_state{state=%q} %d\n", st, v)
+	}
+
+	gauges := []struct {
+		key  string
+		name string
+		help string
+	}{
+		{"term", "tqlite_raft_term", "Current Raft term."},
+		{"commit_index", "tqlite_raft_commit_index", "Index of the last committed Raft log entry."},
+		{"applied_index", "tqlite_raft_applied_index", "Index of the last Raft log entry applied to the database."},
+		{"last_log_index", "tqlite_raft_last_log_index", "Index of the last Raft log entry."},
+		{"last_snapshot_index", "tqlite_raft_last_snapshot_index", "Raft index of the last snapshot."},
+		{"fsm_pending", "tqlite_raft_fsm_pending", "Number of Raft log entries waiting to be applied."},
+		{"num_peers", "tqlite_raft_num_peers", "Number of other voting nodes in the cluster."},
+		{"log_size", "tqlite_raft_log_size_bytes", "Size of the Raft log."},
+	}
+	for _, g := range gauges {
+		if v, ok := toFloat(raftStatus[g.key]); ok {
+			writeGauge(w, g.name, g.help, v)
+		}
+	}
+
+	// The leader reports a last contact of 0, and a node which has never
+	// heard from a leader reports "never".
+	if d, ok := parseDuration(raftStatus["last_contact"]); ok {
+		writeGauge(w, "tqlite_raft_last_contact_seconds",
+			"Time since the node last heard from the leader.", d.Seconds())
+	}
+}
+
+// writeExpvarMetrics writes every value in the expvar maps as a counter.
+func writeExpvarMetrics(w io.Writer) {
+	expvar.Do(func(kv expvar.KeyValue) {
+		m, ok := kv.Value.(*expvar.Map)
+		if !ok {
+			return
+		}
+
+		var keys []string
+		vals := make(map[string]float64)
+		m.Do(func(e expvar.KeyValue) {
+			var v float64
+			switch ev := e.Value.(type) {
+			case *expvar.Int:
+				v = float64(ev.Value())
+			case *expvar.Float:
+				v = ev.Value()
+			default:
+				return
+			}
+			keys = append(keys, e.Key)
+			vals[e.Key] = v
+		})
+
+		for _, k := range keys {
+			name := fmt.Sprintf("tqlite_%s_%s_total", metricName(kv.Key), metricName(k))
+			writeHeader(w, name, "counter", fmt.Sprintf("Value of %s in expvar map %s.", k, kv.Key))
+			fmt.Fprintf(w, "%s %s\n", name, formatFloat(vals[k]))
+		}
+	})
+}
+
+func writeHeader(w io.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+}
+
+func writeGauge(w io.Writer, name, help string, v float64) {
+	writeHeader(w, name, "gauge", help)
+	fmt.Fprintf(w, "%s %s\n", name, formatFloat(v))
+}
+
+// metricName returns s with every character not allowed in a metric name
+// replaced by an underscore.
+func metricName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return fmt.Sprintf("%g", v)
+}
+
+// toFloat returns v as a float64, if it is a number.
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// parseDuration returns v as a time.Duration, if it is a duration string or
+// a number of nanoseconds.
+func parseDuration(v interface{}) (time.Duration, bool) {
+	if s, ok := v.(string); ok {
+		d, err := time.ParseDuration(s)
+		return d, err == nil
+	}
+	if n, ok := toFloat(v); ok {
+		return time.Duration(n), true
+	}
+	return 0, false
+}
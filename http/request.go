@@ -1,6 +1,7 @@
 package http
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 
@@ -16,8 +17,113 @@ var (
 
 	// ErrUnsupportedType is returned when a request contains an unsupported type.
 	ErrUnsupportedType = errors.New("unsupported type")
+
+	// ErrInvalidPrecondition is returned when a precondition cannot be parsed.
+	ErrInvalidPrecondition = errors.New("invalid precondition")
 )
 
+// ParseExecuteRequest generates the Statements and Preconditions of an
+// execute request for a given byte slice. The request is either a set of
+// statements, in any form ParseRequest accepts, or an object holding them
+// along with the preconditions which must hold for them to be executed:
+//
+//	{
+//	  "preconditions": [
+//	    {"query": ["SELECT version FROM docs WHERE id=?", 7], "value": 3},
+//	    {"query": "SELECT * FROM locks", "rows": 0}
+//	  ],
+//	  "statements": [
+//	    ["UPDATE docs SET body=?, version=4 WHERE id=?", "text", 7]
+//	  ]
+//	}
+//
+// A precondition with a "value" requires its query to return exactly one row,
+// whose first column equals the value. Otherwise its query must return
+// exactly "rows" rows.
+func ParseExecuteRequest(b []byte) ([]*command.Statement, []*command.Precondition, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(b), []byte("{")) {
+		stmts, err := ParseRequest(b)
+		return stmts, nil, err
+	}
+
+	var req struct {
+		Preconditions []map[string]json.RawMessage `json:"preconditions"`
+		Statements    json.RawMessage              `json:"statements"`
+	}
+	if err := json.Unmarshal(b, &req); err != nil {
+		return nil, nil, ErrInvalidRequest
+	}
+	stmts, err := ParseRequest(req.Statements)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	preconditions := make([]*command.Precondition, len(req.Preconditions))
+	for i, p := range req.Preconditions {
+		preconditions[i], err = parsePrecondition(p)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return stmts, preconditions, nil
+}
+
+// parsePrecondition generates a Precondition from the fields of its JSON
+// object.
+func parsePrecondition(p map[string]json.RawMessage) (*command.Precondition, error) {
+	q, ok := p["query"]
+	if !ok {
+		return nil, ErrInvalidPrecondition
+	}
+	// The query takes any form a single statement of a request may take.
+	stmts, err := ParseRequest(append(append([]byte("["), q...), ']'))
+	if err != nil {
+		return nil, ErrInvalidPrecondition
+	}
+	precondition := &command.Precondition{Statement: stmts[0]}
+
+	if v, ok := p["value"]; ok {
+		// Numbers are decoded as such, so that integers too large for a
+		// float64 are compared exactly.
+		var value interface{}
+		dec := json.NewDecoder(bytes.NewReader(v))
+		dec.UseNumber()
+		if err := dec.Decode(&value); err != nil {
+			return nil, ErrInvalidPrecondition
+		}
+		precondition.Type = command.Precondition_PRECONDITION_TYPE_VALUE
+		precondition.Value = &command.Parameter{}
+		switch w := value.(type) {
+		case nil:
+		case json.Number:
+			if i, err := w.Int64(); err == nil {
+				precondition.Value.Value = &command.Parameter_I{I: i}
+			} else if d, err := w.Float64(); err == nil {
+				precondition.Value.Value = &command.Parameter_D{D: d}
+			} else {
+				return nil, ErrInvalidPrecondition
+			}
+		case bool:
+			precondition.Value.Value = &command.Parameter_B{B: w}
+		case string:
+			precondition.Value.Value = &command.Parameter_S{S: w}
+		default:
+			return nil, ErrUnsupportedType
+		}
+		return precondition, nil
+	}
+
+	r, ok := p["rows"]
+	if !ok {
+		return nil, ErrInvalidPrecondition
+	}
+	if err := json.Unmarshal(r, &precondition.Rows); err != nil || precondition.Rows < 0 {
+		return nil, ErrInvalidPrecondition
+	}
+	precondition.Type = command.Precondition_PRECONDITION_TYPE_ROWS
+	return precondition, nil
+}
+
 // ParseRequest generates a set of Statements for a given byte slice.
 func ParseRequest(b []byte) ([]*command.Statement, error) {
 	if b == nil {
@@ -0,0 +1,333 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+const (
+	// heartbeatFailureWindow is how long after Raft last reported a failed
+	// heartbeat to a node the node is considered unhealthy. Raft backs off
+	// retrying failed heartbeats for at most about 10 seconds, so failures
+	// are reported at least that often while a node is unreachable.
+	heartbeatFailureWindow = 15 * time.Second
+
+	// maxAutopilotDecisions is the number of recent decisions kept.
+	maxAutopilotDecisions = 20
+
+	autopilotRemove  = "remove"
+	autopilotPromote = "promote"
+	autopilotDefer   = "defer"
+)
+
+// ServerHealth is the health of a node in the cluster, as tracked by
+// autopilot on the leader.
+type ServerHealth struct {
+	ID          string    `json:"id"`
+	Healthy     bool      `json:"healthy"`
+	LastContact time.Time `json:"last_contact"`            // Time the leader last heard from the node.
+	StableSince time.Time `json:"stable_since"`            // Time the node last became healthy or unhealthy.
+	Decision    string    `json:"last_decision,omitempty"` // Last decision autopilot made about the node.
+
+	firstSeen time.Time // Time autopilot started tracking the node.
+}
+
+// AutopilotDecision is a change autopilot made, or chose not to make, to
+// the cluster.
+type AutopilotDecision struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	ID     string    `json:"id"`
+	Reason string    `json:"reason"`
+}
+
+// heartbeatFailure is a failed heartbeat to a node, reported by Raft.
+type heartbeatFailure struct {
+	at          time.Time
+	lastContact time.Time
+}
+
+// autopilot runs on every node, but only acts while the node is the leader.
+// It tracks the health of all other nodes using the heartbeat failures Raft
+// reports, and removes nodes which have been unreachable for too long.
+type autopilot struct {
+	s *Store
+
+	mu          sync.Mutex
+	failures    map[raft.ServerID]heartbeatFailure
+	health      map[string]*ServerHealth
+	decisions   []AutopilotDecision
+	leaderSince time.Time // Time this node was first seen as leader, zero if not leader.
+
+	obsCh    chan raft.Observation
+	observer *raft.Observer
+	done     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+func newAutopilot(s *Store) *autopilot {
+	a := &autopilot{
+		s:        s,
+		failures: make(map[raft.ServerID]heartbeatFailure),
+		health:   make(map[string]*ServerHealth),
+		obsCh:    make(chan raft.Observation, 16),
+		done:     make(chan struct{}),
+	}
+	a.observer = raft.NewObserver(a.obsCh, false, func(o *raft.Observation) bool {
+		_, ok := o.Data.(raft.FailedHeartbeatObservation)
+		return ok
+	})
+	return a
+}
+
+// start starts autopilot.
+func (a *autopilot) start() {
+	a.s.raft.RegisterObserver(a.observer)
+	a.wg.Add(1)
+	go a.run()
+}
+
+// stop stops autopilot, and waits for it to exit. It is safe to call more
+// than once.
+func (a *autopilot) stop() {
+	a.stopOnce.Do(func() {
+		close(a.done)
+		a.wg.Wait()
+		a.s.raft.DeregisterObserver(a.observer)
+	})
+}
+
+func (a *autopilot) run() {
+	defer a.wg.Done()
+	tck := time.NewTicker(a.s.AutopilotInterval)
+	defer tck.Stop()
+
+	for {
+		select {
+		case o := <-a.obsCh:
+			if f, ok := o.Data.(raft.FailedHeartbeatObservation); ok {
+				a.mu.Lock()
+				a.failures[f.PeerID] = heartbeatFailure{at: time.Now(), lastContact: f.LastContact}
+				a.mu.Unlock()
+			}
+		case <-tck.C:
+			if err := a.check(); err != nil {
+				a.s.logger.Printf("autopilot check failed: %s", err.Error())
+			}
+		case <-a.done:
+			return
+		}
+	}
+}
+
+// check updates the health of every node, and removes those which have been
+// unreachable for too long.
+func (a *autopilot) check() error {
+	now := time.Now()
+	if a.s.raft.State() != raft.Leader {
+		a.mu.Lock()
+		a.leaderSince = time.Time{}
+		a.failures = make(map[raft.ServerID]heartbeatFailure)
+		a.health = make(map[string]*ServerHealth)
+		a.mu.Unlock()
+		return nil
+	}
+
+	f := a.s.raft.GetConfiguration()
+	if err := f.Error(); err != nil {
+		return err
+	}
+	servers := f.Configuration().Servers
+	sort.Slice(servers, func(i, j int) bool { return servers[i].ID < servers[j].ID })
+
+	a.mu.Lock()
+	if a.leaderSince.IsZero() {
+		a.leaderSince = now
+	}
+	a.updateHealth(servers, now)
+	removals := a.removals(servers, now)
+	a.mu.Unlock()
+
+	for _, id := range removals {
+		if err := a.s.Remove(id); err != nil {
+			a.record(now, autopilotDefer, id, fmt.Sprintf("removal failed: %s", err.Error()))
+		}
+	}
+	return nil
+}
+
+// updateHealth updates the health of the nodes in servers, and forgets any
+// other nodes. a.mu must be held.
+func (a *autopilot) updateHealth(servers []raft.Server, now time.Time) {
+	ids := make(map[string]bool, len(servers))
+	for _, srv := range servers {
+		id := string(srv.ID)
+		ids[id] = true
+
+		h, ok := a.health[id]
+		if !ok {
+			h = &ServerHealth{ID: id, Healthy: true, StableSince: now, firstSeen: now}
+			a.health[id] = h
+		}
+
+		healthy := true
+		lastContact := now
+		if fail, ok := a.failures[srv.ID]; ok && now.Sub(fail.at) < heartbeatFailureWindow && id != a.s.raftID {
+			healthy = false
+			// A node this leader has never reached is treated as last
+			// contacted when autopilot started tracking it.
+			lastContact = fail.lastContact
+			if lastContact.Before(h.firstSeen) {
+				lastContact = h.firstSeen
+			}
+		}
+		if healthy != h.Healthy {
+			h.Healthy = healthy
+			h.StableSince = now
+			h.Decision = ""
+		}
+		h.LastContact = lastContact
+	}
+
+	for id := range a.health {
+		if !ids[id] {
+			delete(a.health, id)
+		}
+	}
+	for id := range a.failures {
+		if !ids[string(id)] {
+			delete(a.failures, id)
+		}
+	}
+}
+
+// removals returns the IDs of the nodes to remove from the cluster. At most
+// one voter is removed at a time, and only if enough healthy voters remain
+// for quorum, and at least ReapMinVoters voters remain. a.mu must be held.
+func (a *autopilot) removals(servers []raft.Server, now time.Time) []string {
+	if a.s.NonVoterReapTimeout == 0 && a.s.VoterReapTimeout == 0 {
+		return nil
+	}
+	if now.Sub(a.leaderSince) < a.s.ServerStabilizationTime {
+		return nil
+	}
+
+	var voters, healthyVoters int
+	for _, srv := range servers {
+		if srv.Suffrage == raft.Voter {
+			voters++
+			if a.health[string(srv.ID)].Healthy {
+				healthyVoters++
+			}
+		}
+	}
+
+	var ids []string
+	voterRemoved := false
+	for _, srv := range servers {
+		id := string(srv.ID)
+		h := a.health[id]
+		if h.Healthy || id == a.s.raftID || now.Sub(h.StableSince) < a.s.ServerStabilizationTime {
+			continue
+		}
+		down := now.Sub(h.LastContact)
+
+		if srv.Suffrage != raft.Voter {
+			if a.s.NonVoterReapTimeout == 0 || down < a.s.NonVoterReapTimeout {
+				continue
+			}
+			a.decide(now, h, autopilotRemove, fmt.Sprintf("non-voter unreachable for %s", down.Round(time.Second)))
+			ids = append(ids, id)
+			continue
+		}
+
+		if a.s.VoterReapTimeout == 0 || down < a.s.VoterReapTimeout {
+			continue
+		}
+		if voterRemoved {
+			continue
+		}
+		if voters-1 < a.s.ReapMinVoters {
+			a.decide(now, h, autopilotDefer, fmt.Sprintf("voter unreachable for over %s, but removing it would leave "+
+				"%d voters, fewer than the minimum of %d", a.s.VoterReapTimeout, voters-1, a.s.ReapMinVoters))
+			continue
+		}
+		if quorum := (voters-1)/2 + 1; healthyVoters < quorum {
+			a.decide(now, h, autopilotDefer, fmt.Sprintf("voter unreachable for over %s, but removing it would leave "+
+				"%d healthy voters, fewer than the quorum of %d", a.s.VoterReapTimeout, healthyVoters, quorum))
+			continue
+		}
+		a.decide(now, h, autopilotRemove, fmt.Sprintf("voter unreachable for %s", down.Round(time.Second)))
+		ids = append(ids, id)
+		voters--
+		voterRemoved = true
+	}
+	return ids
+}
+
+// decide records a decision about the node h, unless the same decision was
+// made last. a.mu must be held.
+func (a *autopilot) decide(now time.Time, h *ServerHealth, action, reason string) {
+	d := fmt.Sprintf("%s: %s", action, reason)
+	if h.Decision == d {
+		return
+	}
+	h.Decision = d
+	a.s.logger.Printf("autopilot decided to %s node %s: %s", action, h.ID, reason)
+	a.decisions = append(a.decisions, AutopilotDecision{Time: now, Action: action, ID: h.ID, Reason: reason})
+	if len(a.decisions) > maxAutopilotDecisions {
+		a.decisions = a.decisions[len(a.decisions)-maxAutopilotDecisions:]
+	}
+}
+
+// record records a decision about the node with the given ID.
+func (a *autopilot) record(now time.Time, action, id, reason string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	h, ok := a.health[id]
+	if !ok {
+		h = &ServerHealth{ID: id}
+	}
+	a.decide(now, h, action, reason)
+}
+
+// serverHealth returns the health of every node, by ID. It is empty unless
+// this node is the leader.
+func (a *autopilot) serverHealth() map[string]ServerHealth {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	m := make(map[string]ServerHealth, len(a.health))
+	for id, h := range a.health {
+		m[id] = *h
+	}
+	return m
+}
+
+// stats returns the status of autopilot.
+func (a *autopilot) stats() map[string]interface{} {
+	health := a.serverHealth()
+	servers := make([]ServerHealth, 0, len(health))
+	for _, h := range health {
+		servers = append(servers, h)
+	}
+	sort.Slice(servers, func(i, j int) bool { return servers[i].ID < servers[j].ID })
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	decisions := make([]AutopilotDecision, len(a.decisions))
+	copy(decisions, a.decisions)
+	return map[string]interface{}{
+		"non_voter_reap_timeout":    a.s.NonVoterReapTimeout.String(),
+		"voter_reap_timeout":        a.s.VoterReapTimeout.String(),
+		"reap_min_voters":           a.s.ReapMinVoters,
+		"server_stabilization_time": a.s.ServerStabilizationTime.String(),
+		"stable":                    !a.leaderSince.IsZero() && time.Since(a.leaderSince) >= a.s.ServerStabilizationTime,
+		"servers":                   servers,
+		"decisions":                 decisions,
+	}
+}
@@ -0,0 +1,152 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+
+	"github.com/hashicorp/raft"
+	"github.com/minghsu0107/tqlite/command"
+	sql "github.com/minghsu0107/tqlite/db"
+)
+
+// Checksum is a checksum of the content of the database, taken by a node when
+// it applied a checksum command. Every node applies the same log entries, so
+// nodes whose checksums at the same index differ have diverged.
+type Checksum struct {
+	Index  uint64            `json:"index"`            // Index of the log entry which carried the checksum command.
+	Tables map[string]string `json:"tables,omitempty"` // Checksum of each table, and of the schema as "sqlite_master".
+	Error  string            `json:"error,omitempty"`  // Set if the checksum could not be taken.
+}
+
+// Digest returns a checksum covering every table.
+func (c *Checksum) Digest() string {
+	names := make([]string, 0, len(c.Tables))
+	for n := range c.Tables {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, n := range names {
+		h.Write([]byte(n + "=" + c.Tables[n] + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Diff returns the names, sorted, of the tables whose checksums differ
+// between c and o, including tables only one of them has.
+func (c *Checksum) Diff(o *Checksum) []string {
+	var names []string
+	for n, sum := range c.Tables {
+		if o.Tables[n] != sum {
+			names = append(names, n)
+		}
+	}
+	for n := range o.Tables {
+		if _, ok := c.Tables[n]; !ok {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Checksum writes a checksum command to the Raft log. Every node takes a
+// checksum of its database when it applies the command, so the checksums of
+// all nodes reflect the same point in the log. It returns the checksum taken
+// by this node, which must be the leader.
+func (s *Store) Checksum() (*Checksum, error) {
+	if s.raft.State() != raft.Leader {
+		return nil, ErrNotLeader
+	}
+
+	b, err := command.Marshal(&command.Command{
+		Type: command.Command_COMMAND_TYPE_CHECKSUM,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	f := s.raft.Apply(b, s.ApplyTimeout)
+	if e := f.(raft.Future); e.Error() != nil {
+		if e.Error() == raft.ErrNotLeader {
+			return nil, ErrNotLeader
+		}
+		return nil, e.Error()
+	}
+	r := f.Response().(*fsmChecksumResponse)
+	<-r.done
+	return r.checksum, r.error
+}
+
+// LastChecksum returns the checksum this node took when it applied the most
+// recent checksum command, or nil if it has applied none since it started.
+func (s *Store) LastChecksum() *Checksum {
+	s.checksumMu.RLock()
+	defer s.checksumMu.RUnlock()
+	return s.checksum
+}
+
+// applyChecksum takes a checksum of the database, for the checksum command
+// at the given log index. Reading every row of a large database takes long,
+// and writes could not be applied meanwhile, so the database is copied, which
+// is much quicker, and the checksum of the copy is taken in the background.
+// The checksum is done once the done channel of the response is closed. Only
+// one checksum is taken at a time, as each holds a copy of the database.
+func (s *Store) applyChecksum(index uint64) *fsmChecksumResponse {
+	stats.Add(numChecksums, 1)
+	r := &fsmChecksumResponse{
+		checksum: &Checksum{Index: index},
+		done:     make(chan struct{}),
+	}
+
+	s.checksumMu.Lock()
+	busy := s.checksumBusy
+	s.checksumBusy = true
+	s.checksumMu.Unlock()
+	if busy {
+		s.endChecksum(r, nil, ErrChecksumInProgress, false)
+		return r
+	}
+
+	db, err := sql.OpenInMemory()
+	if err != nil {
+		s.endChecksum(r, nil, err, true)
+		return r
+	}
+	if err := s.db.Copy(db); err != nil {
+		db.Close()
+		s.endChecksum(r, nil, err, true)
+		return r
+	}
+	go func() {
+		tables, err := db.Checksum()
+		db.Close()
+		s.endChecksum(r, tables, err, true)
+	}()
+	return r
+}
+
+// endChecksum records the outcome of the checksum of r. The checksum replaces
+// that of the node, unless the node has a checksum at a later index. idle
+// marks the node as no longer taking a checksum.
+func (s *Store) endChecksum(r *fsmChecksumResponse, tables map[string]string, err error, idle bool) {
+	if err != nil {
+		r.checksum.Error = err.Error()
+		r.error = err
+		s.logger.Printf("failed to take checksum at index %d: %s", r.checksum.Index, err)
+	} else {
+		r.checksum.Tables = tables
+	}
+
+	s.checksumMu.Lock()
+	if s.checksum == nil || s.checksum.Index < r.checksum.Index {
+		s.checksum = r.checksum
+	}
+	if idle {
+		s.checksumBusy = false
+	}
+	s.checksumMu.Unlock()
+	close(r.done)
+}
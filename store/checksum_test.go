@@ -0,0 +1,53 @@
+package store
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func Test_ApplyChecksum(t *testing.T) {
+	s := mustOpenDBStore(t, filepath.Join(t.TempDir(), sqliteFile))
+	mustExecute(t, s, "CREATE TABLE foo (id INTEGER NOT NULL PRIMARY KEY, name TEXT)")
+	mustExecute(t, s, `INSERT INTO foo(name) VALUES("fiona")`)
+	exp, err := s.db.Checksum()
+	if err != nil {
+		t.Fatalf("failed to checksum database: %s", err)
+	}
+
+	// The checksum is of the database at the index, even if writes are
+	// applied while it is being taken.
+	r := s.applyChecksum(5)
+	mustExecute(t, s, `INSERT INTO foo(name) VALUES("declan")`)
+	<-r.done
+	if r.error != nil {
+		t.Fatalf("failed to take checksum: %s", r.error)
+	}
+	if r.checksum.Index != 5 || fmt.Sprint(r.checksum.Tables) != fmt.Sprint(exp) {
+		t.Fatalf("wrong checksum, exp %v, got %+v", exp, r.checksum)
+	}
+	if cs := s.LastChecksum(); cs != r.checksum {
+		t.Fatalf("wrong checksum of node: %+v", cs)
+	}
+
+	// A checksum is refused while the previous one is being taken.
+	s.checksumBusy = true
+	r = s.applyChecksum(6)
+	<-r.done
+	if r.error != ErrChecksumInProgress || r.checksum.Error == "" {
+		t.Fatalf("expected checksum to be refused, got %+v", r.checksum)
+	}
+	if cs := s.LastChecksum(); cs.Index != 6 {
+		t.Fatalf("wrong checksum of node: %+v", cs)
+	}
+	s.checksumBusy = false
+
+	r = s.applyChecksum(7)
+	<-r.done
+	if r.error != nil {
+		t.Fatalf("failed to take checksum: %s", r.error)
+	}
+	if fmt.Sprint(r.checksum.Tables) == fmt.Sprint(exp) {
+		t.Fatal("checksum did not change after write")
+	}
+}
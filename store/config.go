@@ -4,6 +4,7 @@ package store
 type DBConfig struct {
 	DSN    string // Any custom DSN
 	Memory bool   // Whether the database is in-memory only.
+	WAL    bool   // Whether an on-disk database runs in WAL mode, enabling incremental snapshots.
 }
 
 // NewDBConfig returns a new DB config instance.
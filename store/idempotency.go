@@ -0,0 +1,238 @@
+package store
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/minghsu0107/tqlite/command"
+	sql "github.com/minghsu0107/tqlite/db"
+	"google.golang.org/protobuf/proto"
+)
+
+// idempotencyTable is the table in which the results of execute requests
+// with idempotency keys are kept. As it is part of the database, it is
+// replicated and snapshotted along with everything else. Being an internal
+// table, requests may not change it, and it is left out of dumps.
+const idempotencyTable = sql.InternalTablePrefix + "idempotency"
+
+var idempotencySchema = []string{
+	`CREATE TABLE IF NOT EXISTS ` + idempotencyTable + ` (
+		key TEXT PRIMARY KEY,
+		request_hash BLOB NOT NULL,
+		results BLOB,
+		error TEXT NOT NULL,
+		precondition_failed INTEGER NOT NULL,
+		expires INTEGER NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS ` + idempotencyTable + `_expires ON ` + idempotencyTable + ` (expires)`,
+}
+
+// applyIdempotentExecute applies an execute request which has an idempotency
+// key. If a request with the same key was applied, and the key has not
+// expired, its results are returned and nothing is executed. Otherwise the
+// request is executed, and its results are kept until the key expires. The
+// request and the record of its results are committed together, so either
+// both take effect or neither does. Expiry is decided by the times carried
+// in the requests, so every node makes the same decision.
+func (s *Store) applyIdempotentExecute(er *command.ExecuteRequest) *fsmExecuteResponse {
+	tx, err := s.beginIdempotent(er)
+	if err != nil {
+		return &fsmExecuteResponse{error: err}
+	}
+	defer func() {
+		if tx != nil {
+			if err := tx.Rollback(); err != nil {
+				s.logger.Printf("failed to roll back request with idempotency key %s: %s", er.IdempotencyKey, err)
+			}
+		}
+	}()
+
+	r, err := lookupIdempotent(tx, er)
+	if err != nil {
+		return &fsmExecuteResponse{error: err}
+	}
+	if r == nil {
+		results, execErr := tx.Execute(er.Request, er.Timings)
+		if execErr != nil || (er.Request.Transaction && failed(results)) {
+			// None of the request may take effect, so only its outcome is
+			// recorded.
+			err := tx.Rollback()
+			tx = nil
+			if err != nil {
+				return &fsmExecuteResponse{error: fmt.Errorf("idempotency table: %s", err)}
+			}
+			if tx, err = s.beginIdempotent(er); err != nil {
+				return &fsmExecuteResponse{error: err}
+			}
+		}
+		if err := recordIdempotent(tx, er, results, execErr); err != nil {
+			return &fsmExecuteResponse{error: err}
+		}
+		r = &fsmExecuteResponse{results: results, error: execErr}
+	}
+
+	err = tx.Commit()
+	tx = nil
+	if err != nil {
+		return &fsmExecuteResponse{error: fmt.Errorf("idempotency table: %s", err)}
+	}
+	return r
+}
+
+// beginIdempotent begins a transaction in which to apply er, creating the
+// idempotency table if it does not exist, and deleting expired keys.
+func (s *Store) beginIdempotent(er *command.ExecuteRequest) (*sql.Tx, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("idempotency table: %s", err)
+	}
+	for _, stmt := range idempotencySchema {
+		if err := execIdempotency(tx, stmt); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	if err := execIdempotency(tx, `DELETE FROM `+idempotencyTable+` WHERE expires <= ?`,
+		int64Param(er.ReceivedAt)); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return tx, nil
+}
+
+// lookupIdempotent returns the recorded response to the request with the
+// idempotency key of er, or nil if there is none.
+func lookupIdempotent(tx *sql.Tx, er *command.ExecuteRequest) (*fsmExecuteResponse, error) {
+	rows, err := tx.Query(internalRequest(
+		`SELECT request_hash, results, error, precondition_failed FROM `+idempotencyTable+` WHERE key = ?`,
+		stringParam(er.IdempotencyKey)), false)
+	if err != nil {
+		return nil, fmt.Errorf("idempotency key lookup: %s", err)
+	}
+	if rows[0].Error != "" {
+		return nil, fmt.Errorf("idempotency key lookup: %s", rows[0].Error)
+	}
+	if len(rows[0].Values) == 0 {
+		return nil, nil
+	}
+
+	stats.Add(numIdempotentReplays, 1)
+	row := rows[0].Values[0]
+	if h, _ := row[0].([]byte); !bytes.Equal(h, er.RequestHash) {
+		return nil, fmt.Errorf("%w: %s", ErrIdempotencyKeyReused, er.IdempotencyKey)
+	}
+	var results []*sql.Result
+	if b, _ := row[1].([]byte); b != nil {
+		if err := json.Unmarshal(b, &results); err != nil {
+			return nil, fmt.Errorf("idempotency key results: %s", err)
+		}
+	}
+	var execErr error
+	if e, _ := row[2].(string); e != "" {
+		execErr = errors.New(e)
+		if failed, _ := row[3].(int64); failed != 0 {
+			// A retry must fail as a failed precondition too.
+			execErr = &recordedError{msg: e, err: sql.ErrPreconditionFailed}
+		}
+	}
+	return &fsmExecuteResponse{results: results, error: execErr}, nil
+}
+
+// recordIdempotent records the results of er in tx, so they are returned
+// should er be retried.
+func recordIdempotent(tx *sql.Tx, er *command.ExecuteRequest, results []*sql.Result, execErr error) error {
+	b, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+	var e string
+	var preconditionFailed int64
+	if execErr != nil {
+		e = execErr.Error()
+		if errors.Is(execErr, sql.ErrPreconditionFailed) {
+			preconditionFailed = 1
+		}
+	}
+	if err := execIdempotency(tx, `INSERT INTO `+idempotencyTable+` VALUES(?, ?, ?, ?, ?, ?)`,
+		stringParam(er.IdempotencyKey), bytesParam(er.RequestHash), bytesParam(b), stringParam(e),
+		int64Param(preconditionFailed), int64Param(er.ReceivedAt+er.IdempotencyRetention)); err != nil {
+		return fmt.Errorf("record results of idempotency key %s: %s", er.IdempotencyKey, err)
+	}
+	return nil
+}
+
+// recordedError is an error recorded along with the results of a request, so
+// that a retry of the request fails with an error which is the same as far as
+// errors.Is is concerned.
+type recordedError struct {
+	msg string
+	err error
+}
+
+func (e *recordedError) Error() string {
+	return e.msg
+}
+
+func (e *recordedError) Unwrap() error {
+	return e.err
+}
+
+// failed returns whether any of results is an error.
+func failed(results []*sql.Result) bool {
+	for _, r := range results {
+		if r.Error != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// execIdempotency executes a statement on the idempotency table in tx.
+func execIdempotency(tx *sql.Tx, stmt string, params ...*command.Parameter) error {
+	r, err := tx.ExecuteInternal(internalRequest(stmt, params...))
+	if err != nil {
+		return fmt.Errorf("idempotency table: %s", err)
+	}
+	if r[0].Error != "" {
+		return fmt.Errorf("idempotency table: %s", r[0].Error)
+	}
+	return nil
+}
+
+func internalRequest(stmt string, params ...*command.Parameter) *command.Request {
+	return &command.Request{
+		Statements: []*command.Statement{
+			{
+				Sql:        stmt,
+				Parameters: params,
+			},
+		},
+	}
+}
+
+func stringParam(v string) *command.Parameter {
+	return &command.Parameter{Value: &command.Parameter_S{S: v}}
+}
+
+func int64Param(v int64) *command.Parameter {
+	return &command.Parameter{Value: &command.Parameter_I{I: v}}
+}
+
+func bytesParam(v []byte) *command.Parameter {
+	return &command.Parameter{Value: &command.Parameter_Y{Y: v}}
+}
+
+// requestHash returns a hash of the statements and preconditions of r, so
+// that reuse of an idempotency key for a different request is detected. It
+// must be taken before a seed and time are bound to r.
+func requestHash(r *command.Request) ([]byte, error) {
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.Sum256(b)
+	return h[:], nil
+}
@@ -0,0 +1,225 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/minghsu0107/tqlite/command"
+	sql "github.com/minghsu0107/tqlite/db"
+)
+
+func Test_ApplyIdempotentExecute(t *testing.T) {
+	s := mustNewIdempotencyStore(t)
+	mustExecute(t, s, "CREATE TABLE foo (id INTEGER NOT NULL PRIMARY KEY, name TEXT)")
+
+	now := time.Now().UnixNano()
+	er := idempotentRequest("key1", now, false, `INSERT INTO foo(name) VALUES("fiona")`)
+	r := s.applyIdempotentExecute(er)
+	if r.error != nil {
+		t.Fatalf("failed to apply request: %s", r.error)
+	}
+	if len(r.results) != 1 || r.results[0].LastInsertID != 1 {
+		t.Fatalf("wrong results: %+v", r.results)
+	}
+
+	// A retry returns the recorded results, without executing again.
+	r = s.applyIdempotentExecute(idempotentRequest("key1", now+1, false, `INSERT INTO foo(name) VALUES("fiona")`))
+	if r.error != nil {
+		t.Fatalf("failed to apply retried request: %s", r.error)
+	}
+	if len(r.results) != 1 || r.results[0].LastInsertID != 1 {
+		t.Fatalf("wrong results for retried request: %+v", r.results)
+	}
+	mustQueryEqual(t, s, "SELECT COUNT(*) FROM foo", "[[1]]")
+
+	// A different request with the same key is refused.
+	other := idempotentRequest("key1", now+2, false, `INSERT INTO foo(name) VALUES("declan")`)
+	other.RequestHash = []byte("other")
+	if r := s.applyIdempotentExecute(other); !errors.Is(r.error, ErrIdempotencyKeyReused) {
+		t.Fatalf("reused key not refused: %v", r.error)
+	}
+
+	// Once expired, the key may be used again.
+	r = s.applyIdempotentExecute(idempotentRequest("key1", now+int64(2*time.Hour), false,
+		`INSERT INTO foo(name) VALUES("fiona")`))
+	if r.error != nil || r.results[0].LastInsertID != 2 {
+		t.Fatalf("expired key not executed: %+v, %v", r.results, r.error)
+	}
+}
+
+func Test_ApplyIdempotentExecuteFailedTransaction(t *testing.T) {
+	s := mustNewIdempotencyStore(t)
+	mustExecute(t, s, "CREATE TABLE foo (id INTEGER NOT NULL PRIMARY KEY, name TEXT)")
+
+	// A failed transaction makes no changes, but its results are recorded.
+	// As without an idempotency key, it stops at the first error.
+	now := time.Now().UnixNano()
+	er := idempotentRequest("key1", now, true,
+		`INSERT INTO foo(id, name) VALUES(1, "fiona")`,
+		`INSERT INTO foo(id, name) VALUES(1, "declan")`,
+		`INSERT INTO foo(id, name) VALUES(2, "sinead")`)
+	r := s.applyIdempotentExecute(er)
+	if r.error != nil {
+		t.Fatalf("failed to apply request: %s", r.error)
+	}
+	if len(r.results) != 2 || r.results[1].Error == "" {
+		t.Fatalf("expected second statement to fail: %+v", r.results)
+	}
+	mustQueryEqual(t, s, "SELECT COUNT(*) FROM foo", "[[0]]")
+	mustQueryEqual(t, s, "SELECT COUNT(*) FROM "+idempotencyTable, "[[1]]")
+
+	r = s.applyIdempotentExecute(idempotentRequest("key1", now+1, true,
+		`INSERT INTO foo(id, name) VALUES(1, "fiona")`,
+		`INSERT INTO foo(id, name) VALUES(1, "declan")`,
+		`INSERT INTO foo(id, name) VALUES(2, "sinead")`))
+	if len(r.results) != 2 || r.results[1].Error == "" {
+		t.Fatalf("wrong results for retried request: %+v", r.results)
+	}
+	mustQueryEqual(t, s, "SELECT COUNT(*) FROM foo", "[[0]]")
+}
+
+func Test_ApplyIdempotentExecutePreconditionFailed(t *testing.T) {
+	s := mustNewIdempotencyStore(t)
+	mustExecute(t, s, "CREATE TABLE foo (id INTEGER NOT NULL PRIMARY KEY, name TEXT)")
+
+	// A retry of a request whose precondition failed fails the same way.
+	now := time.Now().UnixNano()
+	for i := int64(0); i < 2; i++ {
+		er := idempotentRequest("key1", now+i, false, `INSERT INTO foo(name) VALUES("fiona")`)
+		er.Request.Preconditions = []*command.Precondition{{
+			Type:      command.Precondition_PRECONDITION_TYPE_ROWS,
+			Statement: &command.Statement{Sql: "SELECT * FROM foo"},
+			Rows:      1,
+		}}
+		r := s.applyIdempotentExecute(er)
+		if !errors.Is(r.error, sql.ErrPreconditionFailed) {
+			t.Fatalf("expected precondition to fail, got %v", r.error)
+		}
+	}
+	mustQueryEqual(t, s, "SELECT COUNT(*) FROM foo", "[[0]]")
+}
+
+func Test_ApplyIdempotentExecuteNotRecorded(t *testing.T) {
+	s := mustNewIdempotencyStore(t)
+	mustExecute(t, s, "CREATE TABLE foo (id INTEGER NOT NULL PRIMARY KEY, name TEXT)")
+
+	// Statements which end the transaction are denied, so cannot commit the
+	// request without its record.
+	r := s.applyIdempotentExecute(idempotentRequest("key1", time.Now().UnixNano(), false,
+		`INSERT INTO foo(name) VALUES("fiona")`, `COMMIT`))
+	if r.error != nil {
+		t.Fatalf("failed to apply request: %s", r.error)
+	}
+	if len(r.results) != 2 || r.results[1].Error == "" {
+		t.Fatalf("expected COMMIT to be denied: %+v", r.results)
+	}
+	mustQueryEqual(t, s, "SELECT COUNT(*) FROM "+idempotencyTable, "[[1]]")
+
+	// Without a record, nothing the request did remains.
+	mustExecute(t, s, "CREATE TABLE bar (id INTEGER)")
+	tx, err := s.db.Begin()
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %s", err)
+	}
+	if _, err := tx.ExecuteInternal(internalRequest("DROP TABLE " + idempotencyTable)); err != nil {
+		t.Fatalf("failed to drop idempotency table: %s", err)
+	}
+	if _, err := tx.ExecuteInternal(internalRequest("CREATE VIEW " + idempotencyTable + " AS SELECT 1")); err != nil {
+		t.Fatalf("failed to create view: %s", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("failed to commit: %s", err)
+	}
+	r = s.applyIdempotentExecute(idempotentRequest("key2", time.Now().UnixNano(), false,
+		`INSERT INTO bar VALUES(1)`))
+	if r.error == nil {
+		t.Fatal("request succeeded without its results being recorded")
+	}
+	mustQueryEqual(t, s, "SELECT COUNT(*) FROM bar", "[[0]]")
+}
+
+func Test_ApplyIdempotentExecuteBound(t *testing.T) {
+	s1, s2 := mustNewIdempotencyStore(t), mustNewIdempotencyStore(t)
+	s1.DeterministicSQL = true
+
+	// Every node applying a bound request gets the same data.
+	now := time.Now().UnixNano()
+	er := idempotentRequest("key1", now, true,
+		"CREATE TABLE foo (id INTEGER NOT NULL PRIMARY KEY, r INTEGER, at TEXT DEFAULT CURRENT_TIMESTAMP)",
+		"INSERT INTO foo(r) VALUES(random())")
+	if err := s1.bindRequest(er.Request); err != nil {
+		t.Fatalf("failed to bind request: %s", err)
+	}
+	for _, s := range []*Store{s1, s2} {
+		if r := s.applyIdempotentExecute(er); r.error != nil {
+			t.Fatalf("failed to apply request: %s", r.error)
+		}
+	}
+	q := "SELECT r, at FROM foo"
+	r1, _ := s1.db.QueryStringStmt(q)
+	mustQueryEqual(t, s2, q, fmt.Sprint(r1[0].Values))
+
+	// A retry is bound to a different seed and time, but is recognized.
+	retry := idempotentRequest("key1", now+1, true, er.Request.Statements[0].Sql, er.Request.Statements[1].Sql)
+	if err := s1.bindRequest(retry.Request); err != nil {
+		t.Fatalf("failed to bind request: %s", err)
+	}
+	if retry.Request.RandomSeed == er.Request.RandomSeed {
+		t.Fatal("retry bound to the same seed")
+	}
+	if r := s1.applyIdempotentExecute(retry); r.error != nil {
+		t.Fatalf("failed to apply retried request: %s", r.error)
+	}
+	mustQueryEqual(t, s1, "SELECT COUNT(*) FROM foo", "[[1]]")
+}
+
+func mustNewIdempotencyStore(t *testing.T) *Store {
+	return mustOpenDBStore(t, filepath.Join(t.TempDir(), sqliteFile))
+}
+
+// mustOpenDBStore returns a store which has only the database at path, for
+// testing what the store applies to it.
+func mustOpenDBStore(t *testing.T, path string) *Store {
+	db, err := sql.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open database: %s", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return &Store{
+		db:     db,
+		logger: log.New(ioutil.Discard, "", 0),
+	}
+}
+
+func idempotentRequest(key string, receivedAt int64, tx bool, stmts ...string) *command.ExecuteRequest {
+	r := &command.Request{Transaction: tx}
+	for _, s := range stmts {
+		r.Statements = append(r.Statements, &command.Statement{Sql: s})
+	}
+	h, _ := requestHash(r)
+	return &command.ExecuteRequest{
+		Request:              r,
+		IdempotencyKey:       key,
+		ReceivedAt:           receivedAt,
+		IdempotencyRetention: int64(time.Hour),
+		RequestHash:          h,
+	}
+}
+
+func mustQueryEqual(t *testing.T, s *Store, query, exp string) {
+	r, err := s.db.QueryStringStmt(query)
+	if err != nil {
+		t.Fatalf("failed to query %s: %s", query, err)
+	}
+	if r[0].Error != "" {
+		t.Fatalf("failed to query %s: %s", query, r[0].Error)
+	}
+	if got := fmt.Sprint(r[0].Values); got != exp {
+		t.Fatalf("wrong result for %s, exp %s, got %s", query, exp, got)
+	}
+}
@@ -0,0 +1,196 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/minghsu0107/tqlite/command"
+	sql "github.com/minghsu0107/tqlite/db"
+)
+
+// loadsTable is the table in which the progress of chunked loads is kept.
+// Progress is written in the same transaction as the chunk it records, so
+// a chunk is applied exactly once, and as the table is part of the database,
+// progress survives restarts and is carried by snapshots. Only the progress
+// of the most recent loads is kept.
+const loadsTable = sql.InternalTablePrefix + "loads"
+
+var loadsSchema = []string{
+	`CREATE TABLE IF NOT EXISTS ` + loadsTable + ` (
+		id TEXT PRIMARY KEY,
+		load_offset INTEGER NOT NULL,
+		chunks INTEGER NOT NULL,
+		statements INTEGER NOT NULL,
+		done INTEGER NOT NULL
+	)`,
+}
+
+// loadChunk executes the statements of a chunk of a load, if the chunk starts
+// where the load has reached, and records the progress of the load. Chunks
+// which are not to be applied in a transaction, such as pragmas, are executed
+// before their progress is recorded.
+func (s *Store) loadChunk(lcr *command.LoadChunkRequest) *fsmLoadChunkResponse {
+	stmts := lcr.Request.GetStatements()
+	tx, err := s.beginLoad()
+	if err != nil {
+		return &fsmLoadChunkResponse{progress: LoadProgress{ID: lcr.LoadId}, error: err}
+	}
+	defer func() {
+		if tx != nil {
+			if err := tx.Rollback(); err != nil {
+				s.logger.Printf("failed to roll back chunk of load %s: %s", lcr.LoadId, err)
+			}
+		}
+	}()
+
+	p, ok, err := lookupLoad(tx.Query, lcr.LoadId)
+	if err != nil {
+		return &fsmLoadChunkResponse{progress: LoadProgress{ID: lcr.LoadId}, error: err}
+	}
+	if !ok && lcr.Offset != 0 {
+		return &fsmLoadChunkResponse{
+			progress: p,
+			error:    fmt.Errorf("%s: %s", ErrLoadNotFound, lcr.LoadId),
+		}
+	}
+	if p.Done || lcr.Offset != p.Offset {
+		return &fsmLoadChunkResponse{
+			progress: p,
+			error: fmt.Errorf("%s: chunk starts at offset %d, load %s is at offset %d",
+				ErrLoadOffset, lcr.Offset, p.ID, p.Offset),
+		}
+	}
+
+	if len(stmts) > 0 {
+		var results []*sql.Result
+		if lcr.Request.Transaction {
+			results, err = tx.Execute(lcr.Request, false)
+		} else {
+			err = tx.Rollback()
+			tx = nil
+			if err == nil {
+				results, err = s.db.Execute(lcr.Request, false)
+			}
+			if err == nil {
+				tx, err = s.beginLoad()
+			}
+		}
+		if err != nil {
+			return &fsmLoadChunkResponse{progress: p, error: err}
+		}
+		for i := range results {
+			if results[i].Error != "" {
+				return &fsmLoadChunkResponse{
+					progress: p,
+					error: fmt.Errorf("statement %d of load %s: %s",
+						p.Statements+uint64(i)+1, p.ID, results[i].Error),
+				}
+			}
+		}
+	}
+
+	p.Offset = lcr.NextOffset
+	p.Chunks++
+	p.Statements += uint64(len(stmts))
+	p.Done = lcr.Last
+	if err := recordLoad(tx, p, !ok); err != nil {
+		return &fsmLoadChunkResponse{progress: p, error: err}
+	}
+	err = tx.Commit()
+	tx = nil
+	if err != nil {
+		return &fsmLoadChunkResponse{progress: p, error: fmt.Errorf("loads table: %s", err)}
+	}
+	return &fsmLoadChunkResponse{progress: p}
+}
+
+// LoadProgress returns the progress of the load with the given ID, as applied
+// by this node. Progress is kept only for the most recent loads. It returns
+// false if the load is not known.
+func (s *Store) LoadProgress(id string) (LoadProgress, bool) {
+	s.queryMu.RLock()
+	defer s.queryMu.RUnlock()
+	p, ok, err := lookupLoad(s.db.Query, id)
+	if err != nil {
+		s.logger.Printf("failed to read progress of load %s: %s", id, err)
+		return LoadProgress{}, false
+	}
+	return p, ok
+}
+
+// beginLoad begins a transaction in which to apply a chunk of a load,
+// creating the loads table if it does not exist.
+func (s *Store) beginLoad() (*sql.Tx, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("loads table: %s", err)
+	}
+	for _, stmt := range loadsSchema {
+		if err := execLoads(tx, stmt); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	return tx, nil
+}
+
+// lookupLoad returns the progress of the load with the given ID, read with
+// query, and whether the load is known.
+func lookupLoad(query func(*command.Request, bool) ([]*sql.Rows, error), id string) (LoadProgress, bool, error) {
+	p := LoadProgress{ID: id}
+	rows, err := query(internalRequest(
+		`SELECT load_offset, chunks, statements, done FROM `+loadsTable+` WHERE id = ?`,
+		stringParam(id)), false)
+	if err != nil {
+		return p, false, fmt.Errorf("load lookup: %s", err)
+	}
+	if rows[0].Error != "" {
+		if strings.HasPrefix(rows[0].Error, "no such table") {
+			// No load has been applied.
+			return p, false, nil
+		}
+		return p, false, fmt.Errorf("load lookup: %s", rows[0].Error)
+	}
+	if len(rows[0].Values) == 0 {
+		return p, false, nil
+	}
+
+	row := rows[0].Values[0]
+	offset, _ := row[0].(int64)
+	chunks, _ := row[1].(int64)
+	statements, _ := row[2].(int64)
+	done, _ := row[3].(int64)
+	p.Offset, p.Chunks, p.Statements, p.Done = uint64(offset), uint64(chunks), uint64(statements), done != 0
+	return p, true, nil
+}
+
+// recordLoad records p in tx. If the load is new, the progress of all but
+// the most recent loads is deleted.
+func recordLoad(tx *sql.Tx, p LoadProgress, isNew bool) error {
+	var done int64
+	if p.Done {
+		done = 1
+	}
+	if err := execLoads(tx, `INSERT OR REPLACE INTO `+loadsTable+` VALUES(?, ?, ?, ?, ?)`,
+		stringParam(p.ID), int64Param(int64(p.Offset)), int64Param(int64(p.Chunks)),
+		int64Param(int64(p.Statements)), int64Param(done)); err != nil {
+		return fmt.Errorf("record progress of load %s: %s", p.ID, err)
+	}
+	if !isNew {
+		return nil
+	}
+	return execLoads(tx, `DELETE FROM `+loadsTable+` WHERE rowid NOT IN
+		(SELECT rowid FROM `+loadsTable+` ORDER BY rowid DESC LIMIT ?)`, int64Param(maxLoads))
+}
+
+// execLoads executes a statement on the loads table in tx.
+func execLoads(tx *sql.Tx, stmt string, params ...*command.Parameter) error {
+	r, err := tx.ExecuteInternal(internalRequest(stmt, params...))
+	if err != nil {
+		return fmt.Errorf("loads table: %s", err)
+	}
+	if r[0].Error != "" {
+		return fmt.Errorf("loads table: %s", r[0].Error)
+	}
+	return nil
+}
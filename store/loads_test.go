@@ -0,0 +1,102 @@
+package store
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/minghsu0107/tqlite/command"
+)
+
+func Test_LoadChunk(t *testing.T) {
+	path := filepath.Join(t.TempDir(), sqliteFile)
+	s := mustOpenDBStore(t, path)
+
+	if _, ok := s.LoadProgress("load1"); ok {
+		t.Fatal("progress of unknown load returned")
+	}
+	// A load must start at its beginning.
+	if r := s.loadChunk(loadChunkRequest("load1", 10, 20, false, true)); r.error == nil {
+		t.Fatal("chunk of unknown load applied")
+	}
+
+	r := s.loadChunk(loadChunkRequest("load1", 0, 10, false, false, "PRAGMA foreign_keys=ON"))
+	if r.error != nil {
+		t.Fatalf("failed to apply pragma chunk: %s", r.error)
+	}
+	r = s.loadChunk(loadChunkRequest("load1", 10, 20, false, true,
+		"CREATE TABLE foo (id INTEGER NOT NULL PRIMARY KEY, name TEXT)",
+		`INSERT INTO foo(id, name) VALUES(1, "fiona")`))
+	if r.error != nil {
+		t.Fatalf("failed to apply chunk: %s", r.error)
+	}
+	exp := LoadProgress{ID: "load1", Offset: 20, Chunks: 2, Statements: 3}
+	if r.progress != exp {
+		t.Fatalf("wrong progress, exp %+v, got %+v", exp, r.progress)
+	}
+
+	// A chunk is never applied twice.
+	if r := s.loadChunk(loadChunkRequest("load1", 10, 20, false, true,
+		`INSERT INTO foo(name) VALUES("fiona")`)); r.error == nil {
+		t.Fatal("chunk applied twice")
+	}
+
+	// A failed chunk makes no changes, and does not advance the load.
+	r = s.loadChunk(loadChunkRequest("load1", 20, 30, false, true,
+		`INSERT INTO foo(id, name) VALUES(2, "declan")`,
+		`INSERT INTO foo(id, name) VALUES(1, "aoife")`))
+	if r.error == nil {
+		t.Fatal("failed chunk succeeded")
+	}
+	mustQueryEqual(t, s, "SELECT COUNT(*) FROM foo", "[[1]]")
+	if p, _ := s.LoadProgress("load1"); p != exp {
+		t.Fatalf("failed chunk changed progress, exp %+v, got %+v", exp, p)
+	}
+
+	// Progress is part of the database, so the load continues when the
+	// database is reopened, as it is on restart or restore.
+	s.db.Close()
+	s = mustOpenDBStore(t, path)
+	if p, ok := s.LoadProgress("load1"); !ok || p != exp {
+		t.Fatalf("progress lost on reopen, exp %+v, got %+v", exp, p)
+	}
+	r = s.loadChunk(loadChunkRequest("load1", 20, 30, true, true,
+		`INSERT INTO foo(id, name) VALUES(2, "declan")`))
+	if r.error != nil {
+		t.Fatalf("failed to apply chunk after reopen: %s", r.error)
+	}
+	if !r.progress.Done || r.progress.Offset != 30 {
+		t.Fatalf("wrong progress after last chunk: %+v", r.progress)
+	}
+	if r := s.loadChunk(loadChunkRequest("load1", 30, 40, true, true, `INSERT INTO foo(id) VALUES(3)`)); r.error == nil {
+		t.Fatal("chunk applied to finished load")
+	}
+	mustQueryEqual(t, s, "SELECT COUNT(*) FROM foo", "[[2]]")
+}
+
+func Test_LoadChunkPrune(t *testing.T) {
+	s := mustOpenDBStore(t, filepath.Join(t.TempDir(), sqliteFile))
+	for i := 0; i < maxLoads+5; i++ {
+		if r := s.loadChunk(loadChunkRequest(fmt.Sprintf("load%d", i), 0, 1, true, true)); r.error != nil {
+			t.Fatalf("failed to apply chunk: %s", r.error)
+		}
+	}
+	mustQueryEqual(t, s, "SELECT COUNT(*) FROM "+loadsTable, fmt.Sprintf("[[%d]]", maxLoads))
+	if _, ok := s.LoadProgress("load0"); ok {
+		t.Fatal("progress of oldest load kept")
+	}
+}
+
+func loadChunkRequest(id string, offset, next uint64, last, tx bool, stmts ...string) *command.LoadChunkRequest {
+	r := &command.Request{Transaction: tx}
+	for _, s := range stmts {
+		r.Statements = append(r.Statements, &command.Statement{Sql: s})
+	}
+	return &command.LoadChunkRequest{
+		Request:    r,
+		LoadId:     id,
+		Offset:     offset,
+		NextOffset: next,
+		Last:       last,
+	}
+}
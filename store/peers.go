@@ -0,0 +1,174 @@
+package store
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+const (
+	peersJSONFile = "peers.json"
+	peersYAMLFile = "peers.yaml"
+)
+
+// peersFile returns the path of the peers file in dir, or an empty string if
+// there is none. Only one of peers.json and peers.yaml may exist.
+func peersFile(dir string) (string, error) {
+	jsonPath := filepath.Join(dir, peersJSONFile)
+	yamlPath := filepath.Join(dir, peersYAMLFile)
+	switch {
+	case pathExists(jsonPath) && pathExists(yamlPath):
+		return "", fmt.Errorf("both %s and %s exist", jsonPath, yamlPath)
+	case pathExists(jsonPath):
+		return jsonPath, nil
+	case pathExists(yamlPath):
+		return yamlPath, nil
+	default:
+		return "", nil
+	}
+}
+
+// readPeersFile reads the Raft configuration in the peers file at path. A
+// peers.json file has the format Raft reads. A peers.yaml file holds the same
+// entries as a YAML sequence of mappings:
+//
+//   - id: "1"
+//     address: "10.0.0.1:4002"
+//     non_voter: false
+//
+// Only this form is accepted: one key per line, with scalar values.
+func readPeersFile(path string) (raft.Configuration, error) {
+	if filepath.Ext(path) == ".json" {
+		return raft.ReadConfigJSON(path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return raft.Configuration{}, err
+	}
+	defer f.Close()
+
+	var config raft.Configuration
+	var srv *raft.Server
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "-") {
+			config.Servers = append(config.Servers, raft.Server{Suffrage: raft.Voter})
+			srv = &config.Servers[len(config.Servers)-1]
+			line = strings.TrimSpace(line[1:])
+			if line == "" {
+				continue
+			}
+		}
+		if srv == nil {
+			return raft.Configuration{}, fmt.Errorf("line %d: expected list entry", n)
+		}
+
+		kv := strings.SplitN(line, ":", 2)
+		if len(kv) != 2 {
+			return raft.Configuration{}, fmt.Errorf("line %d: expected key: value", n)
+		}
+		value, err := yamlScalar(kv[1])
+		if err != nil {
+			return raft.Configuration{}, fmt.Errorf("line %d: %s", n, err)
+		}
+		switch strings.TrimSpace(kv[0]) {
+		case "id":
+			srv.ID = raft.ServerID(value)
+		case "address":
+			srv.Address = raft.ServerAddress(value)
+		case "non_voter":
+			nonVoter, err := strconv.ParseBool(value)
+			if err != nil {
+				return raft.Configuration{}, fmt.Errorf("line %d: invalid non_voter value %q", n, value)
+			}
+			if nonVoter {
+				srv.Suffrage = raft.Nonvoter
+			}
+		default:
+			return raft.Configuration{}, fmt.Errorf("line %d: unknown key %q", n, strings.TrimSpace(kv[0]))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return raft.Configuration{}, err
+	}
+	return config, nil
+}
+
+// yamlScalar returns the value of the YAML scalar s, which may be quoted.
+func yamlScalar(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(s, `"`):
+		return strconv.Unquote(s)
+	case strings.HasPrefix(s, "'"):
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return "", fmt.Errorf("unterminated string %s", s)
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	return s, nil
+}
+
+// recover forces the Raft configuration of the node to the one in the peers
+// file in the Raft directory, if there is one, and removes the file. This
+// allows a cluster which has permanently lost quorum to be restarted with the
+// surviving nodes. It must be called before Raft starts. s.db is used to
+// replay the log, and is closed afterwards.
+func (s *Store) recover(config *raft.Config, snapshots raft.SnapshotStore) error {
+	path, err := peersFile(s.raftDir)
+	if err != nil || path == "" {
+		return err
+	}
+
+	s.logger.Printf("attempting node recovery using %s", path)
+	peers, err := readPeersFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read peers file %s: %s", path, err)
+	}
+
+	s.db, err = s.openInMemory("")
+	if err != nil {
+		return fmt.Errorf("failed to open in-memory database: %s", err)
+	}
+	err = raft.RecoverCluster(config, s, s.boltStore, s.boltStore, snapshots, s.raftTn, peers)
+	if cerr := s.db.Close(); cerr != nil && err == nil {
+		err = cerr
+	}
+	s.db = nil
+	if err != nil {
+		return fmt.Errorf("failed to recover node: %s", err)
+	}
+
+	// Raft does not release the snapshot recovery created, so remove the
+	// database copy it was taken from.
+	if err := s.removeTmpFiles(); err != nil {
+		return fmt.Errorf("remove temporary files: %s", err)
+	}
+
+	// Raft restores the database from the snapshot recovery created, so
+	// start afresh.
+	s.onDiskCreated = false
+	s.firstLogAppliedT = time.Time{}
+	s.requireFullSnapshot()
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove peers file %s: %s", path, err)
+	}
+	stats.Add(numRecoveries, 1)
+	s.logger.Printf("node recovered successfully using %s, with %d nodes", path, len(peers.Servers))
+	return nil
+}
@@ -1,5 +1,7 @@
 package store
 
+import "time"
+
 // Server represents another node in the cluster.
 type Server struct {
 	ID       string `json:"id,omitempty"`
@@ -13,3 +15,33 @@ type Servers []*Server
 func (s Servers) Less(i, j int) bool { return s[i].ID < s[j].ID }
 func (s Servers) Len() int           { return len(s) }
 func (s Servers) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// NodeStatus is a summary of the state of a node.
+type NodeStatus struct {
+	ID           string
+	Addr         string
+	State        string
+	Term         uint64
+	CommitIndex  uint64
+	AppliedIndex uint64
+	LastIndex    uint64
+	LastContact  time.Time // Time this node last heard from the leader, zero if never or if leader.
+	DBSize       int64
+	Snapshots    int       // Number of snapshots retained.
+	Checksum     *Checksum // Most recent checksum of the database, nil if none.
+
+	// Set by the cluster service, as the store does not know them.
+	APIAddr string
+	Version string
+}
+
+// Readiness is the state of a node which determines whether it can serve
+// requests.
+type Readiness struct {
+	LeaderAddr   string    // Raft address of the leader, empty if no leader is known.
+	IsLeader     bool      // Whether this node is the leader.
+	LogsApplied  bool      // Whether the log entries present when the node opened are applied.
+	CommitIndex  uint64    // Index of the last log entry this node knows is committed.
+	AppliedIndex uint64    // Index of the last log entry this node applied.
+	LastContact  time.Time // Time this node last heard from the leader, zero if never or if leader.
+}
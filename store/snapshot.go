@@ -0,0 +1,159 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/hashicorp/raft"
+)
+
+const (
+	// walSnapshotMarker flags snapshot data holding only the WAL frames written
+	// since the previous snapshot, instead of a complete database.
+	walSnapshotMarker = math.MaxUint64 - 1
+
+	// chainSnapshotMarker flags snapshot data made of a complete snapshot
+	// followed by the WAL snapshots taken on top of it.
+	chainSnapshotMarker = math.MaxUint64 - 2
+)
+
+// snapshotStore is a Raft snapshot store which understands WAL snapshots. A
+// WAL snapshot is stored as-is, but is opened as a chain starting at the most
+// recent complete snapshot preceding it. This way anything read from the
+// store, including snapshots sent to other nodes, always holds the entire
+// database.
+type snapshotStore struct {
+	*raft.FileSnapshotStore
+}
+
+// newSnapshotStore returns a snapshot store in dir, retaining the given number
+// of snapshots.
+func newSnapshotStore(dir string, retain int, logOutput io.Writer) (*snapshotStore, error) {
+	fss, err := raft.NewFileSnapshotStore(dir, retain, logOutput)
+	if err != nil {
+		return nil, err
+	}
+	return &snapshotStore{FileSnapshotStore: fss}, nil
+}
+
+// Open opens the snapshot with the given ID. If that is a WAL snapshot, the
+// returned data is a chain of the complete snapshot it builds on, followed by
+// every WAL snapshot up to and including the requested one.
+func (s *snapshotStore) Open(id string) (*raft.SnapshotMeta, io.ReadCloser, error) {
+	meta, rc, marker, err := s.open(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if marker != walSnapshotMarker {
+		return meta, rc, nil
+	}
+
+	rcs := []io.ReadCloser{rc}
+	closeAll := func() {
+		for _, rc := range rcs {
+			rc.Close()
+		}
+	}
+
+	snaps, err := s.List()
+	if err != nil {
+		closeAll()
+		return nil, nil, err
+	}
+	pos := -1
+	for i := range snaps {
+		if snaps[i].ID == id {
+			pos = i
+			break
+		}
+	}
+	if pos == -1 {
+		closeAll()
+		return nil, nil, fmt.Errorf("snapshot %s not retained", id)
+	}
+
+	// Snapshots are listed newest first, so walk back in time until the
+	// complete snapshot is found.
+	size := meta.Size
+	for i := pos + 1; ; i++ {
+		if i == len(snaps) {
+			closeAll()
+			return nil, nil, fmt.Errorf("no complete snapshot precedes snapshot %s", id)
+		}
+		m, rc, marker, err := s.open(snaps[i].ID)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		rcs = append([]io.ReadCloser{rc}, rcs...)
+		size += m.Size
+		if marker != walSnapshotMarker {
+			break
+		}
+	}
+
+	hdr := new(bytes.Buffer)
+	if err := writeUint64(hdr, chainSnapshotMarker); err != nil {
+		closeAll()
+		return nil, nil, err
+	}
+	if err := writeUint64(hdr, uint64(len(rcs))); err != nil {
+		closeAll()
+		return nil, nil, err
+	}
+
+	chainMeta := *meta
+	chainMeta.Size = size + int64(hdr.Len())
+	readers := []io.Reader{hdr}
+	for _, rc := range rcs {
+		readers = append(readers, rc)
+	}
+	return &chainMeta, &multiReadCloser{
+		Reader:  io.MultiReader(readers...),
+		closers: rcs,
+	}, nil
+}
+
+// open opens the snapshot with the given ID, and returns the marker at the
+// start of its data. The returned data still starts with that marker.
+func (s *snapshotStore) open(id string) (*raft.SnapshotMeta, io.ReadCloser, uint64, error) {
+	meta, rc, err := s.FileSnapshotStore.Open(id)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	b := make([]byte, 8)
+	if _, err := io.ReadFull(rc, b); err != nil {
+		rc.Close()
+		return nil, nil, 0, fmt.Errorf("read snapshot %s marker: %s", id, err)
+	}
+	marker, err := readUint64(b)
+	if err != nil {
+		rc.Close()
+		return nil, nil, 0, fmt.Errorf("read snapshot %s marker: %s", id, err)
+	}
+
+	return meta, &multiReadCloser{
+		Reader:  io.MultiReader(bytes.NewReader(b), rc),
+		closers: []io.ReadCloser{rc},
+	}, marker, nil
+}
+
+// multiReadCloser reads from Reader, and closes all closers when closed.
+type multiReadCloser struct {
+	io.Reader
+	closers []io.ReadCloser
+}
+
+// Close closes all underlying closers, returning the first error seen.
+func (m *multiReadCloser) Close() error {
+	var err error
+	for _, c := range m.closers {
+		if cerr := c.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
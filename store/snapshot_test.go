@@ -0,0 +1,277 @@
+package store
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	sql "github.com/minghsu0107/tqlite/db"
+)
+
+// walMagic is the magic number a SQLite WAL file begins with, in either of
+// its two forms, as big-endian bytes.
+var walMagic = [][]byte{{0x37, 0x7f, 0x06, 0x82}, {0x37, 0x7f, 0x06, 0x83}}
+
+// Test_SnapshotFormat checks the layout of complete and WAL snapshots, as
+// persisted to the snapshot store.
+func Test_SnapshotFormat(t *testing.T) {
+	s := mustNewWALStore(t)
+	ss := mustNewSnapshotStore(t)
+
+	mustExecute(t, s, "CREATE TABLE foo (id INTEGER NOT NULL PRIMARY KEY, name TEXT)")
+	full := mustSnapshot(t, s, ss, false, 1)
+	mustExecute(t, s, `INSERT INTO foo(name) VALUES("fiona")`)
+	wal := mustSnapshot(t, s, ss, true, 2)
+	empty := mustSnapshot(t, s, ss, true, 3)
+
+	tests := []struct {
+		id     string
+		marker uint64
+		magic  [][]byte
+	}{
+		{full, math.MaxUint64, [][]byte{[]byte("SQLite format 3\x00")}},
+		{wal, walSnapshotMarker, walMagic},
+		{empty, walSnapshotMarker, nil},
+	}
+	for _, tt := range tests {
+		_, rc, err := ss.FileSnapshotStore.Open(tt.id)
+		if err != nil {
+			t.Fatalf("failed to open snapshot %s: %s", tt.id, err)
+		}
+		b, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("failed to read snapshot %s: %s", tt.id, err)
+		}
+
+		r := bytes.NewReader(b)
+		if m := mustReadUint64(t, r); m != tt.marker {
+			t.Fatalf("snapshot %s has wrong marker, exp %d, got %d", tt.id, tt.marker, m)
+		}
+		sz := mustReadUint64(t, r)
+		if int(sz) != r.Len() {
+			t.Fatalf("snapshot %s has wrong size, header says %d, %d bytes follow", tt.id, sz, r.Len())
+		}
+		if tt.magic == nil {
+			if sz != 0 {
+				t.Fatalf("empty WAL snapshot %s has size %d", tt.id, sz)
+			}
+			continue
+		}
+		data := mustGunzip(t, r)
+		if !hasPrefix(data, tt.magic) {
+			t.Fatalf("snapshot %s holds wrong data, starts %x", tt.id, data[:8])
+		}
+	}
+}
+
+// Test_SnapshotChain checks that opening a WAL snapshot returns the chain of
+// snapshots it builds on, and that the chain restores the database.
+func Test_SnapshotChain(t *testing.T) {
+	s := mustNewWALStore(t)
+	ss := mustNewSnapshotStore(t)
+
+	mustExecute(t, s, "CREATE TABLE foo (id INTEGER NOT NULL PRIMARY KEY, name TEXT)")
+	mustExecute(t, s, `INSERT INTO foo(name) VALUES("fiona")`)
+	full := mustSnapshot(t, s, ss, false, 1)
+	mustExecute(t, s, `INSERT INTO foo(name) VALUES("declan")`)
+	mustSnapshot(t, s, ss, true, 2)
+	mustExecute(t, s, `UPDATE foo SET name="fiona2" WHERE id=1`)
+	mustExecute(t, s, `INSERT INTO foo(name) VALUES("aoife")`)
+	last := mustSnapshot(t, s, ss, true, 3)
+
+	// A complete snapshot is opened as it is.
+	_, rc, err := ss.Open(full)
+	if err != nil {
+		t.Fatalf("failed to open snapshot %s: %s", full, err)
+	}
+	if m := mustReadUint64(t, rc); m != math.MaxUint64 {
+		t.Fatalf("complete snapshot opened with marker %d", m)
+	}
+	rc.Close()
+
+	meta, rc, err := ss.Open(last)
+	if err != nil {
+		t.Fatalf("failed to open snapshot %s: %s", last, err)
+	}
+	defer rc.Close()
+	b, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("failed to read snapshot chain: %s", err)
+	}
+	if int64(len(b)) != meta.Size {
+		t.Fatalf("wrong chain size, meta says %d, read %d", meta.Size, len(b))
+	}
+
+	r := bytes.NewReader(b)
+	if m := mustReadUint64(t, r); m != chainSnapshotMarker {
+		t.Fatalf("WAL snapshot opened with marker %d, not as a chain", m)
+	}
+	if n := mustReadUint64(t, r); n != 3 {
+		t.Fatalf("wrong chain length, exp 3, got %d", n)
+	}
+	markers := []uint64{math.MaxUint64, walSnapshotMarker, walSnapshotMarker}
+	for i, exp := range markers {
+		if m := mustReadUint64(t, r); m != exp {
+			t.Fatalf("snapshot %d of chain has wrong marker, exp %d, got %d", i, exp, m)
+		}
+		sz := mustReadUint64(t, r)
+		if _, err := r.Seek(int64(sz), io.SeekCurrent); err != nil {
+			t.Fatalf("failed to skip snapshot %d of chain: %s", i, err)
+		}
+	}
+	if r.Len() != 0 {
+		t.Fatalf("%d bytes follow the chain", r.Len())
+	}
+
+	// The chain restores the database as it was at the last snapshot.
+	rs := &Store{raftDir: t.TempDir(), logger: log.New(ioutil.Discard, "", 0)}
+	path, err := rs.readSnapshot(bytes.NewReader(b), "")
+	if err != nil {
+		t.Fatalf("failed to read snapshot chain: %s", err)
+	}
+	db, err := sql.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open restored database: %s", err)
+	}
+	defer db.Close()
+	rows, err := db.QueryStringStmt("SELECT id, name FROM foo ORDER BY id")
+	if err != nil {
+		t.Fatalf("failed to query restored database: %s", err)
+	}
+	if exp, got := "[[1 fiona2] [2 declan] [3 aoife]]", fmt.Sprint(rows[0].Values); exp != got {
+		t.Fatalf("wrong restored data, exp %s, got %s", exp, got)
+	}
+}
+
+// Test_SnapshotChainMissingFull checks that a WAL snapshot which no longer
+// has a complete snapshot before it cannot be opened.
+func Test_SnapshotChainMissingFull(t *testing.T) {
+	s := mustNewWALStore(t)
+	ss := mustNewSnapshotStore(t)
+
+	mustExecute(t, s, "CREATE TABLE foo (id INTEGER NOT NULL PRIMARY KEY, name TEXT)")
+	mustSnapshot(t, s, ss, false, 1)
+	for i := uint64(2); i <= 4; i++ {
+		mustExecute(t, s, `INSERT INTO foo(name) VALUES("fiona")`)
+		mustSnapshot(t, s, ss, true, i)
+	}
+
+	// Only the three most recent snapshots, all WAL snapshots, are retained.
+	snaps, err := ss.List()
+	if err != nil {
+		t.Fatalf("failed to list snapshots: %s", err)
+	}
+	if _, _, err := ss.Open(snaps[0].ID); err == nil {
+		t.Fatal("opened WAL snapshot without a complete snapshot before it")
+	}
+}
+
+func mustNewWALStore(t *testing.T) *Store {
+	dir := t.TempDir()
+	s := &Store{
+		raftDir: dir,
+		dbPath:  filepath.Join(dir, sqliteFile),
+		logger:  log.New(ioutil.Discard, "", 0),
+	}
+	db, err := sql.Open(s.dbPath)
+	if err != nil {
+		t.Fatalf("failed to open database: %s", err)
+	}
+	if err := db.EnableWAL(); err != nil {
+		t.Fatalf("failed to enable WAL: %s", err)
+	}
+	s.db = db
+	t.Cleanup(func() { db.Close() })
+	return s
+}
+
+func mustNewSnapshotStore(t *testing.T) *snapshotStore {
+	ss, err := newSnapshotStore(t.TempDir(), 3, ioutil.Discard)
+	if err != nil {
+		t.Fatalf("failed to create snapshot store: %s", err)
+	}
+	return ss
+}
+
+// mustSnapshot takes a snapshot of the database of s, in the way Snapshot
+// does, and persists it to ss at the given index. It returns the snapshot ID.
+func mustSnapshot(t *testing.T, s *Store, ss *snapshotStore, wal bool, index uint64) string {
+	var path string
+	var err error
+	if wal {
+		path, err = s.walToTmpFile()
+	} else {
+		path, err = s.backupToTmpFile()
+		if err == nil {
+			err = s.db.Checkpoint()
+		}
+	}
+	if err != nil {
+		t.Fatalf("failed to snapshot database: %s", err)
+	}
+
+	fsm := &fsmSnapshot{
+		startT: time.Now(),
+		logger: s.logger,
+		path:   path,
+		wal:    wal,
+	}
+	defer fsm.Release()
+
+	sink, err := ss.Create(raft.SnapshotVersionMax, index, 1, raft.Configuration{}, 1, nil)
+	if err != nil {
+		t.Fatalf("failed to create snapshot sink: %s", err)
+	}
+	if err := fsm.Persist(sink); err != nil {
+		t.Fatalf("failed to persist snapshot: %s", err)
+	}
+	return sink.ID()
+}
+
+func mustExecute(t *testing.T, s *Store, stmt string) {
+	r, err := s.db.ExecuteStringStmt(stmt)
+	if err != nil {
+		t.Fatalf("failed to execute %s: %s", stmt, err)
+	}
+	if r[0].Error != "" {
+		t.Fatalf("failed to execute %s: %s", stmt, r[0].Error)
+	}
+}
+
+func mustReadUint64(t *testing.T, r io.Reader) uint64 {
+	v, err := readUint64From(r)
+	if err != nil {
+		t.Fatalf("failed to read uint64: %s", err)
+	}
+	return v
+}
+
+func mustGunzip(t *testing.T, r io.Reader) []byte {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		t.Fatalf("failed to open gzip data: %s", err)
+	}
+	b, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("failed to read gzip data: %s", err)
+	}
+	return b
+}
+
+func hasPrefix(b []byte, prefixes [][]byte) bool {
+	for _, p := range prefixes {
+		if bytes.HasPrefix(b, p) {
+			return true
+		}
+	}
+	return false
+}
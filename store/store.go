@@ -6,6 +6,7 @@ package store
 import (
 	"bytes"
 	"compress/gzip"
+	"crypto/rand"
 	"encoding/binary"
 	"errors"
 	"expvar"
@@ -20,7 +21,6 @@ import (
 	"strconv"
 	"sync"
 	"time"
-	"unsafe"
 
 	"github.com/hashicorp/raft"
 	"github.com/minghsu0107/tqlite/command"
@@ -44,28 +44,99 @@ var (
 	// ErrInvalidBackupFormat is returned when the requested backup format
 	// is not valid.
 	ErrInvalidBackupFormat = errors.New("invalid backup format")
+
+	// ErrNodeNotFound is returned when an operation references a node
+	// that is not part of the cluster configuration.
+	ErrNodeNotFound = errors.New("node not found")
+
+	// ErrMinIndexTimeout is returned when a query requires a minimum log
+	// index to be applied, and that index is not applied within the timeout.
+	ErrMinIndexTimeout = errors.New("timeout waiting for minimum index to be applied")
+
+	// ErrInvalidDatabase is returned when data to be loaded is not a valid
+	// SQLite database file.
+	ErrInvalidDatabase = errors.New("invalid SQLite database")
+
+	// ErrLoadNotFound is returned when a chunk continues a load which is
+	// not known to the node.
+	ErrLoadNotFound = errors.New("load not found")
+
+	// ErrLoadOffset is returned when a chunk does not start where the load
+	// it belongs to has reached.
+	ErrLoadOffset = errors.New("chunk does not start at load offset")
+
+	// ErrNotCaughtUp is returned when a node is not promoted to voter, as
+	// it has not applied enough of the log.
+	ErrNotCaughtUp = errors.New("node has not caught up with the leader")
+
+	// ErrNotStable is returned when a node is not promoted to voter, as it
+	// has not been healthy for long enough.
+	ErrNotStable = errors.New("node has not been healthy for the server stabilization time")
+
+	// ErrDemoteLeader is returned when a request demotes the leader.
+	ErrDemoteLeader = errors.New("cannot demote the leader, transfer leadership first")
+
+	// ErrTxNotFound is returned when a request refers to an interactive
+	// transaction which is not open on the node.
+	ErrTxNotFound = errors.New("transaction not found")
+
+	// ErrTxConflict is returned when an interactive transaction cannot commit,
+	// as the database has changed since its requests were made.
+	ErrTxConflict = errors.New("transaction conflicts with a concurrent change")
+
+	// ErrTxTooLarge is returned when a request would take an interactive
+	// transaction over the maximum number of statements.
+	ErrTxTooLarge = errors.New("transaction has too many statements")
+
+	// ErrIdempotencyKeyReused is returned when an execute request has the
+	// idempotency key of an earlier, different, request.
+	ErrIdempotencyKeyReused = errors.New("idempotency key reused for a different request")
+
+	// ErrChecksumInProgress is returned when a checksum command is applied
+	// before the node has finished taking the previous checksum.
+	ErrChecksumInProgress = errors.New("previous checksum still in progress")
 )
 
 const (
-	raftDBPath          = "raft.db" // Changing this will break backwards compatibility.
-	retainSnapshotCount = 2
-	applyTimeout        = 10 * time.Second
-	openTimeout         = 120 * time.Second
-	sqliteFile          = "db.sqlite"
-	leaderWaitDelay     = 100 * time.Millisecond
-	appliedWaitDelay    = 100 * time.Millisecond
-	connectionPoolCount = 5
-	connectionTimeout   = 10 * time.Second
-	raftLogCacheSize    = 512
-	trailingScale       = 1.25
+	raftDBPath              = "raft.db" // Changing this will break backwards compatibility.
+	retainSnapshotCount     = 2
+	applyTimeout            = 10 * time.Second
+	autopilotInterval       = 10 * time.Second
+	serverStabilizationTime = 10 * time.Second
+	promoteMaxLag           = 1024
+	openTimeout             = 120 * time.Second
+	sqliteFile              = "db.sqlite"
+	tmpFilePattern          = "tqlite-tmp-*"
+	leaderWaitDelay         = 100 * time.Millisecond
+	appliedWaitDelay        = 100 * time.Millisecond
+	connectionPoolCount     = 5
+	connectionTimeout       = 10 * time.Second
+	raftLogCacheSize        = 512
+	trailingScale           = 1.25
+	maxLoads                = 100
+	txTimeout               = 30 * time.Second
+	txMaxStatements         = 1000
+	idempotencyRetention    = time.Hour
 )
 
 const (
 	numSnaphots             = "num_snapshots"
+	numWALSnapshots         = "num_wal_snapshots"
 	numBackups              = "num_backups"
 	numRestores             = "num_restores"
+	numRecoveries           = "num_recoveries"
+	numLoads                = "num_loads"
+	numLoadChunks           = "num_load_chunks"
 	numUncompressedCommands = "num_uncompressed_commands"
 	numCompressedCommands   = "num_compressed_commands"
+	numTxs                  = "num_transactions"
+	numTxCommits            = "num_transaction_commits"
+	numTxRollbacks          = "num_transaction_rollbacks"
+	numTxConflicts          = "num_transaction_conflicts"
+	numTxTimeouts           = "num_transaction_timeouts"
+	numIdempotentReplays    = "num_idempotent_replays"
+	numBoundRequests        = "num_bound_requests"
+	numChecksums            = "num_checksums"
 )
 
 // BackupFormat represents the format of database backup.
@@ -85,10 +156,31 @@ var stats *expvar.Map
 func init() {
 	stats = expvar.NewMap("store")
 	stats.Add(numSnaphots, 0)
+	stats.Add(numWALSnapshots, 0)
 	stats.Add(numBackups, 0)
 	stats.Add(numRestores, 0)
+	stats.Add(numRecoveries, 0)
+	stats.Add(numLoads, 0)
+	stats.Add(numLoadChunks, 0)
 	stats.Add(numUncompressedCommands, 0)
 	stats.Add(numCompressedCommands, 0)
+	stats.Add(numTxs, 0)
+	stats.Add(numTxCommits, 0)
+	stats.Add(numTxRollbacks, 0)
+	stats.Add(numTxConflicts, 0)
+	stats.Add(numTxTimeouts, 0)
+	stats.Add(numIdempotentReplays, 0)
+	stats.Add(numBoundRequests, 0)
+	stats.Add(numChecksums, 0)
+}
+
+// LoadProgress is the progress of a chunked load of SQL text.
+type LoadProgress struct {
+	ID         string `json:"id"`
+	Offset     uint64 `json:"offset"`     // Offset in the SQL text up to which all statements are applied.
+	Chunks     uint64 `json:"chunks"`     // Number of chunks applied.
+	Statements uint64 `json:"statements"` // Number of statements applied.
+	Done       bool   `json:"done"`       // Whether the last chunk is applied.
 }
 
 // ClusterState defines the possible Raft states the current node can be in
@@ -120,6 +212,7 @@ type Store struct {
 	raftLog       raft.LogStore             // Persistent log store.
 	raftStable    raft.StableStore          // Persistent k-v store.
 	boltStore     *rlog.Log                 // Physical store.
+	snapshotStore raft.SnapshotStore        // Snapshot store.
 
 	onDiskCreated        bool      // On disk database actually created?
 	snapsExistOnOpen     bool      // Any snaps present when store opens?
@@ -135,17 +228,51 @@ type Store struct {
 	txMu    sync.RWMutex // Sync between snapshots and query-level transactions.
 	queryMu sync.RWMutex // Sync queries generally with other operations.
 
+	snapMu         sync.Mutex    // Sync access to fullSnapNeeded and snapshot timings.
+	fullSnapNeeded bool          // Must the next snapshot be a full snapshot?
+	numWALSnaps    uint64        // WAL snapshots taken since the last full snapshot.
+	lastSnapT      time.Time     // Time the last snapshot was persisted.
+	lastSnapDur    time.Duration // Time taken to create the last snapshot.
+	lastPersistDur time.Duration // Time taken to create and persist the last snapshot.
+
+	ap *autopilot // Removes nodes which are unreachable for too long.
+
+	notifyMu        sync.Mutex         // Sync access to notifyingNodes.
+	notifyingNodes  map[string]*Server // Nodes ready to bootstrap a cluster, by ID.
+	BootstrapExpect int                // Number of nodes which bootstrap the cluster together.
+
+	txsMu     sync.Mutex            // Sync access to txs.
+	txs       map[string]*txSession // Open interactive transactions, by ID.
+	TxTimeout time.Duration         // Abort interactive transactions idle for this long.
+
+	TxMaxStatements int // Maximum number of statements made in an interactive transaction.
+
+	IdempotencyRetention time.Duration // Keep the results of requests with idempotency keys for this long.
+	DeterministicSQL     bool          // Bind a random seed and time to writes, for non-deterministic SQL functions.
+
+	checksumMu   sync.RWMutex // Sync access to checksum and checksumBusy.
+	checksum     *Checksum    // Checksum taken when the last checksum command was applied.
+	checksumBusy bool         // Whether a checksum is being taken.
+
 	logger *log.Logger
 
 	ShutdownOnRemove   bool
 	SnapshotThreshold  uint64
 	SnapshotInterval   time.Duration
+	FullSnapshotEvery  uint64 // In WAL mode, take a full snapshot at least this often.
 	LeaderLeaseTimeout time.Duration
 	HeartbeatTimeout   time.Duration
 	ElectionTimeout    time.Duration
 	ApplyTimeout       time.Duration
 	RaftLogLevel       string
 
+	AutopilotInterval       time.Duration // How often autopilot checks the health of nodes.
+	NonVoterReapTimeout     time.Duration // Remove non-voters unreachable for this long. Zero disables removal.
+	VoterReapTimeout        time.Duration // Remove voters unreachable for this long, if quorum allows. Zero disables removal.
+	ReapMinVoters           int           // Never remove voters if fewer than this many would remain.
+	ServerStabilizationTime time.Duration // Time a leader, and a node's health, must be stable before autopilot acts.
+	PromoteMaxLag           uint64        // Promote joining nodes once within this many log entries of the leader.
+
 	numTrailingLogs uint64
 }
 
@@ -174,14 +301,26 @@ func New(ln Listener, c *StoreConfig) *Store {
 	}
 
 	return &Store{
-		ln:            ln,
-		raftDir:       c.Dir,
-		raftID:        c.ID,
-		dbConf:        c.DBConf,
-		dbPath:        filepath.Join(c.Dir, sqliteFile),
-		reqMarshaller: command.NewRequestMarshaler(),
-		logger:        logger,
-		ApplyTimeout:  applyTimeout,
+		ln:             ln,
+		raftDir:        c.Dir,
+		raftID:         c.ID,
+		dbConf:         c.DBConf,
+		dbPath:         filepath.Join(c.Dir, sqliteFile),
+		reqMarshaller:  command.NewRequestMarshaler(),
+		notifyingNodes: make(map[string]*Server),
+		txs:            make(map[string]*txSession),
+		logger:         logger,
+		ApplyTimeout:   applyTimeout,
+		TxTimeout:      txTimeout,
+
+		TxMaxStatements: txMaxStatements,
+
+		IdempotencyRetention: idempotencyRetention,
+		DeterministicSQL:     true,
+
+		AutopilotInterval:       autopilotInterval,
+		ServerStabilizationTime: serverStabilizationTime,
+		PromoteMaxLag:           promoteMaxLag,
 	}
 }
 
@@ -198,6 +337,10 @@ func (s *Store) Open(enableBootstrap bool) error {
 	if err != nil {
 		return err
 	}
+	if err := s.removeTmpFiles(); err != nil {
+		return fmt.Errorf("remove temporary files: %s", err)
+	}
+	s.requireFullSnapshot()
 
 	// Create Raft-compatible network layer.
 	s.raftTn = raft.NewNetworkTransport(NewTransport(s.ln), connectionPoolCount, connectionTimeout, nil)
@@ -208,17 +351,17 @@ func (s *Store) Open(enableBootstrap bool) error {
 	config := s.raftConfig()
 	config.LocalID = raft.ServerID(s.raftID)
 
-	// Create the snapshot store. This allows Raft to truncate the log.
-	snapshots, err := raft.NewFileSnapshotStore(s.raftDir, retainSnapshotCount, os.Stderr)
-	if err != nil {
-		return fmt.Errorf("file snapshot store: %s", err)
+	// Create the snapshot store. This allows Raft to truncate the log. WAL
+	// snapshots depend on the snapshots before them, so enough snapshots must
+	// be retained to always cover the latest full snapshot.
+	retain := retainSnapshotCount
+	if s.walEnabled() && int(s.FullSnapshotEvery)+1 > retain {
+		retain = int(s.FullSnapshotEvery) + 1
 	}
-	snaps, err := snapshots.List()
+	snapshots, err := newSnapshotStore(s.raftDir, retain, os.Stderr)
 	if err != nil {
-		return fmt.Errorf("list snapshots: %s", err)
+		return fmt.Errorf("file snapshot store: %s", err)
 	}
-	s.logger.Printf("%d preexisting snapshots present", len(snaps))
-	s.snapsExistOnOpen = len(snaps) > 0
 
 	// Create the log store and stable store.
 	s.boltStore, err = rlog.NewLog(filepath.Join(s.raftDir, raftDBPath))
@@ -226,11 +369,26 @@ func (s *Store) Open(enableBootstrap bool) error {
 		return fmt.Errorf("new log store: %s", err)
 	}
 	s.raftStable = s.boltStore
+
+	// Recover the node, if a peers file requests it. This rewrites the log and
+	// snapshots, so must happen before they are examined.
+	if err := s.recover(config, snapshots); err != nil {
+		return err
+	}
+
 	s.raftLog, err = raft.NewLogCache(raftLogCacheSize, s.boltStore)
 	if err != nil {
 		return fmt.Errorf("new cached store: %s", err)
 	}
 
+	s.snapshotStore = snapshots
+	snaps, err := snapshots.List()
+	if err != nil {
+		return fmt.Errorf("list snapshots: %s", err)
+	}
+	s.logger.Printf("%d preexisting snapshots present", len(snaps))
+	s.snapsExistOnOpen = len(snaps) > 0
+
 	// Get some info about the log, before any more entries are committed.
 	if err := s.setLogInfo(); err != nil {
 		return fmt.Errorf("set log info: %s", err)
@@ -242,14 +400,14 @@ func (s *Store) Open(enableBootstrap bool) error {
 	// there are no commands in the log, then this is the only opportunity to
 	// create that on-disk database file before Raft initializes.
 	if !s.dbConf.Memory && !s.snapsExistOnOpen && s.lastCommandIdxOnOpen == 0 {
-		s.db, err = s.openOnDisk(nil)
+		s.db, err = s.openOnDisk("")
 		if err != nil {
 			return fmt.Errorf("failed to open on-disk database")
 		}
 		s.onDiskCreated = true
 	} else {
 		// We need an in-memory database, at least for bootstrapping purposes.
-		s.db, err = s.openInMemory(nil)
+		s.db, err = s.openInMemory("")
 		if err != nil {
 			return fmt.Errorf("failed to open in-memory database")
 		}
@@ -278,11 +436,18 @@ func (s *Store) Open(enableBootstrap bool) error {
 
 	s.raft = ra
 
+	s.ap = newAutopilot(s)
+	s.ap.start()
+
 	return nil
 }
 
 // Close closes the store. If wait is true, waits for a graceful shutdown.
 func (s *Store) Close(wait bool) error {
+	if s.ap != nil {
+		s.ap.stop()
+	}
+	s.abortTxs()
 	f := s.raft.Shutdown()
 	if wait {
 		if e := f.(raft.Future); e.Error() != nil {
@@ -397,6 +562,12 @@ func (s *Store) Nodes() ([]*Server, error) {
 	return servers, nil
 }
 
+// ServerHealth returns the health of every node in the cluster, by ID, as
+// tracked by autopilot. It is empty unless this node is the leader.
+func (s *Store) ServerHealth() map[string]ServerHealth {
+	return s.ap.serverHealth()
+}
+
 // WaitForLeader blocks until a leader is detected, or the timeout expires.
 func (s *Store) WaitForLeader(timeout time.Duration) (string, error) {
 	tck := time.NewTicker(leaderWaitDelay)
@@ -430,6 +601,10 @@ func (s *Store) SetRequestCompression(batch, size int) {
 // WaitForAppliedIndex blocks until a given log index has been applied,
 // or the timeout expires.
 func (s *Store) WaitForAppliedIndex(idx uint64, timeout time.Duration) error {
+	if s.raft.AppliedIndex() >= idx {
+		return nil
+	}
+
 	tck := time.NewTicker(appliedWaitDelay)
 	defer tck.Stop()
 	tmr := time.NewTimer(timeout)
@@ -468,6 +643,7 @@ func (s *Store) Stats() (map[string]interface{}, error) {
 		dbStatus["path"] = ":memory:"
 	} else {
 		dbStatus["path"] = s.dbPath
+		dbStatus["wal"] = enabledFromBool(s.dbConf.WAL)
 		if s.onDiskCreated {
 			if dbStatus["size"], err = s.db.FileSize(); err != nil {
 				return nil, err
@@ -507,6 +683,16 @@ func (s *Store) Stats() (map[string]interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
+
+	s.snapMu.Lock()
+	snapStatus := map[string]interface{}{}
+	if !s.lastSnapT.IsZero() {
+		snapStatus["last_time"] = s.lastSnapT
+		snapStatus["last_create_duration"] = s.lastSnapDur.String()
+		snapStatus["last_persist_duration"] = s.lastPersistDur.String()
+	}
+	s.snapMu.Unlock()
+
 	status := map[string]interface{}{
 		"node_id": s.raftID,
 		"raft":    raftStats,
@@ -516,32 +702,51 @@ func (s *Store) Stats() (map[string]interface{}, error) {
 			"addr":    leaderAddr,
 		},
 		"apply_timeout":      s.ApplyTimeout.String(),
+		"tx_timeout":         s.TxTimeout.String(),
+		"tx_max_statements":  s.TxMaxStatements,
+		"transactions":       s.numTxs(),
 		"heartbeat_timeout":  s.HeartbeatTimeout.String(),
 		"election_timeout":   s.ElectionTimeout.String(),
 		"snapshot_threshold": s.SnapshotThreshold,
 		"snapshot_interval":  s.SnapshotInterval,
 		"trailing_logs":      s.numTrailingLogs,
+		"snapshot":           snapStatus,
+		"autopilot":          s.ap.stats(),
 		"request_marshaler":  s.reqMarshaller.Stats(),
 		"nodes":              nodes,
 		"dir":                s.raftDir,
 		"dir_size":           dirSz,
 		"sqlite3":            dbStatus,
 		"db_conf":            s.dbConf,
+		"idempotency": map[string]interface{}{
+			"retention": s.IdempotencyRetention.String(),
+		},
+	}
+	if cs := s.LastChecksum(); cs != nil {
+		checksumStatus := map[string]interface{}{
+			"index":  cs.Index,
+			"digest": cs.Digest(),
+		}
+		if cs.Error != "" {
+			checksumStatus["error"] = cs.Error
+		}
+		status["checksum"] = checksumStatus
 	}
 	return status, nil
 }
 
 // Execute executes queries that return no rows, but do modify the database.
-func (s *Store) Execute(ex *command.ExecuteRequest) ([]*sql.Result, error) {
+// It also returns the index of the Raft log entry which carried the request.
+func (s *Store) Execute(ex *command.ExecuteRequest) ([]*sql.Result, uint64, error) {
 	if s.raft.State() != raft.Leader {
-		return nil, ErrNotLeader
+		return nil, 0, ErrNotLeader
 	}
 	return s.execute(ex)
 }
 
 // ExecuteOrAbort executes the requests, but aborts any active transaction
 // on the underlying database in the case of any error.
-func (s *Store) ExecuteOrAbort(ex *command.ExecuteRequest) (results []*sql.Result, retErr error) {
+func (s *Store) ExecuteOrAbort(ex *command.ExecuteRequest) (results []*sql.Result, idx uint64, retErr error) {
 	defer func() {
 		var errored bool
 		if results != nil {
@@ -561,10 +766,23 @@ func (s *Store) ExecuteOrAbort(ex *command.ExecuteRequest) (results []*sql.Resul
 	return s.execute(ex)
 }
 
-func (s *Store) execute(ex *command.ExecuteRequest) ([]*sql.Result, error) {
+func (s *Store) execute(ex *command.ExecuteRequest) ([]*sql.Result, uint64, error) {
+	if ex.IdempotencyKey != "" {
+		h, err := requestHash(ex.Request)
+		if err != nil {
+			return nil, 0, err
+		}
+		ex.RequestHash = h
+		ex.ReceivedAt = time.Now().UnixNano()
+		ex.IdempotencyRetention = s.IdempotencyRetention.Nanoseconds()
+	}
+	if err := s.bindRequest(ex.Request); err != nil {
+		return nil, 0, err
+	}
+
 	b, compressed, err := s.reqMarshaller.Marshal(ex)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	if compressed {
 		stats.Add(numCompressedCommands, 1)
@@ -580,19 +798,141 @@ func (s *Store) execute(ex *command.ExecuteRequest) ([]*sql.Result, error) {
 
 	b, err = command.Marshal(c)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	f := s.raft.Apply(b, s.ApplyTimeout)
 	if e := f.(raft.Future); e.Error() != nil {
 		if e.Error() == raft.ErrNotLeader {
-			return nil, ErrNotLeader
+			return nil, 0, ErrNotLeader
 		}
-		return nil, e.Error()
+		return nil, 0, e.Error()
 	}
 
 	r := f.Response().(*fsmExecuteResponse)
-	return r.results, r.error
+	return r.results, f.Index(), r.error
+}
+
+// bindRequest binds a random seed, and the time now, to r, if enabled, so
+// that every node which executes r gets the same results from
+// non-deterministic SQL functions such as RANDOM() and datetime('now'). This
+// must be done before r is written to the log.
+func (s *Store) bindRequest(r *command.Request) error {
+	if !s.DeterministicSQL || r == nil {
+		return nil
+	}
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	r.RandomSeed = int64(binary.LittleEndian.Uint64(b))
+	r.Now = time.Now().UnixNano()
+	stats.Add(numBoundRequests, 1)
+	return nil
+}
+
+// Load replaces the entire database, on every node, with the SQLite database
+// file contained in the request. The file is checked for integrity before it
+// is sent through the Raft log. The Raft index of the committed request is
+// returned.
+func (s *Store) Load(lr *command.LoadRequest) (uint64, error) {
+	if s.raft.State() != raft.Leader {
+		return 0, ErrNotLeader
+	}
+	if err := s.checkDatabase(lr.Data); err != nil {
+		return 0, err
+	}
+
+	b, err := command.MarshalLoadRequest(lr)
+	if err != nil {
+		return 0, err
+	}
+	c := &command.Command{
+		Type:       command.Command_COMMAND_TYPE_LOAD,
+		SubCommand: b,
+		Compressed: true,
+	}
+	b, err = command.Marshal(c)
+	if err != nil {
+		return 0, err
+	}
+
+	f := s.raft.Apply(b, s.ApplyTimeout)
+	if e := f.(raft.Future); e.Error() != nil {
+		if e.Error() == raft.ErrNotLeader {
+			return 0, ErrNotLeader
+		}
+		return 0, e.Error()
+	}
+
+	stats.Add(numLoads, 1)
+	r := f.Response().(*fsmGenericResponse)
+	return f.Index(), r.error
+}
+
+// LoadChunk applies a chunk of SQL text, which is part of a larger load, as
+// a single Raft log entry. Chunks must be applied in order: a chunk is only
+// applied if it starts at the offset up to which the load has been applied,
+// so a chunk which is retried after it was in fact applied is rejected, and
+// a failed load can be resumed from its offset. The progress of the load
+// after the chunk and the Raft index of the chunk are returned. If the chunk
+// is not applied, the progress returned is that of the load as it stands.
+func (s *Store) LoadChunk(lcr *command.LoadChunkRequest) (LoadProgress, uint64, error) {
+	if s.raft.State() != raft.Leader {
+		return LoadProgress{}, 0, ErrNotLeader
+	}
+	if err := s.bindRequest(lcr.Request); err != nil {
+		return LoadProgress{}, 0, err
+	}
+
+	b, compressed, err := s.reqMarshaller.Marshal(lcr)
+	if err != nil {
+		return LoadProgress{}, 0, err
+	}
+	if compressed {
+		stats.Add(numCompressedCommands, 1)
+	} else {
+		stats.Add(numUncompressedCommands, 1)
+	}
+
+	c := &command.Command{
+		Type:       command.Command_COMMAND_TYPE_LOAD_CHUNK,
+		SubCommand: b,
+		Compressed: compressed,
+	}
+	b, err = command.Marshal(c)
+	if err != nil {
+		return LoadProgress{}, 0, err
+	}
+
+	f := s.raft.Apply(b, s.ApplyTimeout)
+	if e := f.(raft.Future); e.Error() != nil {
+		if e.Error() == raft.ErrNotLeader {
+			return LoadProgress{}, 0, ErrNotLeader
+		}
+		return LoadProgress{}, 0, e.Error()
+	}
+
+	stats.Add(numLoadChunks, 1)
+	r := f.Response().(*fsmLoadChunkResponse)
+	return r.progress, f.Index(), r.error
+}
+
+// checkDatabase returns an error unless b is an intact SQLite database file.
+func (s *Store) checkDatabase(b []byte) error {
+	if !sql.IsValidSQLiteData(b) {
+		return ErrInvalidDatabase
+	}
+	path, err := s.restoreToTmpFile(bytes.NewReader(b), int64(len(b)), false)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	if err := sql.CheckIntegrity(path); err != nil {
+		return fmt.Errorf("%s: %s", ErrInvalidDatabase, err)
+	}
+	return nil
 }
 
 // Backup writes a snapshot of the underlying database to dst
@@ -623,9 +963,8 @@ func (s *Store) Backup(leader bool, fmt BackupFormat, dst io.Writer) error {
 
 // Query executes queries that return rows, and do not modify the database.
 func (s *Store) Query(qr *command.QueryRequest) ([]*sql.Rows, error) {
-	s.queryMu.RLock()
-	defer s.queryMu.RUnlock()
-
+	// No lock may be held while waiting on the log, as applying the entries
+	// ahead may need to block queries.
 	if qr.Level == command.QueryRequest_QUERY_REQUEST_LEVEL_STRONG {
 		b, compressed, err := s.reqMarshaller.Marshal(qr)
 		if err != nil {
@@ -660,6 +999,12 @@ func (s *Store) Query(qr *command.QueryRequest) ([]*sql.Rows, error) {
 		return r.rows, r.error
 	}
 
+	if qr.Level == command.QueryRequest_QUERY_REQUEST_LEVEL_LINEARIZABLE {
+		if err := s.readIndex(); err != nil {
+			return nil, err
+		}
+	}
+
 	if qr.Level == command.QueryRequest_QUERY_REQUEST_LEVEL_WEAK && s.raft.State() != raft.Leader {
 		return nil, ErrNotLeader
 	}
@@ -669,6 +1014,16 @@ func (s *Store) Query(qr *command.QueryRequest) ([]*sql.Rows, error) {
 		return nil, ErrStaleRead
 	}
 
+	// Ensure any write the client depends on has been applied locally.
+	if qr.MinIndex > 0 {
+		if err := s.WaitForAppliedIndex(qr.MinIndex, s.ApplyTimeout); err != nil {
+			return nil, ErrMinIndexTimeout
+		}
+	}
+
+	s.queryMu.RLock()
+	defer s.queryMu.RUnlock()
+
 	// Read straight from database. If a transaction is requested, we must block
 	// certain other database operations.
 	if qr.Request.Transaction {
@@ -678,8 +1033,155 @@ func (s *Store) Query(qr *command.QueryRequest) ([]*sql.Rows, error) {
 	return s.db.Query(qr.Request, qr.Timings)
 }
 
+// readIndex ensures that a read performed after it returns reflects every
+// write committed before it was called. It records the commit index, confirms
+// with a quorum of nodes that this node is still the leader, and then waits
+// until the commit index has been applied to the database. This provides
+// linearizable reads without writing anything to the Raft log.
+func (s *Store) readIndex() error {
+	if s.raft.State() != raft.Leader {
+		return ErrNotLeader
+	}
+
+	idx, err := s.commitIndex()
+	if err != nil {
+		return err
+	}
+
+	if err := s.raft.VerifyLeader().Error(); err != nil {
+		if err == raft.ErrNotLeader || err == raft.ErrLeadershipLost {
+			return ErrNotLeader
+		}
+		return err
+	}
+
+	if err := s.WaitForAppliedIndex(idx, s.ApplyTimeout); err != nil {
+		return fmt.Errorf("waiting for commit index %d to be applied: %s", idx, err)
+	}
+	return nil
+}
+
+// commitIndex returns the latest index known by this node to be committed.
+func (s *Store) commitIndex() (uint64, error) {
+	idx, err := strconv.ParseUint(s.raft.Stats()["commit_index"], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse commit index: %s", err)
+	}
+	return idx, nil
+}
+
+// NodeStatus returns a summary of the state of this node.
+func (s *Store) NodeStatus() (*NodeStatus, error) {
+	dbSz, err := s.db.Size()
+	if err != nil {
+		return nil, err
+	}
+	snaps, err := s.snapshotStore.List()
+	if err != nil {
+		return nil, fmt.Errorf("list snapshots: %s", err)
+	}
+	term, err := strconv.ParseUint(s.raft.Stats()["term"], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse term: %s", err)
+	}
+	commitIdx, err := s.commitIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	st := &NodeStatus{
+		ID:           s.raftID,
+		Addr:         s.Addr(),
+		State:        s.raft.State().String(),
+		Term:         term,
+		CommitIndex:  commitIdx,
+		AppliedIndex: s.raft.AppliedIndex(),
+		LastIndex:    s.raft.LastIndex(),
+		DBSize:       dbSz,
+		Snapshots:    len(snaps),
+		Checksum:     s.LastChecksum(),
+	}
+	if s.raft.State() != raft.Leader {
+		st.LastContact = s.raft.LastContact()
+	}
+	return st, nil
+}
+
+// Readiness returns the state of this node which determines whether it can
+// serve requests.
+func (s *Store) Readiness() (*Readiness, error) {
+	commitIdx, err := s.commitIndex()
+	if err != nil {
+		return nil, err
+	}
+	appliedIdx := s.raft.AppliedIndex()
+
+	r := &Readiness{
+		LeaderAddr:   string(s.raft.Leader()),
+		IsLeader:     s.raft.State() == raft.Leader,
+		LogsApplied:  appliedIdx >= s.lastIdxOnOpen,
+		CommitIndex:  commitIdx,
+		AppliedIndex: appliedIdx,
+	}
+	if !r.IsLeader {
+		r.LastContact = s.raft.LastContact()
+	}
+	return r, nil
+}
+
+// Notify records that the node with the given ID, located at addr, is ready
+// to bootstrap a cluster. Once BootstrapExpect nodes, this node included, are
+// ready, this node bootstraps the cluster with all of them as voters. Every
+// node bootstraps with the same nodes, so they form a single cluster. Notify
+// does nothing if this node is already part of a cluster.
+func (s *Store) Notify(id, addr string) error {
+	s.notifyMu.Lock()
+	defer s.notifyMu.Unlock()
+
+	if s.BootstrapExpect == 0 || s.notifyingNodes == nil {
+		// Not bootstrapping, or bootstrapped already.
+		return nil
+	}
+
+	f := s.raft.GetConfiguration()
+	if err := f.Error(); err != nil {
+		return err
+	}
+	if len(f.Configuration().Servers) > 0 {
+		s.notifyingNodes = nil
+		return nil
+	}
+
+	if _, ok := s.notifyingNodes[id]; !ok {
+		s.logger.Printf("node %s at %s is ready to bootstrap, %d of %d nodes ready",
+			id, addr, len(s.notifyingNodes)+1, s.BootstrapExpect)
+	}
+	s.notifyingNodes[id] = &Server{ID: id, Addr: addr}
+	if len(s.notifyingNodes) < s.BootstrapExpect {
+		return nil
+	}
+
+	servers := make([]raft.Server, 0, len(s.notifyingNodes))
+	for _, n := range s.notifyingNodes {
+		servers = append(servers, raft.Server{
+			ID:      raft.ServerID(n.ID),
+			Address: raft.ServerAddress(n.Addr),
+		})
+	}
+	s.logger.Printf("bootstrapping cluster with %d nodes", len(servers))
+	if err := s.raft.BootstrapCluster(raft.Configuration{Servers: servers}).Error(); err != nil {
+		s.logger.Printf("failed to bootstrap cluster: %s", err.Error())
+		return err
+	}
+	s.notifyingNodes = nil
+	return nil
+}
+
 // Join joins a node, identified by id and located at addr, to this store.
 // The node must be ready to respond to Raft communications at that address.
+// A node joining as a voter is added as a non-voter, so that it does not
+// count towards quorum until it has caught up with the leader, when it asks
+// to be promoted.
 func (s *Store) Join(id, addr string, voter bool) error {
 	s.logger.Printf("received request to join node at %s", addr)
 	if s.raft.State() != raft.Leader {
@@ -710,27 +1212,111 @@ func (s *Store) Join(id, addr string, voter bool) error {
 		}
 	}
 
-	var f raft.IndexFuture
+	f := s.raft.AddNonvoter(raft.ServerID(id), raft.ServerAddress(addr), 0, 0)
+	if e := f.(raft.Future); e.Error() != nil {
+		if e.Error() == raft.ErrNotLeader {
+			return ErrNotLeader
+		}
+		return e.Error()
+	}
+
 	if voter {
-		f = s.raft.AddVoter(raft.ServerID(id), raft.ServerAddress(addr), 0, 0)
+		s.logger.Printf("node at %s joined successfully as non-voter, pending promotion to voter", addr)
 	} else {
+		s.logger.Printf("node at %s joined successfully as %s", addr, prettyVoter(voter))
+	}
+	return nil
+}
 
-		f = s.raft.AddNonvoter(raft.ServerID(id), raft.ServerAddress(addr), 0, 0)
+// Promote makes the non-voting node with the given ID a voter. Unless force
+// is set, the node is promoted only if appliedIndex, the index of the last
+// log entry it applied, is within PromoteMaxLag entries of the leader's,
+// and autopilot has seen it healthy for the server stabilization time.
+func (s *Store) Promote(id string, appliedIndex uint64, force bool) error {
+	s.logger.Printf("received request to promote node %s", id)
+	if s.raft.State() != raft.Leader {
+		return ErrNotLeader
 	}
-	if e := f.(raft.Future); e.Error() != nil {
-		if e.Error() == raft.ErrNotLeader {
+
+	srv, err := s.server(id)
+	if err != nil {
+		return err
+	}
+	if srv.Suffrage == raft.Voter {
+		return nil
+	}
+
+	if !force {
+		if idx := s.raft.AppliedIndex(); idx > appliedIndex && idx-appliedIndex > s.PromoteMaxLag {
+			return fmt.Errorf("%s: %d log entries behind", ErrNotCaughtUp, idx-appliedIndex)
+		}
+		h, ok := s.ServerHealth()[id]
+		if !ok || !h.Healthy || time.Since(h.StableSince) < s.ServerStabilizationTime {
+			return ErrNotStable
+		}
+	}
+
+	f := s.raft.AddVoter(srv.ID, srv.Address, 0, 0)
+	if f.Error() != nil {
+		if f.Error() == raft.ErrNotLeader {
 			return ErrNotLeader
 		}
-		return e.Error()
+		return f.Error()
+	}
+
+	if !force {
+		s.ap.record(time.Now(), autopilotPromote, id,
+			fmt.Sprintf("non-voter caught up, at log index %d", appliedIndex))
+	}
+	s.logger.Printf("node %s promoted to voter successfully", id)
+	return nil
+}
+
+// Demote makes the voting node with the given ID a non-voter.
+func (s *Store) Demote(id string) error {
+	s.logger.Printf("received request to demote node %s", id)
+	if s.raft.State() != raft.Leader {
+		return ErrNotLeader
+	}
+	if id == s.raftID {
+		return ErrDemoteLeader
 	}
 
-	s.logger.Printf("node at %s joined successfully as %s", addr, prettyVoter(voter))
+	srv, err := s.server(id)
+	if err != nil {
+		return err
+	}
+	if srv.Suffrage != raft.Voter {
+		return nil
+	}
+
+	f := s.raft.DemoteVoter(srv.ID, 0, 0)
+	if f.Error() != nil {
+		if f.Error() == raft.ErrNotLeader {
+			return ErrNotLeader
+		}
+		return f.Error()
+	}
+
+	s.logger.Printf("node %s demoted to non-voter successfully", id)
 	return nil
 }
 
+// AppliedIndex returns the index of the last log entry applied to the
+// database.
+func (s *Store) AppliedIndex() uint64 {
+	return s.raft.AppliedIndex()
+}
+
 // Remove removes a node from the store, specified by ID.
 func (s *Store) Remove(id string) error {
 	s.logger.Printf("received request to remove node %s", id)
+	if s.raft.State() != raft.Leader {
+		return ErrNotLeader
+	}
+	if _, err := s.server(id); err != nil {
+		return err
+	}
 	if err := s.remove(id); err != nil {
 		s.logger.Printf("failed to remove node %s: %s", id, err.Error())
 		return err
@@ -740,6 +1326,49 @@ func (s *Store) Remove(id string) error {
 	return nil
 }
 
+// TransferLeadership transfers leadership of the cluster to the node with
+// the given ID. If id is empty, Raft picks the most up-to-date follower.
+// This node must be the leader.
+func (s *Store) TransferLeadership(id string) error {
+	s.logger.Printf("received request to transfer leadership to node %s", prettyTarget(id))
+	if s.raft.State() != raft.Leader {
+		return ErrNotLeader
+	}
+
+	var f raft.Future
+	if id == "" {
+		f = s.raft.LeadershipTransfer()
+	} else {
+		configFuture := s.raft.GetConfiguration()
+		if err := configFuture.Error(); err != nil {
+			s.logger.Printf("failed to get raft configuration: %v", err)
+			return err
+		}
+
+		var addr raft.ServerAddress
+		for _, srv := range configFuture.Configuration().Servers {
+			if srv.ID == raft.ServerID(id) {
+				addr = srv.Address
+				break
+			}
+		}
+		if addr == "" {
+			return ErrNodeNotFound
+		}
+		f = s.raft.LeadershipTransferToServer(raft.ServerID(id), addr)
+	}
+	if err := f.Error(); err != nil {
+		if err == raft.ErrNotLeader {
+			return ErrNotLeader
+		}
+		s.logger.Printf("failed to transfer leadership: %s", err.Error())
+		return err
+	}
+
+	s.logger.Printf("leadership transferred successfully")
+	return nil
+}
+
 // Noop writes a noop command to the Raft log. A noop command simply
 // consumes a slot in the Raft log, but has no other affect on the
 // system.
@@ -771,30 +1400,126 @@ func (s *Store) Noop(id string) error {
 	return nil
 }
 
-// openInMemory returns an in-memory database. If b is non-nil, then the
-// database will be initialized with the contents of b.
-func (s *Store) openInMemory(b []byte) (db *sql.DB, err error) {
-	if b == nil {
+// openInMemory returns an in-memory database. If src is non-empty, then the
+// database will be initialized with the contents of the SQLite file at src.
+func (s *Store) openInMemory(src string) (db *sql.DB, err error) {
+	if src == "" {
 		db, err = sql.OpenInMemoryWithDSN(s.dbConf.DSN)
 	} else {
-		db, err = sql.DeserializeInMemoryWithDSN(b, s.dbConf.DSN)
+		db, err = sql.LoadInMemoryWithDSN(src, s.dbConf.DSN)
 	}
 	return
 }
 
-// openOnDisk opens an on-disk database file at the Store's configured path. If
-// b is non-nil, any preexisting file will first be overwritten with those contents.
-// Otherwise any pre-existing file will be removed before the database is opened.
-func (s *Store) openOnDisk(b []byte) (*sql.DB, error) {
-	if err := os.Remove(s.dbPath); err != nil && !os.IsNotExist(err) {
+// openOnDisk opens an on-disk database file at the Store's configured path.
+// Any pre-existing file, and its WAL, will be removed before the database is
+// opened. If src is non-empty, the SQLite file at src is then moved into
+// place, so src must reside on the same filesystem as the Store's directory.
+func (s *Store) openOnDisk(src string) (*sql.DB, error) {
+	for _, p := range []string{s.dbPath, sql.WALPath(s.dbPath), s.dbPath + "-shm"} {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	if src != "" {
+		if err := os.Rename(src, s.dbPath); err != nil {
+			return nil, err
+		}
+	}
+	db, err := sql.OpenWithDSN(s.dbPath, s.dbConf.DSN)
+	if err != nil {
 		return nil, err
 	}
-	if b != nil {
-		if err := ioutil.WriteFile(s.dbPath, b, 0660); err != nil {
+	if s.dbConf.WAL {
+		if err := db.EnableWAL(); err != nil {
+			db.Close()
 			return nil, err
 		}
 	}
-	return sql.OpenWithDSN(s.dbPath, s.dbConf.DSN)
+	return db, nil
+}
+
+// backupToTmpFile writes a consistent copy of the database to a new temporary
+// file in the Store's directory, and returns the path to that file. It is up
+// to the caller to remove the file.
+func (s *Store) backupToTmpFile() (string, error) {
+	f, err := ioutil.TempFile(s.raftDir, tmpFilePattern)
+	if err != nil {
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := s.db.Backup(f.Name()); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// walToTmpFile copies the database's WAL to a new temporary file in the
+// Store's directory, checkpoints the WAL, and returns the path to the copy.
+// It is up to the caller to remove the file.
+func (s *Store) walToTmpFile() (path string, retErr error) {
+	f, err := ioutil.TempFile(s.raftDir, tmpFilePattern)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := f.Close(); err != nil && retErr == nil {
+			retErr = err
+		}
+		if retErr != nil {
+			os.Remove(f.Name())
+		}
+	}()
+
+	wal, err := os.Open(sql.WALPath(s.dbPath))
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	if wal != nil {
+		defer wal.Close()
+		if _, err := io.Copy(f, wal); err != nil {
+			return "", err
+		}
+	}
+
+	if err := s.db.Checkpoint(); err != nil {
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// walEnabled returns whether the Store snapshots its database incrementally
+// by way of the SQLite WAL.
+func (s *Store) walEnabled() bool {
+	return s.dbConf.WAL && !s.dbConf.Memory
+}
+
+// requireFullSnapshot ensures the next snapshot is a full snapshot. It must be
+// called whenever the WAL may no longer hold exactly the changes made since
+// the last snapshot which was persisted.
+func (s *Store) requireFullSnapshot() {
+	s.snapMu.Lock()
+	defer s.snapMu.Unlock()
+	s.fullSnapNeeded = true
+}
+
+// removeTmpFiles removes any temporary files left behind in the Store's
+// directory, for example by a crash during snapshotting.
+func (s *Store) removeTmpFiles() error {
+	files, err := filepath.Glob(filepath.Join(s.raftDir, tmpFilePattern))
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := os.Remove(f); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // setLogInfo records some key indexs about the log.
@@ -815,6 +1540,20 @@ func (s *Store) setLogInfo() error {
 	return nil
 }
 
+// server returns the node with the given ID in the Raft configuration.
+func (s *Store) server(id string) (raft.Server, error) {
+	f := s.raft.GetConfiguration()
+	if err := f.Error(); err != nil {
+		return raft.Server{}, err
+	}
+	for _, srv := range f.Configuration().Servers {
+		if srv.ID == raft.ServerID(id) {
+			return srv, nil
+		}
+	}
+	return raft.Server{}, ErrNodeNotFound
+}
+
 // remove removes the node, with the given ID, from the cluster.
 func (s *Store) remove(id string) error {
 	if s.raft.State() != raft.Leader {
@@ -866,6 +1605,17 @@ type fsmQueryResponse struct {
 	error error
 }
 
+type fsmLoadChunkResponse struct {
+	progress LoadProgress
+	error    error
+}
+
+type fsmChecksumResponse struct {
+	checksum *Checksum
+	error    error
+	done     chan struct{} // Closed once the checksum is taken.
+}
+
 type fsmGenericResponse struct {
 	error error
 }
@@ -890,19 +1640,24 @@ func (s *Store) Apply(l *raft.Log) (e interface{}) {
 					// been applied, but it wouldn't have created the on-disk database in that
 					// case since there were commands in the log. This is the very last chance
 					// to do convert from in-memory to on-disk.
-					b, _ := s.db.Serialize()
-					err := s.db.Close()
+					path, err := s.backupToTmpFile()
 					if err != nil {
+						e = &fsmGenericResponse{error: fmt.Errorf("backup failed: %s", err)}
+						return
+					}
+					if err := s.db.Close(); err != nil {
+						os.Remove(path)
 						e = &fsmGenericResponse{error: fmt.Errorf("close failed: %s", err)}
 						return
 					}
 					// Open a new on-disk database.
-					s.db, err = s.openOnDisk(b)
+					s.db, err = s.openOnDisk(path)
 					if err != nil {
 						e = &fsmGenericResponse{error: fmt.Errorf("open on-disk failed: %s", err)}
 						return
 					}
 					s.onDiskCreated = true
+					s.requireFullSnapshot()
 					s.logger.Println("successfully switched to on-disk database")
 				}
 			}
@@ -925,6 +1680,9 @@ func (s *Store) Apply(l *raft.Log) (e interface{}) {
 		if err := command.UnmarshalSubCommand(&c, &qr); err != nil {
 			panic(fmt.Sprintf("failed to unmarshal query subcommand: %s", err.Error()))
 		}
+		s.queryMu.RLock()
+		defer s.queryMu.RUnlock()
+
 		// Read from database. If a transaction is requested, we must block
 		// certain other database operations.
 		if qr.Request.Transaction {
@@ -938,8 +1696,47 @@ func (s *Store) Apply(l *raft.Log) (e interface{}) {
 		if err := command.UnmarshalSubCommand(&c, &er); err != nil {
 			panic(fmt.Sprintf("failed to unmarshal execute subcommand: %s", err.Error()))
 		}
+		if er.IdempotencyKey != "" {
+			// Queries must not see the request until it commits.
+			s.queryMu.Lock()
+			defer s.queryMu.Unlock()
+			return s.applyIdempotentExecute(&er)
+		}
+		s.queryMu.RLock()
+		defer s.queryMu.RUnlock()
 		r, err := s.db.Execute(er.Request, er.Timings)
 		return &fsmExecuteResponse{results: r, error: err}
+	case command.Command_COMMAND_TYPE_LOAD:
+		var lr command.LoadRequest
+		if err := command.UnmarshalSubCommand(&c, &lr); err != nil {
+			panic(fmt.Sprintf("failed to unmarshal load subcommand: %s", err.Error()))
+		}
+		if err := s.load(lr.Data); err != nil {
+			return &fsmGenericResponse{error: fmt.Errorf("failed to load database: %s", err)}
+		}
+		return &fsmGenericResponse{}
+	case command.Command_COMMAND_TYPE_LOAD_CHUNK:
+		var lcr command.LoadChunkRequest
+		if err := command.UnmarshalSubCommand(&c, &lcr); err != nil {
+			panic(fmt.Sprintf("failed to unmarshal load chunk subcommand: %s", err.Error()))
+		}
+		// Queries must not see the chunk until it commits.
+		s.queryMu.Lock()
+		defer s.queryMu.Unlock()
+		return s.loadChunk(&lcr)
+	case command.Command_COMMAND_TYPE_TRANSACTION:
+		var tr command.TransactionRequest
+		if err := command.UnmarshalSubCommand(&c, &tr); err != nil {
+			panic(fmt.Sprintf("failed to unmarshal transaction subcommand: %s", err.Error()))
+		}
+		// Queries must not see the transaction until it commits.
+		s.queryMu.Lock()
+		defer s.queryMu.Unlock()
+		return s.applyTransaction(&tr)
+	case command.Command_COMMAND_TYPE_CHECKSUM:
+		s.queryMu.RLock()
+		defer s.queryMu.RUnlock()
+		return s.applyChecksum(l.Index)
 	case command.Command_COMMAND_TYPE_NOOP:
 		s.numNoops++
 		return &fsmGenericResponse{}
@@ -948,6 +1745,48 @@ func (s *Store) Apply(l *raft.Log) (e interface{}) {
 	}
 }
 
+// load replaces the database with the SQLite database file contained in b.
+// It blocks all queries while doing so. If the file cannot be opened, the
+// current database is kept.
+func (s *Store) load(b []byte) error {
+	s.queryMu.Lock()
+	defer s.queryMu.Unlock()
+
+	path, err := s.restoreToTmpFile(bytes.NewReader(b), int64(len(b)), false)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	// Open the loaded database before closing the current one, so that a
+	// database which cannot be opened leaves the current one in place.
+	db, err := s.openInMemory(path)
+	if err != nil {
+		return fmt.Errorf("openInMemory: %s", err)
+	}
+	if err := s.db.Close(); err != nil {
+		db.Close()
+		return fmt.Errorf("failed to close pre-load database: %s", err)
+	}
+	s.db = db
+	s.requireFullSnapshot()
+
+	if !s.dbConf.Memory && s.onDiskCreated {
+		// The on-disk database can only be replaced once the current one is
+		// closed. Should that fail, the loaded database is served from memory,
+		// as it is while the log is applied on open.
+		ddb, err := s.openOnDisk(path)
+		if err != nil {
+			s.onDiskCreated = false
+			s.logger.Printf("failed to open loaded database on disk, continuing with in-memory database: %s", err)
+			return nil
+		}
+		db.Close()
+		s.db = ddb
+	}
+	return nil
+}
+
 // Database returns a copy of the underlying database. The caller MUST
 // ensure that no transaction is taking place during this call, or an error may
 // be returned. If leader is true, this operation is performed with a read
@@ -977,16 +1816,68 @@ func (s *Store) Snapshot() (raft.FSMSnapshot, error) {
 		startT: time.Now(),
 		logger: s.logger,
 	}
+	fsm.onSuccess = func() {
+		s.snapMu.Lock()
+		defer s.snapMu.Unlock()
+		s.lastSnapT = time.Now()
+		s.lastSnapDur = fsm.createDur
+		s.lastPersistDur = time.Since(fsm.startT)
+	}
 
 	s.txMu.Lock()
 	defer s.txMu.Unlock()
 
-	fsm.database, _ = s.db.Serialize()
-	// The error code is not meaningful from Serialize(). The code needs to be able
-	// handle a nil byte slice being returned.
+	walMode := s.walEnabled() && s.onDiskCreated
+	if walMode {
+		// Any snapshot which is not persisted leaves a gap in the chain of
+		// WAL snapshots, so must be followed by a full snapshot.
+		fsm.onFailure = s.requireFullSnapshot
+	}
+
+	s.snapMu.Lock()
+	fullNeeded := s.fullSnapNeeded
+	s.snapMu.Unlock()
+
+	if walMode && !fullNeeded && s.numWALSnaps+1 < s.FullSnapshotEvery {
+		// Only the changes since the last snapshot need to be persisted.
+		path, err := s.walToTmpFile()
+		if err != nil {
+			s.requireFullSnapshot()
+			return nil, fmt.Errorf("snapshot WAL: %s", err)
+		}
+		fsm.path = path
+		fsm.wal = true
+		s.numWALSnaps++
+
+		stats.Add(numWALSnapshots, 1)
+		fsm.createDur = time.Since(fsm.startT)
+		s.logger.Printf("node WAL snapshot created in %s", fsm.createDur)
+		return fsm, nil
+	}
+
+	// Copy the database to a file, so the snapshot never needs to hold the
+	// whole database in RAM.
+	path, err := s.backupToTmpFile()
+	if err != nil {
+		return nil, fmt.Errorf("snapshot backup: %s", err)
+	}
+	fsm.path = path
+
+	if walMode {
+		// Start the WAL afresh, so it holds the changes for the next snapshot.
+		if err := s.db.Checkpoint(); err != nil {
+			os.Remove(path)
+			return nil, fmt.Errorf("snapshot checkpoint: %s", err)
+		}
+		s.numWALSnaps = 0
+		s.snapMu.Lock()
+		s.fullSnapNeeded = false
+		s.snapMu.Unlock()
+	}
 
 	stats.Add(numSnaphots, 1)
-	s.logger.Printf("node snapshot created in %s", time.Since(fsm.startT))
+	fsm.createDur = time.Since(fsm.startT)
+	s.logger.Printf("node snapshot created in %s", fsm.createDur)
 	return fsm, nil
 }
 
@@ -1000,59 +1891,17 @@ func (s *Store) Restore(rc io.ReadCloser) error {
 	s.queryMu.Lock()
 	defer s.queryMu.Unlock()
 
-	var uint64Size uint64
-	inc := int64(unsafe.Sizeof(uint64Size))
-
-	// Read all the data into RAM, since we have to decode known-length
-	// chunks of various forms.
-	var offset int64
-	b, err := ioutil.ReadAll(rc)
-	if err != nil {
-		return fmt.Errorf("readall: %s", err)
-	}
-
-	// Get size of database, checking for compression.
-	compressed := false
-	sz, err := readUint64(b[offset : offset+inc])
+	// Stream the database file data, decompressing and applying any WAL
+	// snapshots as necessary, into a temporary file from which the database
+	// is then restored.
+	path, err := s.readSnapshot(rc, "")
 	if err != nil {
-		return fmt.Errorf("read compression check: %s", err)
-	}
-	offset = offset + inc
-
-	if sz == math.MaxUint64 {
-		compressed = true
-		// Database is actually compressed, read actual size next.
-		sz, err = readUint64(b[offset : offset+inc])
-		if err != nil {
-			return fmt.Errorf("read compressed size: %s", err)
-		}
-		offset = offset + inc
+		return err
 	}
-
-	// Now read in the database file data, decompress if necessary, and restore.
-	var database []byte
-	if sz > 0 {
-		if compressed {
-			buf := new(bytes.Buffer)
-			gz, err := gzip.NewReader(bytes.NewReader(b[offset : offset+int64(sz)]))
-			if err != nil {
-				return err
-			}
-
-			if _, err := io.Copy(buf, gz); err != nil {
-				return fmt.Errorf("SQLite database decompress: %s", err)
-			}
-
-			if err := gz.Close(); err != nil {
-				return err
-			}
-			database = buf.Bytes()
-		} else {
-			database = b[offset : offset+int64(sz)]
-		}
+	if path != "" {
+		defer os.Remove(path)
 	} else {
 		s.logger.Println("no database data present in restored snapshot")
-		database = nil
 	}
 
 	if err := s.db.Close(); err != nil {
@@ -1065,7 +1914,7 @@ func (s *Store) Restore(rc io.ReadCloser) error {
 		// are no command entries in the log -- so Apply will not be called.
 		// Therefore this is the last opportunity to create the on-disk database
 		// before Raft starts.
-		db, err = s.openOnDisk(database)
+		db, err = s.openOnDisk(path)
 		if err != nil {
 			return fmt.Errorf("open on-disk file during restore: %s", err)
 		}
@@ -1077,18 +1926,130 @@ func (s *Store) Restore(rc io.ReadCloser) error {
 		// command entries in the log. So by sticking with an in-memory database
 		// those entries will be applied in the fastest possible manner. We will
 		// defer creation of any database on disk until the Apply function.
-		db, err = s.openInMemory(database)
+		db, err = s.openInMemory(path)
 		if err != nil {
 			return fmt.Errorf("openInMemory: %s", err)
 		}
 	}
 	s.db = db
+	s.requireFullSnapshot()
 
 	stats.Add(numRestores, 1)
 	s.logger.Printf("node restored in %s", time.Since(startT))
 	return nil
 }
 
+// readSnapshot reads one snapshot from r. A complete snapshot is written to a
+// new temporary file, replacing the database file at path, if any. A WAL
+// snapshot is applied to the database file at path. It returns the path of
+// the resulting database file, which is empty if there is no database data.
+// On error no database file remains.
+func (s *Store) readSnapshot(r io.Reader, path string) (string, error) {
+	sz, err := readUint64From(r)
+	if err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("read compression check: %s", err)
+	}
+
+	switch sz {
+	case chainSnapshotMarker:
+		n, err := readUint64From(r)
+		if err != nil {
+			os.Remove(path)
+			return "", fmt.Errorf("read snapshot chain length: %s", err)
+		}
+		for i := uint64(0); i < n; i++ {
+			if path, err = s.readSnapshot(r, path); err != nil {
+				return "", err
+			}
+		}
+		return path, nil
+
+	case walSnapshotMarker:
+		sz, err = readUint64From(r)
+		if err != nil {
+			os.Remove(path)
+			return "", fmt.Errorf("read compressed WAL size: %s", err)
+		}
+		if sz == 0 {
+			return path, nil
+		}
+		if path == "" {
+			return "", fmt.Errorf("WAL snapshot without preceding database")
+		}
+		walPath, err := s.restoreToTmpFile(io.LimitReader(r, int64(sz)), int64(sz), true)
+		if err != nil {
+			os.Remove(path)
+			return "", err
+		}
+		if err := sql.ReplayWAL(path, walPath); err != nil {
+			os.Remove(walPath)
+			os.Remove(path)
+			return "", err
+		}
+		return path, nil
+	}
+
+	// A complete snapshot replaces whatever came before it.
+	if path != "" {
+		os.Remove(path)
+	}
+
+	compressed := false
+	if sz == math.MaxUint64 {
+		compressed = true
+		// Database is actually compressed, read actual size next.
+		sz, err = readUint64From(r)
+		if err != nil {
+			return "", fmt.Errorf("read compressed size: %s", err)
+		}
+	}
+	if sz == 0 {
+		return "", nil
+	}
+	return s.restoreToTmpFile(io.LimitReader(r, int64(sz)), int64(sz), compressed)
+}
+
+// restoreToTmpFile copies sz bytes of database data from r to a new temporary
+// file in the Store's directory, decompressing the data if compressed is set.
+// It returns the path to the file, which it is up to the caller to remove.
+func (s *Store) restoreToTmpFile(r io.Reader, sz int64, compressed bool) (path string, retErr error) {
+	f, err := ioutil.TempFile(s.raftDir, tmpFilePattern)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := f.Close(); err != nil && retErr == nil {
+			retErr = err
+		}
+		if retErr != nil {
+			os.Remove(f.Name())
+		}
+	}()
+
+	if compressed {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return "", fmt.Errorf("SQLite database decompress: %s", err)
+		}
+		if _, err := io.Copy(f, gz); err != nil {
+			return "", fmt.Errorf("SQLite database decompress: %s", err)
+		}
+		if err := gz.Close(); err != nil {
+			return "", err
+		}
+	} else {
+		n, err := io.Copy(f, r)
+		if err != nil {
+			return "", fmt.Errorf("SQLite database read: %s", err)
+		}
+		if n != sz {
+			return "", fmt.Errorf("SQLite database read: %s", io.ErrUnexpectedEOF)
+		}
+	}
+	return f.Name(), nil
+}
+
 // RegisterObserver registers an observer of Raft events
 func (s *Store) RegisterObserver(o *raft.Observer) {
 	s.raft.RegisterObserver(o)
@@ -1112,7 +2073,12 @@ type fsmSnapshot struct {
 	startT time.Time
 	logger *log.Logger
 
-	database []byte
+	path      string        // Temporary file containing a copy of the database, or its WAL.
+	wal       bool          // Whether path holds the WAL only.
+	createDur time.Duration // Time taken to create the snapshot.
+	persisted bool          // Whether Persist succeeded.
+	onSuccess func()        // Called once the snapshot is persisted.
+	onFailure func()        // Called on release if the snapshot was not persisted.
 }
 
 // Persist writes the snapshot to the given sink.
@@ -1126,8 +2092,12 @@ func (f *fsmSnapshot) Persist(sink raft.SnapshotSink) error {
 
 		// Flag compressed database by writing max uint64 value first.
 		// No SQLite database written by earlier versions will have this
-		// as a size. *Surely*.
-		err := writeUint64(b, math.MaxUint64)
+		// as a size. *Surely*. WAL snapshots are flagged similarly.
+		marker := uint64(math.MaxUint64)
+		if f.wal {
+			marker = walSnapshotMarker
+		}
+		err := writeUint64(b, marker)
 		if err != nil {
 			return err
 		}
@@ -1143,8 +2113,16 @@ func (f *fsmSnapshot) Persist(sink raft.SnapshotSink) error {
 		}
 
 		if cdb != nil {
+			defer os.Remove(cdb.Name())
+			defer cdb.Close()
+
+			fi, err := cdb.Stat()
+			if err != nil {
+				return err
+			}
+
 			// Write size of compressed database.
-			err = writeUint64(b, uint64(len(cdb)))
+			err = writeUint64(b, uint64(fi.Size()))
 			if err != nil {
 				return err
 			}
@@ -1152,12 +2130,14 @@ func (f *fsmSnapshot) Persist(sink raft.SnapshotSink) error {
 				return err
 			}
 
-			// Write compressed database to sink.
-			if _, err := sink.Write(cdb); err != nil {
+			// Stream compressed database to sink.
+			if _, err := io.Copy(sink, cdb); err != nil {
 				return err
 			}
 		} else {
-			f.logger.Println("no database data available for snapshot")
+			if !f.wal {
+				f.logger.Println("no database data available for snapshot")
+			}
 			err = writeUint64(b, uint64(0))
 			if err != nil {
 				return err
@@ -1176,27 +2156,57 @@ func (f *fsmSnapshot) Persist(sink raft.SnapshotSink) error {
 		return err
 	}
 
+	f.persisted = true
+	if f.onSuccess != nil {
+		f.onSuccess()
+	}
 	return nil
 }
 
-func (f *fsmSnapshot) compressedDatabase() ([]byte, error) {
-	if f.database == nil {
+// compressedDatabase writes a gzipped copy of the snapshotted database to a
+// temporary file alongside it, and returns that file positioned at its start.
+// It returns nil if the database is empty. It is up to the caller to close and
+// remove the file.
+func (f *fsmSnapshot) compressedDatabase() (cf *os.File, retErr error) {
+	src, err := os.Open(f.path)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	fi, err := src.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() == 0 {
 		return nil, nil
 	}
 
-	var buf bytes.Buffer
-	gz, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	cf, err = ioutil.TempFile(filepath.Dir(f.path), tmpFilePattern)
 	if err != nil {
 		return nil, err
 	}
+	defer func() {
+		if retErr != nil {
+			cf.Close()
+			os.Remove(cf.Name())
+		}
+	}()
 
-	if _, err := gz.Write(f.database); err != nil {
+	gz, err := gzip.NewWriterLevel(cf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(gz, src); err != nil {
 		return nil, err
 	}
 	if err := gz.Close(); err != nil {
 		return nil, err
 	}
-	return buf.Bytes(), nil
+	if _, err := cf.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return cf, nil
 }
 
 // Database copies contents of the underlying SQLite database to dst
@@ -1209,6 +2219,7 @@ func (s *Store) database(leader bool, dst io.Writer) error {
 	if err != nil {
 		return err
 	}
+	defer os.Remove(f.Name())
 	if err := f.Close(); err != nil {
 		return err
 	}
@@ -1234,8 +2245,15 @@ func (s *Store) databaseTypePretty() string {
 	return "on-disk"
 }
 
-// Release is a no-op.
-func (f *fsmSnapshot) Release() {}
+// Release removes the temporary copy of the database.
+func (f *fsmSnapshot) Release() {
+	if !f.persisted && f.onFailure != nil {
+		f.onFailure()
+	}
+	if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+		f.logger.Printf("failed to remove snapshot database copy: %s", err)
+	}
+}
 
 func readUint64(b []byte) (uint64, error) {
 	var sz uint64
@@ -1245,6 +2263,14 @@ func readUint64(b []byte) (uint64, error) {
 	return sz, nil
 }
 
+func readUint64From(r io.Reader) (uint64, error) {
+	var v uint64
+	if err := binary.Read(r, binary.LittleEndian, &v); err != nil {
+		return 0, err
+	}
+	return v, nil
+}
+
 func writeUint64(w io.Writer, v uint64) error {
 	return binary.Write(w, binary.LittleEndian, v)
 }
@@ -1265,6 +2291,14 @@ func prettyVoter(v bool) string {
 	return "non-voter"
 }
 
+// prettyTarget converts an empty leadership transfer target to "any".
+func prettyTarget(id string) string {
+	if id == "" {
+		return "any"
+	}
+	return id
+}
+
 // pathExists returns true if the given path exists.
 func pathExists(p string) bool {
 	if _, err := os.Lstat(p); err != nil && os.IsNotExist(err) {
@@ -0,0 +1,333 @@
+package store
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/minghsu0107/tqlite/command"
+	sql "github.com/minghsu0107/tqlite/db"
+)
+
+// txSession is an interactive transaction, open on the leader. Each request
+// made in the transaction runs against the database inside a SQLite
+// transaction, which is then rolled back. The requests are recorded, with a
+// digest of their results, so that when the transaction commits they can be
+// replayed through the Raft log as a single entry. The entry only takes
+// effect if every request returns the same results again, so changes made
+// by others since the requests ran abort the transaction.
+type txSession struct {
+	id      string
+	steps   []*command.TransactionStep
+	timer   *time.Timer // Aborts the transaction once it has been idle for too long.
+	expires time.Time   // Time after which the transaction is idle for too long.
+}
+
+// BeginTx starts an interactive transaction, and returns its ID. Transactions
+// only exist on the node on which they begin, which must be the leader.
+func (s *Store) BeginTx() (string, error) {
+	if s.raft.State() != raft.Leader {
+		return "", ErrNotLeader
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	tx := &txSession{
+		id:      hex.EncodeToString(b),
+		expires: time.Now().Add(s.TxTimeout),
+	}
+
+	s.txsMu.Lock()
+	defer s.txsMu.Unlock()
+	s.txs[tx.id] = tx
+	tx.timer = time.AfterFunc(s.TxTimeout, func() { s.expireTx(tx.id) })
+	stats.Add(numTxs, 1)
+	return tx.id, nil
+}
+
+// ExecuteTx executes the statements of ex in the interactive transaction with
+// the given ID. The changes they make are only seen by the transaction until
+// it commits.
+func (s *Store) ExecuteTx(id string, ex *command.ExecuteRequest) ([]*sql.Result, error) {
+	if err := s.bindRequest(ex.Request); err != nil {
+		return nil, err
+	}
+	var results []*sql.Result
+	err := s.runTx(id, ex.Request, false, func(tx *sql.Tx) ([]byte, error) {
+		var err error
+		results, err = tx.Execute(ex.Request, ex.Timings)
+		if err != nil {
+			return nil, err
+		}
+		return txDigest(results, nil)
+	})
+	return results, err
+}
+
+// QueryTx executes the queries of qr in the interactive transaction with the
+// given ID. They see the changes made by the transaction. The read
+// consistency level of qr is ignored.
+func (s *Store) QueryTx(id string, qr *command.QueryRequest) ([]*sql.Rows, error) {
+	if err := s.bindRequest(qr.Request); err != nil {
+		return nil, err
+	}
+	var rows []*sql.Rows
+	err := s.runTx(id, qr.Request, true, func(tx *sql.Tx) ([]byte, error) {
+		var err error
+		rows, err = tx.Query(qr.Request, qr.Timings)
+		if err != nil {
+			return nil, err
+		}
+		return txDigest(nil, rows)
+	})
+	return rows, err
+}
+
+// CommitTx commits the interactive transaction with the given ID, returning
+// the index of the Raft log entry which carried it. ErrTxConflict is returned
+// if any request made in the transaction would now return different results.
+// The transaction ends whether or not it commits.
+func (s *Store) CommitTx(id string) (uint64, error) {
+	tx, err := s.takeTx(id)
+	if err != nil {
+		return 0, err
+	}
+	if s.raft.State() != raft.Leader {
+		return 0, ErrNotLeader
+	}
+
+	b, err := command.MarshalTransactionRequest(&command.TransactionRequest{Steps: tx.steps})
+	if err != nil {
+		return 0, err
+	}
+	c := &command.Command{
+		Type:       command.Command_COMMAND_TYPE_TRANSACTION,
+		SubCommand: b,
+	}
+	b, err = command.Marshal(c)
+	if err != nil {
+		return 0, err
+	}
+
+	f := s.raft.Apply(b, s.ApplyTimeout)
+	if e := f.(raft.Future); e.Error() != nil {
+		if e.Error() == raft.ErrNotLeader {
+			return 0, ErrNotLeader
+		}
+		return 0, e.Error()
+	}
+
+	r := f.Response().(*fsmGenericResponse)
+	if r.error == ErrTxConflict {
+		stats.Add(numTxConflicts, 1)
+	} else if r.error == nil {
+		stats.Add(numTxCommits, 1)
+	}
+	return f.Index(), r.error
+}
+
+// RollbackTx discards the interactive transaction with the given ID.
+func (s *Store) RollbackTx(id string) error {
+	if _, err := s.takeTx(id); err != nil {
+		return err
+	}
+	stats.Add(numTxRollbacks, 1)
+	return nil
+}
+
+// runTx runs a request in the interactive transaction with the given ID. The
+// changes made by the transaction so far are replayed inside a SQLite
+// transaction, then run is called, and the SQLite transaction is rolled back.
+// run returns the digest of the results of the request, which is recorded
+// with the request. As every request replays the writes before it, and the
+// commit replays every request, ErrTxTooLarge is returned for a request which
+// would take the transaction over TxMaxStatements statements.
+func (s *Store) runTx(id string, req *command.Request, query bool, run func(tx *sql.Tx) ([]byte, error)) error {
+	s.txsMu.Lock()
+	defer s.txsMu.Unlock()
+	tx, ok := s.txs[id]
+	if !ok {
+		return ErrTxNotFound
+	}
+	if s.raft.State() != raft.Leader {
+		s.endTx(tx)
+		return ErrNotLeader
+	}
+
+	n := len(req.Statements)
+	for _, st := range tx.steps {
+		n += len(st.Request.Statements)
+	}
+	if n > s.TxMaxStatements {
+		return ErrTxTooLarge
+	}
+
+	// The database connection is shared, so nothing else may use it while
+	// the transaction's changes are applied.
+	s.queryMu.Lock()
+	defer s.queryMu.Unlock()
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+
+	dbTx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := dbTx.Rollback(); err != nil {
+			s.logger.Printf("failed to roll back transaction %s: %s", id, err)
+		}
+	}()
+
+	// Writes made earlier in the transaction must still have the same
+	// results, or the transaction would only fail to commit later.
+	for _, st := range tx.steps {
+		if st.Query {
+			continue
+		}
+		d, err := replayStep(dbTx, st)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(d, st.Digest) {
+			s.endTx(tx)
+			stats.Add(numTxConflicts, 1)
+			return ErrTxConflict
+		}
+	}
+
+	d, err := run(dbTx)
+	if err != nil {
+		return err
+	}
+	tx.steps = append(tx.steps, &command.TransactionStep{
+		Request: &command.Request{
+			Statements:    req.Statements,
+			Preconditions: req.Preconditions,
+			RandomSeed:    req.RandomSeed,
+			Now:           req.Now,
+		},
+		Query:  query,
+		Digest: d,
+	})
+	tx.expires = time.Now().Add(s.TxTimeout)
+	tx.timer.Reset(s.TxTimeout)
+	return nil
+}
+
+// takeTx removes the interactive transaction with the given ID, and returns
+// it.
+func (s *Store) takeTx(id string) (*txSession, error) {
+	s.txsMu.Lock()
+	defer s.txsMu.Unlock()
+	tx, ok := s.txs[id]
+	if !ok {
+		return nil, ErrTxNotFound
+	}
+	s.endTx(tx)
+	return tx, nil
+}
+
+// expireTx aborts the interactive transaction with the given ID, if it has
+// been idle for longer than the transaction timeout.
+func (s *Store) expireTx(id string) {
+	s.txsMu.Lock()
+	defer s.txsMu.Unlock()
+	tx, ok := s.txs[id]
+	if !ok || time.Now().Before(tx.expires) {
+		return
+	}
+	s.endTx(tx)
+	stats.Add(numTxTimeouts, 1)
+	s.logger.Printf("transaction %s aborted after being idle for %s", id, s.TxTimeout)
+}
+
+// abortTxs aborts all interactive transactions.
+func (s *Store) abortTxs() {
+	s.txsMu.Lock()
+	defer s.txsMu.Unlock()
+	for _, tx := range s.txs {
+		s.endTx(tx)
+	}
+}
+
+// endTx ends tx. The caller must hold txsMu.
+func (s *Store) endTx(tx *txSession) {
+	tx.timer.Stop()
+	delete(s.txs, tx.id)
+}
+
+// applyTransaction replays the requests made in an interactive transaction,
+// and commits their changes if every request returns the same results it
+// did when it was made. Otherwise no changes are made, and ErrTxConflict is
+// returned.
+func (s *Store) applyTransaction(tr *command.TransactionRequest) *fsmGenericResponse {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return &fsmGenericResponse{error: err}
+	}
+	for _, st := range tr.Steps {
+		d, err := replayStep(tx, st)
+		if err != nil || !bytes.Equal(d, st.Digest) {
+			if err := tx.Rollback(); err != nil {
+				return &fsmGenericResponse{error: err}
+			}
+			return &fsmGenericResponse{error: ErrTxConflict}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return &fsmGenericResponse{error: err}
+	}
+	return &fsmGenericResponse{}
+}
+
+// replayStep runs the request of st in tx, and returns the digest of its
+// results.
+func replayStep(tx *sql.Tx, st *command.TransactionStep) ([]byte, error) {
+	if st.Query {
+		rows, err := tx.Query(st.Request, false)
+		if err != nil {
+			return nil, err
+		}
+		return txDigest(nil, rows)
+	}
+	results, err := tx.Execute(st.Request, false)
+	if err != nil {
+		return nil, err
+	}
+	return txDigest(results, nil)
+}
+
+// txDigest returns a digest of the results of a request, ignoring timings.
+func txDigest(results []*sql.Result, rows []*sql.Rows) ([]byte, error) {
+	h := sha256.New()
+	enc := json.NewEncoder(h)
+	for _, r := range results {
+		c := *r
+		c.Time = 0
+		if err := enc.Encode(&c); err != nil {
+			return nil, err
+		}
+	}
+	for _, r := range rows {
+		c := *r
+		c.Time = 0
+		if err := enc.Encode(&c); err != nil {
+			return nil, err
+		}
+	}
+	return h.Sum(nil), nil
+}
+
+// numTxs returns the number of open interactive transactions.
+func (s *Store) numTxs() int {
+	s.txsMu.Lock()
+	defer s.txsMu.Unlock()
+	return len(s.txs)
+}
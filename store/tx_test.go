@@ -0,0 +1,89 @@
+package store
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/minghsu0107/tqlite/command"
+)
+
+func Test_CommitTxBound(t *testing.T) {
+	s := mustOpenSingleNodeStore(t)
+	mustExecuteStore(t, s, "CREATE TABLE foo (id INTEGER NOT NULL PRIMARY KEY, r INTEGER, at TEXT)")
+
+	id, err := s.BeginTx()
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %s", err)
+	}
+	if _, err := s.ExecuteTx(id, executeRequest(`INSERT INTO foo(r, at) VALUES(random(), datetime('now'))`)); err != nil {
+		t.Fatalf("failed to execute in transaction: %s", err)
+	}
+	rows, err := s.QueryTx(id, &command.QueryRequest{Request: &command.Request{
+		Statements: []*command.Statement{{Sql: "SELECT r, at FROM foo"}},
+	}})
+	if err != nil {
+		t.Fatalf("failed to query in transaction: %s", err)
+	}
+	// Later requests replay the insert, which must get the same values.
+	if _, err := s.ExecuteTx(id, executeRequest(`INSERT INTO foo(r, at) VALUES(random(), CURRENT_TIMESTAMP)`)); err != nil {
+		t.Fatalf("failed to execute in transaction: %s", err)
+	}
+	if _, err := s.CommitTx(id); err != nil {
+		t.Fatalf("failed to commit transaction: %s", err)
+	}
+
+	// The values committed are those seen in the transaction.
+	mustQueryEqual(t, s, "SELECT r, at FROM foo WHERE id = 1", fmt.Sprint(rows[0].Values))
+	mustQueryEqual(t, s, "SELECT COUNT(*) FROM foo", "[[2]]")
+}
+
+// mustOpenSingleNodeStore returns an open store, which is the leader of a
+// cluster of one node.
+func mustOpenSingleNodeStore(t *testing.T) *Store {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	s := New(&tcpListener{ln}, &StoreConfig{
+		DBConf: NewDBConfig("", true),
+		Dir:    t.TempDir(),
+		ID:     ln.Addr().String(),
+		Logger: log.New(ioutil.Discard, "", 0),
+	})
+	if err := s.Open(true); err != nil {
+		t.Fatalf("failed to open store: %s", err)
+	}
+	t.Cleanup(func() { s.Close(true) })
+	if _, err := s.WaitForLeader(10 * time.Second); err != nil {
+		t.Fatalf("failed to wait for leader: %s", err)
+	}
+	return s
+}
+
+func mustExecuteStore(t *testing.T, s *Store, stmt string) {
+	r, _, err := s.Execute(executeRequest(stmt))
+	if err != nil {
+		t.Fatalf("failed to execute %s: %s", stmt, err)
+	}
+	if r[0].Error != "" {
+		t.Fatalf("failed to execute %s: %s", stmt, r[0].Error)
+	}
+}
+
+func executeRequest(stmt string) *command.ExecuteRequest {
+	return &command.ExecuteRequest{Request: &command.Request{
+		Statements: []*command.Statement{{Sql: stmt}},
+	}}
+}
+
+type tcpListener struct {
+	net.Listener
+}
+
+func (l *tcpListener) Dial(addr string, timeout time.Duration) (net.Conn, error) {
+	return net.DialTimeout("tcp", addr, timeout)
+}
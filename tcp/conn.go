@@ -1,10 +1,13 @@
 package tcp
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"expvar"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"net"
 	"os"
@@ -12,6 +15,12 @@ import (
 	"time"
 )
 
+var (
+	// ErrNoCACert is returned when node-to-node encryption is requested
+	// without a CA certificate with which to authenticate nodes.
+	ErrNoCACert = errors.New("CA certificate required to authenticate nodes")
+)
+
 const (
 	// DefaultTimeout is the default length of time to wait for first byte.
 	DefaultTimeout = 30 * time.Second
@@ -38,6 +47,8 @@ type Layer struct {
 	ln     net.Listener
 	header byte
 	addr   net.Addr
+
+	tlsConfig *tls.Config // Config for dialing other nodes, nil if unencrypted.
 }
 
 // Dial creates a new network connection.
@@ -46,7 +57,11 @@ func (l *Layer) Dial(addr string, timeout time.Duration) (net.Conn, error) {
 
 	var err error
 	var conn net.Conn
-	conn, err = dialer.Dial("tcp", addr)
+	if l.tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, l.tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -79,6 +94,8 @@ type Mux struct {
 
 	wg sync.WaitGroup
 
+	tlsConfig *tls.Config // Config for dialing other nodes, nil if unencrypted.
+
 	// The amount of time to wait for the first header byte.
 	Timeout time.Duration
 
@@ -103,6 +120,39 @@ func NewMux(ln net.Listener, adv net.Addr) (*Mux, error) {
 	}, nil
 }
 
+// NewTLSMux returns a new instance of Mux for ln, which encrypts all
+// connections, incoming and outgoing, using TLS with the given certificate
+// and key. Nodes must present a certificate signed by caCert, and the
+// certificates of nodes dialed are verified against it, so nodes
+// authenticate each other. caCert may only be empty if insecure is set, in
+// which case certificates are required but not verified, and connections are
+// encrypted but not authenticated.
+func NewTLSMux(ln net.Listener, adv net.Addr, cert, key, caCert string, insecure bool) (*Mux, error) {
+	if caCert == "" && !insecure {
+		return nil, ErrNoCACert
+	}
+	serverConfig, err := createTLSConfig(cert, key, caCert)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create TLS config: %s", err)
+	}
+	serverConfig.ClientCAs = serverConfig.RootCAs
+	serverConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	if insecure {
+		serverConfig.ClientAuth = tls.RequireAnyClientCert
+	}
+
+	mux, err := NewMux(tls.NewListener(ln, serverConfig), adv)
+	if err != nil {
+		return nil, err
+	}
+	mux.tlsConfig, err = createTLSConfig(cert, key, caCert)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create TLS config: %s", err)
+	}
+	mux.tlsConfig.InsecureSkipVerify = insecure
+	return mux, nil
+}
+
 // Serve handles connections from ln and multiplexes then across registered listener.
 func (mux *Mux) Serve() error {
 	mux.Logger.Printf("mux serving on %s, advertising %s", mux.ln.Addr().String(), mux.addr)
@@ -135,8 +185,9 @@ func (mux *Mux) Serve() error {
 // Stats returns status of the mux.
 func (mux *Mux) Stats() (interface{}, error) {
 	s := map[string]string{
-		"addr":    mux.addr.String(),
-		"timeout": mux.Timeout.String(),
+		"addr":      mux.addr.String(),
+		"timeout":   mux.Timeout.String(),
+		"encrypted": fmt.Sprintf("%t", mux.tlsConfig != nil),
 	}
 
 	return s, nil
@@ -197,9 +248,10 @@ func (mux *Mux) Listen(header byte) *Layer {
 	mux.m[header] = ln
 
 	layer := &Layer{
-		ln:     ln,
-		header: header,
-		addr:   mux.addr,
+		ln:        ln,
+		header:    header,
+		addr:      mux.addr,
+		tlsConfig: mux.tlsConfig,
 	}
 
 	return layer
@@ -224,3 +276,27 @@ func (ln *listener) Close() error { return nil }
 
 // Addr always returns nil
 func (ln *listener) Addr() net.Addr { return nil }
+
+// createTLSConfig returns a TLS config presenting the given certificate, and
+// trusting the given CA certificate, if any.
+func createTLSConfig(cert, key, caCert string) (*tls.Config, error) {
+	pair, err := tls.LoadX509KeyPair(cert, key)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{pair},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if caCert != "" {
+		asn1Data, err := ioutil.ReadFile(caCert)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if ok := config.RootCAs.AppendCertsFromPEM(asn1Data); !ok {
+			return nil, fmt.Errorf("failed to parse CA certificate(s) in %q", caCert)
+		}
+	}
+	return config, nil
+}
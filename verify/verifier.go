@@ -0,0 +1,328 @@
+// Package verify checks that every node in the cluster holds the same
+// database content as the leader, on demand and periodically.
+package verify
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minghsu0107/tqlite/store"
+)
+
+const (
+	numVerifications = "num_verifications"
+	numVerifyErrors  = "num_verification_errors"
+	numDivergences   = "num_divergences"
+	numSkipped       = "num_skipped_not_leader"
+	numTooFrequent   = "num_too_frequent"
+)
+
+// ErrTooFrequent is returned when a verification is requested less than the
+// minimum interval after the previous one.
+var ErrTooFrequent = errors.New("verification requested too soon after the previous one")
+
+// pollInterval is the time between requests for the checksum of a node
+// which has not yet taken it.
+const pollInterval = 100 * time.Millisecond
+
+const (
+	// StatusConsistent means a node has the same checksums as the leader.
+	StatusConsistent = "consistent"
+
+	// StatusDiverged means the checksums of a node differ from the leader's.
+	StatusDiverged = "diverged"
+
+	// StatusUnknown means the checksums of a node could not be compared
+	// with the leader's, as the node did not report them.
+	StatusUnknown = "unknown"
+)
+
+// stats captures stats for the verifier.
+var stats *expvar.Map
+
+func init() {
+	stats = expvar.NewMap("verify")
+	stats.Add(numVerifications, 0)
+	stats.Add(numVerifyErrors, 0)
+	stats.Add(numDivergences, 0)
+	stats.Add(numSkipped, 0)
+	stats.Add(numTooFrequent, 0)
+}
+
+// Database is the interface the store must implement.
+type Database interface {
+	// IsLeader returns whether this node is the leader.
+	IsLeader() bool
+
+	// Addr returns the Raft address of this node.
+	Addr() string
+
+	// Nodes returns the nodes in the cluster.
+	Nodes() ([]*store.Server, error)
+
+	// Checksum makes every node take a checksum of its database at the
+	// same log index, and returns the checksum taken by this node.
+	Checksum() (*store.Checksum, error)
+}
+
+// Cluster is the interface the cluster service must implement.
+type Cluster interface {
+	// GetNodeStatus returns a summary of the state of the node at the given
+	// Raft address, including its most recent checksum.
+	GetNodeStatus(nodeAddr string, timeout time.Duration) (*store.NodeStatus, error)
+}
+
+// Config is the configuration of a Verifier.
+type Config struct {
+	Interval    time.Duration // Time between background verifications, 0 to disable them.
+	Timeout     time.Duration // Time allowed for nodes to report their checksums.
+	MinInterval time.Duration // Minimum time between the starts of verifications.
+}
+
+// NodeReport is the outcome of the verification of a single node.
+type NodeReport struct {
+	ID     string   `json:"id"`
+	Addr   string   `json:"addr"`
+	Status string   `json:"status"`
+	Digest string   `json:"digest,omitempty"`
+	Tables []string `json:"mismatched_tables,omitempty"` // Tables whose checksums differ from the leader's.
+	Error  string   `json:"error,omitempty"`
+}
+
+// Report is the outcome of a verification. Status is StatusConsistent if
+// every node matches the leader, StatusDiverged if any node does not, and
+// StatusUnknown otherwise.
+type Report struct {
+	Index  uint64        `json:"index"`
+	Time   time.Time     `json:"time"`
+	Status string        `json:"status"`
+	Nodes  []*NodeReport `json:"nodes"`
+}
+
+// Verifier compares the checksums of the databases of all nodes with those
+// of the leader. The checksums are taken by every node when it applies the
+// same log entry, so nodes with the same content always agree.
+type Verifier struct {
+	db      Database
+	cluster Cluster
+	conf    Config
+
+	verifyMu  sync.Mutex // Only one verification runs at a time.
+	lastStart time.Time  // Start of the most recent verification.
+
+	done chan struct{}
+	wg   sync.WaitGroup
+
+	mu          sync.RWMutex
+	last        *Report
+	lastFailure time.Time
+	lastError   string
+
+	logger *log.Logger
+}
+
+// New returns a Verifier which verifies the nodes of the cluster of db.
+func New(db Database, cluster Cluster, c *Config) *Verifier {
+	return &Verifier{
+		db:      db,
+		cluster: cluster,
+		conf:    *c,
+		done:    make(chan struct{}),
+		logger:  log.New(os.Stderr, "[verify] ", log.LstdFlags),
+	}
+}
+
+// Start starts background verification, if an interval is set.
+func (v *Verifier) Start() {
+	if v.conf.Interval <= 0 {
+		return
+	}
+	v.wg.Add(1)
+	go v.run()
+	v.logger.Printf("verifying every %s", v.conf.Interval)
+}
+
+// Close stops background verification, waiting for any verification in
+// progress to finish.
+func (v *Verifier) Close() {
+	close(v.done)
+	v.wg.Wait()
+}
+
+// Stats returns the status of the Verifier, including the most recent report.
+func (v *Verifier) Stats() (interface{}, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	status := map[string]interface{}{
+		"interval":     v.conf.Interval.String(),
+		"timeout":      v.conf.Timeout.String(),
+		"min_interval": v.conf.MinInterval.String(),
+	}
+	if v.last != nil {
+		status["last_report"] = v.last
+	}
+	if !v.lastFailure.IsZero() {
+		status["last_failure_time"] = v.lastFailure
+		status["last_failure_error"] = v.lastError
+	}
+	return status, nil
+}
+
+// Verify makes every node take a checksum of its database, and compares the
+// checksums with the leader's. Nodes are given up to timeout to report their
+// checksums. This node must be the leader. As every node copies its database
+// to take the checksum, ErrTooFrequent is returned if the previous
+// verification started less than the minimum interval ago.
+func (v *Verifier) Verify(timeout time.Duration) (*Report, error) {
+	v.verifyMu.Lock()
+	defer v.verifyMu.Unlock()
+
+	if !v.lastStart.IsZero() && time.Since(v.lastStart) < v.conf.MinInterval {
+		stats.Add(numTooFrequent, 1)
+		return nil, ErrTooFrequent
+	}
+	r, err := v.verify(timeout)
+	if err == store.ErrNotLeader {
+		return nil, err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err != nil {
+		stats.Add(numVerifyErrors, 1)
+		v.lastFailure = time.Now()
+		v.lastError = err.Error()
+		return nil, err
+	}
+	stats.Add(numVerifications, 1)
+	v.last = r
+	return r, nil
+}
+
+func (v *Verifier) verify(timeout time.Duration) (*Report, error) {
+	nodes, err := v.db.Nodes()
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	cs, err := v.db.Checksum()
+	if err != store.ErrNotLeader {
+		v.lastStart = start
+	}
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(timeout)
+
+	r := &Report{
+		Index: cs.Index,
+		Time:  time.Now(),
+		Nodes: make([]*NodeReport, len(nodes)),
+	}
+	var wg sync.WaitGroup
+	for i, n := range nodes {
+		nr := &NodeReport{
+			ID:   n.ID,
+			Addr: n.Addr,
+		}
+		r.Nodes[i] = nr
+		if n.Addr == v.db.Addr() {
+			compare(nr, cs, cs)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ncs, err := v.nodeChecksum(nr.Addr, cs.Index, deadline)
+			if err != nil {
+				nr.Status = StatusUnknown
+				nr.Error = err.Error()
+				return
+			}
+			compare(nr, cs, ncs)
+		}()
+	}
+	wg.Wait()
+
+	r.Status = StatusConsistent
+	for _, nr := range r.Nodes {
+		switch nr.Status {
+		case StatusDiverged:
+			r.Status = StatusDiverged
+			stats.Add(numDivergences, 1)
+			v.logger.Printf("node %s has diverged from the leader at index %d, mismatched tables: %s",
+				nr.ID, r.Index, strings.Join(nr.Tables, ", "))
+		case StatusUnknown:
+			if r.Status == StatusConsistent {
+				r.Status = StatusUnknown
+			}
+			v.logger.Printf("failed to verify node %s at index %d: %s", nr.ID, r.Index, nr.Error)
+		}
+	}
+	return r, nil
+}
+
+// nodeChecksum returns the checksum the node at nodeAddr took at the given
+// index, waiting until deadline for the node to apply the index.
+func (v *Verifier) nodeChecksum(nodeAddr string, index uint64, deadline time.Time) (*store.Checksum, error) {
+	for {
+		st, err := v.cluster.GetNodeStatus(nodeAddr, time.Until(deadline))
+		if err == nil && st.Checksum != nil && st.Checksum.Index >= index {
+			if st.Checksum.Index > index {
+				return nil, fmt.Errorf("checksum at index %d replaced by checksum at index %d", index, st.Checksum.Index)
+			}
+			return st.Checksum, nil
+		}
+		if time.Now().Add(pollInterval).After(deadline) {
+			if err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("timed out waiting for checksum at index %d, applied index is %d", index, st.AppliedIndex)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// compare records in nr the outcome of comparing the checksum ncs of a node
+// with the checksum cs of the leader.
+func compare(nr *NodeReport, cs, ncs *store.Checksum) {
+	if ncs.Error != "" {
+		nr.Status = StatusUnknown
+		nr.Error = ncs.Error
+		return
+	}
+	nr.Digest = ncs.Digest()
+	nr.Tables = cs.Diff(ncs)
+	if len(nr.Tables) > 0 {
+		nr.Status = StatusDiverged
+		return
+	}
+	nr.Status = StatusConsistent
+}
+
+func (v *Verifier) run() {
+	defer v.wg.Done()
+	ticker := time.NewTicker(v.conf.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-v.done:
+			return
+		case <-ticker.C:
+			if !v.db.IsLeader() {
+				stats.Add(numSkipped, 1)
+				continue
+			}
+			if _, err := v.Verify(v.conf.Timeout); err != nil && err != ErrTooFrequent {
+				v.logger.Printf("failed to verify cluster: %s", err.Error())
+			}
+		}
+	}
+}